@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/palantir/stacktrace"
+
+	"go-poc/external"
+	"go-poc/middleware"
+	"go-poc/utils"
+	"go-poc/utils/activity"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+)
+
+type database struct {
+	db     *sql.DB
+	driver string
+}
+
+// runMigrate handles `migrate up|down|status|force [service] [n]`. Without a
+// service up and status run on every configured service, down and force
+// always need one.
+func runMigrate(ctx context.Context, databases map[string]database, args []string) error {
+	if len(args) == 0 {
+		return stacktrace.NewError("usage: migrate up|down|status|force [service] [n]")
+	}
+
+	action := args[0]
+	targets := []string{}
+	if len(args) > 1 {
+		targets = append(targets, args[1])
+	} else {
+		if action == "down" || action == "force" {
+			return stacktrace.NewError("migrate %s needs a service", action)
+		}
+
+		for _, service := range services {
+			if _, exist := databases[service]; exist {
+				targets = append(targets, service)
+			}
+		}
+	}
+
+	for _, service := range targets {
+		db, exist := databases[service]
+		if !exist {
+			return stacktrace.NewError("service %s has no database configured", service)
+		}
+
+		migrator, err := external.NewMigrator(db.db, service, db.driver)
+		if err != nil {
+			return stacktrace.Propagate(err, "new migrator error")
+		}
+
+		switch action {
+		case "up":
+			if err := migrator.Up(); err != nil {
+				return err
+			}
+		case "down":
+			steps := 1
+			if len(args) > 2 {
+				steps, err = strconv.Atoi(args[2])
+				if err != nil || steps <= 0 {
+					return stacktrace.NewError("invalid steps %s", args[2])
+				}
+			}
+
+			if err := migrator.Down(steps); err != nil {
+				return err
+			}
+		case "force":
+			if len(args) < 3 {
+				return stacktrace.NewError("migrate force needs a version")
+			}
+
+			version, err := strconv.Atoi(args[2])
+			if err != nil {
+				return stacktrace.NewError("invalid version %s", args[2])
+			}
+
+			if err := migrator.Force(version); err != nil {
+				return err
+			}
+		case "status":
+		default:
+			return stacktrace.NewError("unknown migrate action %s", action)
+		}
+
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		log.WithContext(ctx).Infof("%s at version %d of %d dirty %t", service, status.Version, status.Latest, status.Dirty)
+	}
+
+	return nil
+}
+
+type seeder struct {
+	file string
+	run  func(ctx context.Context, data []byte) error
+}
+
+// newSeeder decodes a fixture file into upsert inputs, upserts match on id
+// or natural key so seeding twice leaves the same rows.
+func newSeeder[I any, O any](file string, upsert func(ctx context.Context, inputs []I) ([]O, error)) seeder {
+	return seeder{
+		file: file,
+		run: func(ctx context.Context, data []byte) error {
+			var inputs []I
+			if err := json.Unmarshal(data, &inputs); err != nil {
+				return stacktrace.Propagate(err, "decode fixture error")
+			}
+
+			outputs, err := upsert(ctx, inputs)
+			if err != nil {
+				return stacktrace.Propagate(err, "upsert fixture error %v", outputs)
+			}
+
+			return nil
+		},
+	}
+}
+
+// runSeed loads the fixtures in order, later fixtures may refer to rows of
+// the earlier ones by the ids they are created with.
+func runSeed(ctx context.Context, seeders ...seeder) error {
+	ctx = activity.WithSeed(ctx)
+	for _, seeder := range seeders {
+		data, err := os.ReadFile(seeder.file)
+		if err != nil {
+			return stacktrace.Propagate(err, "read fixture %s error", seeder.file)
+		}
+
+		if err := seeder.run(ctx, data); err != nil {
+			return stacktrace.Propagate(err, "seed %s error", seeder.file)
+		}
+
+		log.WithContext(ctx).Infof("seeded %s", seeder.file)
+	}
+
+	return nil
+}
+
+type cacheWarmer struct {
+	name string
+	warm func(ctx context.Context, batchSize int64) (int, error)
+}
+
+// runCache handles `cache warm`, every warmer is loaded batchSize rows at
+// a time.
+func runCache(ctx context.Context, args []string, batchSize int64, warmers ...cacheWarmer) error {
+	if len(args) == 0 || args[0] != "warm" {
+		return stacktrace.NewError("usage: cache warm")
+	}
+
+	for _, warmer := range warmers {
+		total, err := warmer.warm(ctx, batchSize)
+		if err != nil {
+			return stacktrace.Propagate(err, "warm %s cache error", warmer.name)
+		}
+
+		log.WithContext(ctx).Infof("warmed %d %s", total, warmer.name)
+	}
+
+	return nil
+}
+
+type purger struct {
+	name  string
+	purge func(ctx context.Context, before time.Time) (int64, error)
+}
+
+// runPurge handles `purge [retention]`, rows soft deleted longer than the
+// retention ago are removed for good. The retention defaults to
+// PURGE_RETENTION.
+func runPurge(ctx context.Context, args []string, retention time.Duration, purgers ...purger) error {
+	if len(args) > 0 {
+		var err error
+		retention, err = time.ParseDuration(args[0])
+		if err != nil || retention < 0 {
+			return stacktrace.NewError("invalid retention %s", args[0])
+		}
+	}
+
+	before := time.Now().Add(-retention)
+	for _, purger := range purgers {
+		total, err := purger.purge(ctx, before)
+		if err != nil {
+			return stacktrace.Propagate(err, "purge %s error", purger.name)
+		}
+
+		log.WithContext(ctx).Infof("purged %d %s deleted before %s", total, purger.name, before.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// runAPIKey handles `apikey {client}`, it prints a new key for the client
+// and the AUTH_API_KEYS and AUTH_API_KEYS_FILE entries holding its hash.
+func runAPIKey(args []string) error {
+	if len(args) == 0 {
+		return stacktrace.NewError("usage: apikey {client}")
+	}
+
+	key := utils.GenerateSecureToken(32)
+	if key == "" {
+		return stacktrace.NewError("generate api key error")
+	}
+
+	hash := middleware.HashAPIKey(key)
+	entry, err := json.Marshal(middleware.APIKeyClient{
+		Client: args[0],
+		Hash:   hash,
+		Scopes: []string{},
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, "encode api key error")
+	}
+
+	// single quoted so a .env keeps the entry as is, godotenv expands $ in
+	// unquoted and double quoted values
+	fmt.Printf("key: %s\nAUTH_API_KEYS entry, every scope: '%s:%s'\nAUTH_API_KEYS_FILE entry, fill in the scopes: %s\n", key, args[0], hash, entry)
+
+	return nil
+}
+
+// newWorker runs the jobs every interval of cfg in batches of its batch
+// size until it is stopped, the batch in flight is finished first.
+func newWorker(ctx context.Context, name string, cfg config.Worker, jobs ...workerJob) lifecycle.Runner {
+	interval := cfg.Interval
+	batchSize := cfg.BatchSize
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	return lifecycle.Runner{
+		Name: name,
+		Start: func() error {
+			defer close(done)
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				for _, job := range jobs {
+					for {
+						total, err := job(ctx, batchSize)
+						if err != nil {
+							log.WithContext(ctx).Error(stacktrace.Propagate(err, name+" error"))
+							break
+						}
+
+						// a full batch means more rows are probably waiting,
+						// unless the worker is stopping
+						if int64(total) < batchSize || stopping(stop) {
+							break
+						}
+					}
+				}
+
+				select {
+				case <-stop:
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+		Stop: func(ctx context.Context) error {
+			close(stop)
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+func stopping(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+type workerJob func(ctx context.Context, limit int64) (int, error)
@@ -0,0 +1,56 @@
+package external
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-redis/redis"
+	"github.com/rainycape/memcache"
+)
+
+// Probe checks that one dependency of a service answers. Check should give
+// up once ctx is done, the caller stops waiting for it anyway.
+type Probe struct {
+	Service string
+	Name    string
+	Kind    string
+	Check   func(ctx context.Context) error
+}
+
+func NewSQLProbe(service, driver string, db *sql.DB) Probe {
+	return Probe{
+		Service: service,
+		Name:    "main",
+		Kind:    driver,
+		Check:   db.PingContext,
+	}
+}
+
+func NewRedisProbe(service string, client *redis.Client) Probe {
+	return Probe{
+		Service: service,
+		Name:    "cache",
+		Kind:    "redis",
+		Check: func(ctx context.Context) error {
+			return client.WithContext(ctx).Ping().Err()
+		},
+	}
+}
+
+// NewMemcacheProbe reads a key nobody sets, memcache has no ping and a
+// miss proves the server answered.
+func NewMemcacheProbe(service string, client *memcache.Client) Probe {
+	return Probe{
+		Service: service,
+		Name:    "cache",
+		Kind:    "memcache",
+		Check: func(ctx context.Context) error {
+			_, err := client.Get("healthcheck")
+			if err == memcache.ErrCacheMiss {
+				return nil
+			}
+
+			return err
+		},
+	}
+}
@@ -1,13 +1,11 @@
 package external
 
 import (
-	"os"
-
 	"github.com/rainycape/memcache"
+
+	"go-poc/utils/config"
 )
 
-func NewMemcache() (*memcache.Client, error) {
-	dbHost := os.Getenv("MEMCACHE_HOST")
-	dbPort := os.Getenv("MEMCACHE_PORT")
-	return memcache.New(dbHost + ":" + dbPort)
+func NewMemcache(cfg config.Server) (*memcache.Client, error) {
+	return memcache.New(cfg.Addr())
 }
@@ -1,118 +1,174 @@
 package external
 
 import (
-	"context"
 	"database/sql"
 	"errors"
+	"os"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 	"github.com/palantir/stacktrace"
-
-	"go-poc/utils/log"
 )
 
-const (
-	SALES_CHANNEL_STEP = 2
-	INVENTORY_STEP     = 2
+var (
+	ErrDirtySchema = errors.New("schema is dirty")
+	ErrAheadSchema = errors.New("schema is ahead of the migration files")
 )
 
-type Schema_migration struct {
-	Version int  `orm:"version" json:"version"`
-	Dirty   bool `orm:"dirty" json:"dirty"`
+type MigrationStatus struct {
+	Service string `json:"service"`
+	Version int    `json:"version"`
+	Latest  int    `json:"latest"`
+	Dirty   bool   `json:"dirty"`
 }
 
-func initMigration(ctx context.Context, db *sql.DB, service, driverType string) {
-	var err error
+// Migrator runs the migrations of one service, each service keeps its
+// version in its own <service>_schema_migrations table. The latest version
+// is read from service/<service>/migration so adding a file is enough.
+type Migrator struct {
+	service string
+	latest  uint
+	m       *migrate.Migrate
+}
+
+func NewMigrator(db *sql.DB, service, driverType string) (*Migrator, error) {
 	migrationsTable := service + "_schema_migrations"
-	m := &migrate.Migrate{}
-	if driverType == "mysql" {
-		driver, _ := mysql.WithInstance(db, &mysql.Config{
+
+	var driver database.Driver
+	var err error
+	switch driverType {
+	case "mysql":
+		driver, err = mysql.WithInstance(db, &mysql.Config{
 			MigrationsTable: migrationsTable,
 		})
-
-		m, err = migrate.NewWithDatabaseInstance(
-			"file://./service/"+service+"/migration",
-			driverType,
-			driver,
-		)
-
-		if err != nil {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "init instance db migration error"))
-		}
-	} else if driverType == "postgres" {
-		driver, _ := postgres.WithInstance(db, &postgres.Config{
+	case "postgres":
+		driver, err = postgres.WithInstance(db, &postgres.Config{
 			MigrationsTable: migrationsTable,
 		})
+	default:
+		return nil, stacktrace.NewError("driver %s not supported", driverType)
+	}
 
-		m, err = migrate.NewWithDatabaseInstance(
-			"file://./service/"+service+"/migration",
-			driverType,
-			driver,
-		)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "init migration driver error")
+	}
 
-		if err != nil {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "init instance db migration error"))
-		}
-	} else {
-		return
+	src, err := source.Open("file://./service/" + service + "/migration")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "open migration source error")
 	}
 
-	sqlStatement := "SELECT version, dirty FROM " + migrationsTable
-	stmt, err := db.Prepare(sqlStatement)
+	latest, err := latestVersion(src)
 	if err != nil {
-		log.WithContext(ctx).Error(stacktrace.Propagate(err, "select schema migration error"))
-		switch service {
-		case "saleschannel":
-			err = m.Steps(SALES_CHANNEL_STEP)
-		case "inventory":
-			err = m.Steps(INVENTORY_STEP)
-		default:
-			err = errors.New("service not found")
-		}
+		return nil, stacktrace.Propagate(err, "find latest migration error")
+	}
 
-		if err != nil {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "init step migration error"))
+	m, err := migrate.NewWithInstance("file", src, driverType, driver)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "init instance db migration error")
+	}
+
+	return &Migrator{
+		service: service,
+		latest:  latest,
+		m:       m,
+	}, nil
+}
+
+// Up migrates to the latest version. A dirty schema or one migrated by a
+// newer build is refused instead of guessed at.
+func (m *Migrator) Up() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return stacktrace.Propagate(ErrDirtySchema, "%s schema is dirty at version %d, fix it by hand then run `migrate force %s <version>`", m.service, status.Version, m.service)
+	}
+
+	if status.Version > status.Latest {
+		return stacktrace.Propagate(ErrAheadSchema, "%s schema is at version %d but the latest migration is %d", m.service, status.Version, status.Latest)
+	}
+
+	if m.latest == 0 {
+		return nil
+	}
+
+	err = m.m.Migrate(m.latest)
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return stacktrace.Propagate(err, "migrate %s up error", m.service)
+	}
+
+	return nil
+}
+
+// Down rolls back the given number of migrations.
+func (m *Migrator) Down(steps int) error {
+	err := m.m.Steps(-steps)
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return stacktrace.Propagate(err, "migrate %s down error", m.service)
+	}
+
+	return nil
+}
+
+// Force sets the version without running any migration, it is the way out
+// of a dirty schema once it has been fixed by hand.
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return stacktrace.Propagate(err, "migrate %s force error", m.service)
+	}
+
+	return nil
+}
+
+func (m *Migrator) Status() (MigrationStatus, error) {
+	status := MigrationStatus{
+		Service: m.service,
+		Latest:  int(m.latest),
+	}
+
+	version, dirty, err := m.m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return status, nil
 		}
-	} else {
-		row := stmt.QueryRow()
-
-		var schema Schema_migration
-		err = row.Scan(&schema.Version, &schema.Dirty)
-		deltaSchema := 0
-		switch service {
-		case "saleschannel":
-			deltaSchema = SALES_CHANNEL_STEP - schema.Version
-		case "inventory":
-			deltaSchema = INVENTORY_STEP - schema.Version
+
+		return status, stacktrace.Propagate(err, "migrate %s version error", m.service)
+	}
+
+	status.Version = int(version)
+	status.Dirty = dirty
+
+	return status, nil
+}
+
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
 		}
 
-		switch err {
-		case sql.ErrNoRows:
-			switch service {
-			case "saleschannel":
-				err = m.Steps(SALES_CHANNEL_STEP)
-			case "inventory":
-				err = m.Steps(INVENTORY_STEP)
-			default:
-				err = errors.New("service not found")
-			}
+		return 0, err
+	}
 
-			if err != nil {
-				log.WithContext(ctx).Error(stacktrace.Propagate(err, "init step migration error"))
-			}
-		case nil:
-			err = m.Steps(deltaSchema)
-			if err != nil {
-				if err.Error() != "no change" {
-					log.WithContext(ctx).Error(stacktrace.Propagate(err, "init step migration error"))
-				}
+	for {
+		next, err := src.Next(version)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return version, nil
 			}
-		default:
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "migration error"))
+
+			return 0, err
 		}
+
+		version = next
 	}
 }
@@ -3,39 +3,39 @@ package external
 import (
 	"database/sql"
 	"fmt"
-	"os"
 
 	_ "github.com/lib/pq"
 	"github.com/palantir/stacktrace"
 
 	"go-poc/utils/activity"
+	"go-poc/utils/config"
 	"go-poc/utils/log"
+	"go-poc/utils/metrics"
 )
 
-func NewPostgres(service string) (*sql.DB, error) {
+// NewPostgres opens the connection of service with its pool settings.
+func NewPostgres(service string, cfg config.Database) (*sql.DB, error) {
 	ctx := activity.NewContext("init_postgres")
 
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbPort := os.Getenv("POSTGRES_PORT")
-	dbUsername := os.Getenv("POSTGRES_USERNAME")
-	dbPassword := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", dbHost, dbPort, dbUsername, dbPassword, dbName)
+	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Name)
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		log.WithContext(ctx).Error(stacktrace.Propagate(err, "can't open postgres connection"))
 		return nil, stacktrace.Propagate(err, "can't open postgres connection")
 	}
 
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	if err := db.Ping(); err != nil {
 		log.WithContext(ctx).Error(stacktrace.Propagate(err, "can't ping postgres db"))
 		return nil, stacktrace.Propagate(err, "can't ping postgres db")
 	}
 
-	initMigration(ctx, db, service, "postgres")
+	if err := metrics.RegisterDB(service, db); err != nil {
+		log.WithContext(ctx).Warn(stacktrace.Propagate(err, "can't register postgres pool metrics"))
+	}
 
 	return db, nil
 }
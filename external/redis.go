@@ -1,20 +1,19 @@
 package external
 
 import (
-	"os"
-
 	"github.com/go-redis/redis"
 	"github.com/palantir/stacktrace"
 
 	"go-poc/utils/activity"
+	"go-poc/utils/config"
 	"go-poc/utils/log"
 )
 
-func NewRedis() (*redis.Client, error) {
+func NewRedis(cfg config.Server) (*redis.Client, error) {
 	ctx := activity.NewContext("init_redis")
 
 	client := redis.NewClient(&redis.Options{
-		Addr: os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+		Addr: cfg.Addr(),
 		DB:   0, // use default DB
 	})
 
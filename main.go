@@ -1,186 +1,483 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
 	joonix "github.com/joonix/log"
-	"github.com/opentracing/opentracing-go"
 	"github.com/palantir/stacktrace"
+	"github.com/rainycape/memcache"
 	"github.com/sirupsen/logrus"
 
 	"go-poc/external"
+	"go-poc/middleware"
 	"go-poc/service"
+	identityHandler "go-poc/service/identity/handler"
+	identityAdapter "go-poc/service/identity/repository/adapter"
+	identityPort "go-poc/service/identity/repository/port"
+	identityUsecase "go-poc/service/identity/usecase"
 	inventoryHandler "go-poc/service/inventory/handler"
 	inventoryAdapter "go-poc/service/inventory/repository/adapter"
 	inventoryPort "go-poc/service/inventory/repository/port"
 	inventoryUsecase "go-poc/service/inventory/usecase"
 	salesChannelHandler "go-poc/service/saleschannel/handler"
 	salesChannelAdapter "go-poc/service/saleschannel/repository/adapter"
+	salesChannelInventoryAdapter "go-poc/service/saleschannel/repository/adapter/inventory"
 	salesChannelPort "go-poc/service/saleschannel/repository/port"
 	salesChannelUsecase "go-poc/service/saleschannel/usecase"
+	systemHandler "go-poc/service/system/handler"
+	webhookHandler "go-poc/service/webhook/handler"
+	webhookAdapter "go-poc/service/webhook/repository/adapter"
+	webhookPort "go-poc/service/webhook/repository/port"
+	webhookUsecase "go-poc/service/webhook/usecase"
 	"go-poc/utils"
 	"go-poc/utils/activity"
+	"go-poc/utils/config"
+	"go-poc/utils/event"
+	"go-poc/utils/httpclient"
+	"go-poc/utils/lifecycle"
 	"go-poc/utils/log"
+	"go-poc/utils/tracing"
 )
 
 const (
+	appName = "go-poc"
+
 	salesChannelService = "saleschannel"
 	inventoryService    = "inventory"
+	webhookService      = "webhook"
+	identityService     = "identity"
 )
 
+// services lists every service in migration order.
+var services = []string{salesChannelService, inventoryService, webhookService, identityService}
+
+// requirement is what a command needs besides the service databases.
+type requirement struct {
+	tracing bool
+	cache   bool
+}
+
+// commands lists the commands, the one shot ones skip the tracer and the
+// ones not reading or writing the cache do not open it.
+var commands = map[string]requirement{
+	"serve":   {tracing: true, cache: true},
+	"migrate": {},
+	"seed":    {cache: true},
+	"cache":   {cache: true},
+	"purge":   {},
+	"relay":   {tracing: true},
+	"deliver": {tracing: true},
+}
+
 func main() {
 	godotenv.Load(".env")
 	configureLogging()
 	ctx := activity.NewContext("init_app")
 
-	salesChannelJaeger, closer, err := external.NewJaeger(salesChannelService)
-	if err != nil {
-		log.WithContext(ctx).Error(stacktrace.Propagate(err, "jaeger open telemetry error"))
-		panic(err)
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
 	}
-	defer closer.Close()
-	opentracing.SetGlobalTracer(salesChannelJaeger)
 
-	redisDB, err := external.NewRedis()
-	if err != nil {
-		log.WithContext(ctx).Error(stacktrace.Propagate(err, "redis connection error"))
-		panic(err)
+	// apikey only prints a key, it needs neither the config nor a client
+	if command == "apikey" {
+		if err := runAPIKey(os.Args[2:]); err != nil {
+			log.WithContext(ctx).Error(err)
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	memcacheDB, err := external.NewMemcache()
+	need, exist := commands[command]
+	if !exist {
+		log.WithContext(ctx).Error(stacktrace.NewError("unknown command %s", command))
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
 	if err != nil {
-		log.WithContext(ctx).Error(stacktrace.Propagate(err, "memcache connection error"))
-		panic(err)
+		log.WithContext(ctx).Error(stacktrace.Propagate(err, "load config error"))
+		os.Exit(1)
+	}
+	utils.SetQueryTimeout(cfg.App.QueryTimeout)
+
+	// The clients are closed in the order they are added once the server
+	// and the workers stopped, the tracer first so the last spans are sent
+	manager := lifecycle.New(cfg.App.ShutdownTimeout)
+
+	if need.tracing {
+		tracingProvider, err := tracing.Start(cfg.Tracing.Exporter, cfg.Tracing.JaegerURL, appName, cfg.App.Environment)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "open telemetry error"))
+			panic(err)
+		}
+		manager.OnClose("tracer", tracingProvider.Shutdown)
 	}
 
+	// only the caches a service is configured with are opened
+	usesCache := func(adapter string) bool {
+		return need.cache && (cfg.SalesChannel.Cache == adapter || cfg.Inventory.Cache == adapter)
+	}
+
+	var redisDB *redis.Client
+	if usesCache(config.AdapterRedis) {
+		redisDB, err = external.NewRedis(cfg.Redis)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "redis connection error"))
+			panic(err)
+		}
+		manager.OnClose("redis", func(context.Context) error {
+			return redisDB.Close()
+		})
+	}
+
+	var memcacheDB *memcache.Client
+	if usesCache(config.AdapterMemcache) {
+		memcacheDB, err = external.NewMemcache(cfg.Memcache)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "memcache connection error"))
+			panic(err)
+		}
+		manager.OnClose("memcache", func(context.Context) error {
+			return memcacheDB.Close()
+		})
+	}
+
+	databases := map[string]database{}
+	// probes are the dependencies /readyz checks, the databases are added
+	// once every service is registered
+	probes := []external.Probe{}
+
+	// Register inventory service
+	var inventoryDB *sql.DB
+	var inventoryMain inventoryPort.MainRepository
+	switch cfg.Inventory.Main {
+	case "mysql":
+		inventoryDB, err = external.NewMySQL(inventoryService, cfg.Inventory.Database)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "mysql connection error"))
+			panic(err)
+		}
+
+		inventoryMain = inventoryAdapter.NewMySQL(inventoryDB)
+		databases[inventoryService] = database{db: inventoryDB, driver: "mysql"}
+	case "postgres":
+		inventoryDB, err = external.NewPostgres(inventoryService, cfg.Inventory.Database)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "postgres connection error"))
+			panic(err)
+		}
+
+		inventoryMain = inventoryAdapter.NewPostgres(inventoryDB)
+		databases[inventoryService] = database{db: inventoryDB, driver: "postgres"}
+	}
+
+	var inventoryCache inventoryPort.CacheRepository
+	switch {
+	case need.cache && cfg.Inventory.Cache == config.AdapterRedis:
+		inventoryCache = inventoryAdapter.NewRedis(redisDB)
+		probes = append(probes, external.NewRedisProbe(inventoryService, redisDB))
+	case need.cache && cfg.Inventory.Cache == config.AdapterMemcache:
+		inventoryCache = inventoryAdapter.NewMemcache(memcacheDB)
+		probes = append(probes, external.NewMemcacheProbe(inventoryService, memcacheDB))
+	}
+
+	locationUsecase := inventoryUsecase.NewLocation(inventoryMain, inventoryCache, cfg.Inventory)
+	locationHandler := inventoryHandler.NewLocation(locationUsecase)
+	sourcingUsecase := inventoryUsecase.NewSourcing(inventoryMain, inventoryCache, cfg.Inventory)
+	sourcingHandler := inventoryHandler.NewSourcing(sourcingUsecase)
+
 	// Register sales channel service
 	var salesChannelDB *sql.DB
 	var salesChannelMain salesChannelPort.MainRepository
-	switch os.Getenv("SALES_CHANNEL_MAIN") {
+	switch cfg.SalesChannel.Main {
 	case "mysql":
-		salesChannelDB, err = external.NewMySQL(salesChannelService)
+		salesChannelDB, err = external.NewMySQL(salesChannelService, cfg.SalesChannel.Database)
 		if err != nil {
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "mysql connection error"))
 			panic(err)
 		}
 
 		salesChannelMain = salesChannelAdapter.NewMySQL(salesChannelDB)
+		databases[salesChannelService] = database{db: salesChannelDB, driver: "mysql"}
 	case "postgres":
-		salesChannelDB, err = external.NewPostgres(salesChannelService)
+		salesChannelDB, err = external.NewPostgres(salesChannelService, cfg.SalesChannel.Database)
 		if err != nil {
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "mysql connection error"))
 			panic(err)
 		}
 
 		salesChannelMain = salesChannelAdapter.NewPostgres(salesChannelDB)
+		databases[salesChannelService] = database{db: salesChannelDB, driver: "postgres"}
 	}
 
 	var salesChannelCache salesChannelPort.CacheRepository
-	switch os.Getenv("SALES_CHANNEL_CACHE") {
-	case "redis":
+	switch {
+	case need.cache && cfg.SalesChannel.Cache == config.AdapterRedis:
 		salesChannelCache = salesChannelAdapter.NewRedis(redisDB)
-	case "memcache":
+		probes = append(probes, external.NewRedisProbe(salesChannelService, redisDB))
+	case need.cache && cfg.SalesChannel.Cache == config.AdapterMemcache:
 		salesChannelCache = salesChannelAdapter.NewMemcache(memcacheDB)
+		probes = append(probes, external.NewMemcacheProbe(salesChannelService, memcacheDB))
 	}
 
-	salesChannelUsecase := salesChannelUsecase.NewChannel(salesChannelMain, salesChannelCache)
-	salesChannelHandler := salesChannelHandler.NewChannel(salesChannelUsecase)
+	var salesChannelInventory salesChannelPort.InventoryRepository
+	switch cfg.SalesChannel.Inventory {
+	case "http":
+		salesChannelInventory = salesChannelInventoryAdapter.NewHTTPRepository(httpclient.NewDoer(cfg.HTTPClient.Timeout), cfg.Inventory.URL, cfg.Inventory.APIKey)
+	default:
+		salesChannelInventory = salesChannelInventoryAdapter.NewInProcessRepository(sourcingUsecase)
+	}
 
-	// Register inventory service
-	var inventoryDB *sql.DB
-	var inventoryMain inventoryPort.MainRepository
-	switch os.Getenv("INVENTORY_MAIN") {
+	channelUsecase := salesChannelUsecase.NewChannel(salesChannelMain, salesChannelCache, cfg.SalesChannel)
+	channelHandler := salesChannelHandler.NewChannel(channelUsecase)
+	channelProductUsecase := salesChannelUsecase.NewChannelProduct(salesChannelMain, salesChannelCache, cfg.SalesChannel)
+	channelProductHandler := salesChannelHandler.NewChannelProduct(channelProductUsecase)
+	availabilityUsecase := salesChannelUsecase.NewAvailability(salesChannelMain, salesChannelInventory)
+	availabilityHandler := salesChannelHandler.NewAvailability(availabilityUsecase)
+	allocationRuleUsecase := salesChannelUsecase.NewAllocationRule(salesChannelMain, salesChannelCache, cfg.SalesChannel)
+	allocationRuleHandler := salesChannelHandler.NewAllocationRule(allocationRuleUsecase)
+
+	// Register webhook service
+	var webhookDB *sql.DB
+	var webhookMain webhookPort.MainRepository
+	switch cfg.Webhook.Main {
 	case "mysql":
-		inventoryDB, err = external.NewMySQL(inventoryService)
+		webhookDB, err = external.NewMySQL(webhookService, cfg.Webhook.Database)
 		if err != nil {
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "mysql connection error"))
 			panic(err)
 		}
 
-		inventoryMain = inventoryAdapter.NewMySQL(inventoryDB)
+		webhookMain = webhookAdapter.NewMySQL(webhookDB)
+		databases[webhookService] = database{db: webhookDB, driver: "mysql"}
 	case "postgres":
-		inventoryDB, err = external.NewPostgres(inventoryService)
+		webhookDB, err = external.NewPostgres(webhookService, cfg.Webhook.Database)
 		if err != nil {
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "postgres connection error"))
 			panic(err)
 		}
 
-		inventoryMain = inventoryAdapter.NewPostgres(inventoryDB)
+		webhookMain = webhookAdapter.NewPostgres(webhookDB)
+		databases[webhookService] = database{db: webhookDB, driver: "postgres"}
 	}
 
-	var inventoryCache inventoryPort.CacheRepository
-	switch os.Getenv("INVENTORY_CACHE") {
-	case "redis":
-		inventoryCache = inventoryAdapter.NewRedis(redisDB)
-	case "memcache":
-		inventoryCache = inventoryAdapter.NewMemcache(memcacheDB)
+	subscriptionUsecase := webhookUsecase.NewSubscription(webhookMain, cfg.Webhook)
+	subscriptionHandler := webhookHandler.NewSubscription(subscriptionUsecase)
+	deliveryUsecase := webhookUsecase.NewDelivery(webhookMain, httpclient.NewDoer(cfg.HTTPClient.Timeout), cfg.Webhook)
+	deliveryHandler := webhookHandler.NewDelivery(deliveryUsecase)
+
+	// Register identity service
+	var identityDB *sql.DB
+	var identityMain identityPort.MainRepository
+	switch cfg.Identity.Main {
+	case "mysql":
+		identityDB, err = external.NewMySQL(identityService, cfg.Identity.Database)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "mysql connection error"))
+			panic(err)
+		}
+
+		identityMain = identityAdapter.NewMySQL(identityDB)
+		databases[identityService] = database{db: identityDB, driver: "mysql"}
+	case "postgres":
+		identityDB, err = external.NewPostgres(identityService, cfg.Identity.Database)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "postgres connection error"))
+			panic(err)
+		}
+
+		identityMain = identityAdapter.NewPostgres(identityDB)
+		databases[identityService] = database{db: identityDB, driver: "postgres"}
 	}
 
-	inventoryUsecase := inventoryUsecase.NewLocation(inventoryMain, inventoryCache)
-	inventoryHandler := inventoryHandler.NewLocation(inventoryUsecase)
+	for _, name := range services {
+		if db, exist := databases[name]; exist {
+			manager.OnClose(name+" database", func(context.Context) error {
+				return db.db.Close()
+			})
+		}
+	}
 
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		default:
-			return
+	var eventPublisher event.Publisher
+	switch cfg.Event.Publisher {
+	case "subscription":
+		eventPublisher = deliveryUsecase
+	case "webhook":
+		eventPublisher = event.NewWebhookPublisher(httpclient.NewDoer(cfg.HTTPClient.Timeout), cfg.Event.WebhookURL)
+	default:
+		eventPublisher = event.NewLogPublisher(cfg.Event.LogFile)
+	}
+
+	salesChannelRelay := salesChannelUsecase.NewRelay(salesChannelService, salesChannelMain, eventPublisher)
+	inventoryRelay := inventoryUsecase.NewRelay(inventoryService, inventoryMain, eventPublisher)
+
+	switch command {
+	case "serve":
+		// Migrate every service to its latest version, a dirty schema stops the start
+		migrators := []*external.Migrator{}
+		for _, name := range services {
+			if db, exist := databases[name]; exist {
+				migrator, err := external.NewMigrator(db.db, name, db.driver)
+				if err != nil {
+					log.WithContext(ctx).Error(stacktrace.Propagate(err, "init migration error"))
+					os.Exit(1)
+				}
+
+				if err := migrator.Up(); err != nil {
+					log.WithContext(ctx).Error(stacktrace.Propagate(err, "migration error"))
+					os.Exit(1)
+				}
+
+				migrators = append(migrators, migrator)
+			}
 		}
-	} else {
+
+		migrationHandler := systemHandler.NewMigration(migrators...)
+
+		for _, name := range services {
+			if db, exist := databases[name]; exist {
+				probes = append(probes, external.NewSQLProbe(name, db.driver, db.db))
+			}
+		}
+		healthHandler := systemHandler.NewHealth(probes, migrators, cfg.App.HealthTimeout)
+
 		// Set application mode
-		mode := os.Getenv("APP_MODE")
-		gin.SetMode(mode)
+		gin.SetMode(cfg.App.Mode)
 
 		corsConfig := cors.New(cors.Config{
 			AllowMethods:     []string{"*"},
 			AllowHeaders:     []string{"*"},
 			AllowOrigins:     []string{"*"},
+			ExposeHeaders:    []string{"ETag", middleware.RequestIDHeader},
 			AllowCredentials: true,
 		})
 
+		authenticators, err := middleware.Authenticators(cfg.Auth)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "init auth error"))
+			os.Exit(1)
+		}
+
+		// The identity service is only built on its database. Its clients
+		// go first, their keys carry a prefix the static keys do not have
+		var clientUsecase identityUsecase.Client
+		if identityMain != nil {
+			clientUsecase = identityUsecase.NewClient(identityMain, cfg.Identity)
+			authenticators = append([]middleware.Authenticator{identityHandler.NewClientAuthenticator(clientUsecase)}, authenticators...)
+		}
+
+		if len(authenticators) == 0 {
+			if !cfg.Auth.Disabled {
+				log.WithContext(ctx).Error(stacktrace.NewError("no authenticator configured, set AUTH_API_KEYS, AUTH_API_KEYS_FILE, AUTH_JWT_SECRET, AUTH_JWT_PUBLIC_KEY_FILE or IDENTITY_MAIN, or AUTH_DISABLED=true to leave the routes open"))
+				os.Exit(1)
+			}
+
+			log.WithContext(ctx).Warn("AUTH_DISABLED is set, every route is open")
+		}
+
 		// Define application
 		app := gin.Default()
 		app.Use(
 			corsConfig,
 			gin.Recovery(),
 			gin.Logger(),
+			middleware.Tracing(),
+			middleware.Metrics(),
+			middleware.RequestContext(),
 		)
 
 		// Init route
+		auth := middleware.Auth(authenticators...)
 		service.InitRoute(
 			ctx,
 			app,
-			salesChannelHandler,
-			inventoryHandler,
+			auth,
+			channelHandler,
+			channelProductHandler,
+			availabilityHandler,
+			allocationRuleHandler,
+			locationHandler,
+			sourcingHandler,
+			subscriptionHandler,
+			deliveryHandler,
+			migrationHandler,
+			healthHandler,
 		)
 
+		if identityMain != nil {
+			userUsecase := identityUsecase.NewUser(identityMain)
+			sessionUsecase := identityUsecase.NewSession(identityMain, cfg.Identity, cfg.Auth)
+			service.InitIdentityRoute(
+				app,
+				auth,
+				identityHandler.NewUser(userUsecase),
+				identityHandler.NewSession(sessionUsecase),
+				identityHandler.NewClient(clientUsecase),
+			)
+		}
+
 		// Start HTTP server
 		srv := &http.Server{
-			Addr:    ":" + os.Getenv("SERVER_PORT"),
+			Addr:    ":" + cfg.App.Port,
 			Handler: app,
 		}
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "running error"))
-		}
-
-		if err := srv.Shutdown(ctx); err != nil {
-			log.WithContext(ctx).Error("cannot shutdown http server")
-		}
+		manager.Add(lifecycle.HTTPServer(srv))
+		err = manager.Run(ctx)
+	case "migrate":
+		err = runMigrate(ctx, databases, os.Args[2:])
+	case "seed":
+		err = runSeed(
+			ctx,
+			newSeeder("service/saleschannel/fixture/channels.json", channelUsecase.UpsertWithTransaction),
+			newSeeder("service/saleschannel/fixture/channel_products.json", channelProductUsecase.Upsert),
+			newSeeder("service/saleschannel/fixture/allocation_rules.json", allocationRuleUsecase.Upsert),
+			newSeeder("service/inventory/fixture/locations.json", locationUsecase.Upsert),
+			newSeeder("service/inventory/fixture/sourcings.json", sourcingUsecase.Upsert),
+		)
+	case "cache":
+		err = runCache(
+			ctx,
+			os.Args[2:],
+			cfg.App.CacheWarmBatchSize,
+			cacheWarmer{name: "channel", warm: channelUsecase.WarmCache},
+			cacheWarmer{name: "location", warm: locationUsecase.WarmCache},
+		)
+	case "purge":
+		err = runPurge(
+			ctx,
+			os.Args[2:],
+			cfg.App.PurgeRetention,
+			purger{name: "channel", purge: channelUsecase.Purge},
+			purger{name: "location", purge: locationUsecase.Purge},
+		)
+	case "relay":
+		manager.Add(newWorker(ctx, "relay", cfg.Relay, salesChannelRelay.Relay, inventoryRelay.Relay))
+		err = manager.Run(ctx)
+	case "deliver":
+		manager.Add(newWorker(ctx, "deliver", cfg.Webhook.Deliver, deliveryUsecase.Deliver))
+		err = manager.Run(ctx)
 	}
 
-	// OS notify
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// a no-op after Run, it closes the clients of the one shot commands
+	if shutdownErr := manager.Shutdown(ctx); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
 
-	log.WithContext(ctx).Info("service stopped")
+	if err != nil {
+		log.WithContext(ctx).Error(err)
+		os.Exit(1)
+	}
 }
 
 func configureLogging() {
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	MethodAPIKey = "api_key"
+)
+
+// APIKeyClient is a client allowed in with a static key. Hash is the
+// HashAPIKey of the key, nil ChannelIDs means every channel.
+type APIKeyClient struct {
+	Client     string      `json:"client"`
+	Hash       string      `json:"hash"`
+	Scopes     []string    `json:"scopes"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+}
+
+// apiKey finds the client of a key by its sha256, a request costs one
+// hash whatever key it sends. The keys are random so a slow hash like
+// bcrypt adds nothing but a way to exhaust the cpu.
+type apiKey struct {
+	clients map[string]APIKeyClient
+}
+
+func NewAPIKey(clients []APIKeyClient) Authenticator {
+	clientMap := make(map[string]APIKeyClient, len(clients))
+	for _, client := range clients {
+		clientMap[strings.ToLower(client.Hash)] = client
+	}
+
+	return &apiKey{
+		clients: clientMap,
+	}
+}
+
+// HashAPIKey is the hex sha256 of key, the hash kept for a static key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validHash reports whether hash looks like a HashAPIKey.
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// ParseAPIKeys reads `client:hash,client:hash`, these clients get every
+// scope.
+func ParseAPIKeys(value string) ([]APIKeyClient, error) {
+	clients := []APIKeyClient{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		client, hash, found := strings.Cut(entry, ":")
+		if !found || client == "" || hash == "" {
+			return nil, stacktrace.NewError("invalid api key entry %s", client)
+		}
+
+		if !validHash(hash) {
+			return nil, stacktrace.NewError("api key hash of %s is not a hex sha256, create it with `apikey`", client)
+		}
+
+		clients = append(clients, APIKeyClient{
+			Client: client,
+			Hash:   hash,
+			Scopes: []string{ScopeAll},
+		})
+	}
+
+	return clients, nil
+}
+
+// LoadAPIKeys reads a json list of APIKeyClient, the way to give a key
+// fewer scopes or a subset of the channels.
+func LoadAPIKeys(file string) ([]APIKeyClient, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "read api keys error")
+	}
+
+	clients := []APIKeyClient{}
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, stacktrace.Propagate(err, "decode api keys error")
+	}
+
+	for _, client := range clients {
+		if client.Client == "" || client.Hash == "" {
+			return nil, stacktrace.NewError("api key of %s needs a client and a hash", client.Client)
+		}
+
+		if !validHash(client.Hash) {
+			return nil, stacktrace.NewError("api key hash of %s is not a hex sha256, create it with `apikey`", client.Client)
+		}
+	}
+
+	return clients, nil
+}
+
+func (a *apiKey) Authenticate(c *gin.Context) (*Caller, error) {
+	key := c.GetHeader(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	client, exist := a.clients[HashAPIKey(key)]
+	if !exist {
+		return nil, ErrInvalidCredentials
+	}
+
+	return client.caller(), nil
+}
+
+func (client APIKeyClient) caller() *Caller {
+	return &Caller{
+		ID:         client.Client,
+		Method:     MethodAPIKey,
+		Scopes:     client.Scopes,
+		ChannelIDs: client.ChannelIDs,
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func apiKeyContext(key string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		c.Request.Header.Set(APIKeyHeader, key)
+	}
+
+	return c
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	auth := NewAPIKey([]APIKeyClient{
+		{Client: "reader", Hash: HashAPIKey("reader-key"), Scopes: []string{"channel:read"}},
+		{Client: "upper", Hash: strings.ToUpper(HashAPIKey("upper-key")), Scopes: []string{ScopeAll}},
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		wantClient string
+		wantErr    error
+	}{
+		{name: "known key", key: "reader-key", wantClient: "reader"},
+		{name: "hash in upper case", key: "upper-key", wantClient: "upper"},
+		{name: "no key", wantErr: ErrNoCredentials},
+		{name: "unknown key", key: "other-key", wantErr: ErrInvalidCredentials},
+		{name: "hash sent as the key", key: HashAPIKey("reader-key"), wantErr: ErrInvalidCredentials},
+		{name: "key with a suffix", key: "reader-key ", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := auth.Authenticate(apiKeyContext(tt.key))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+
+			if caller.ID != tt.wantClient || caller.Method != MethodAPIKey {
+				t.Fatalf("caller = %+v, want %s by %s", caller, tt.wantClient, MethodAPIKey)
+			}
+		})
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	hash := HashAPIKey("key")
+	tests := []struct {
+		name        string
+		value       string
+		wantClients []string
+		wantErr     bool
+	}{
+		{name: "empty", value: ""},
+		{name: "one entry", value: "a:" + hash, wantClients: []string{"a"}},
+		{name: "spaces and trailing comma", value: " a:" + hash + " , b:" + hash + ",", wantClients: []string{"a", "b"}},
+		{name: "no hash", value: "a", wantErr: true},
+		{name: "no client", value: ":" + hash, wantErr: true},
+		{name: "plain key", value: "a:secret", wantErr: true},
+		{name: "short hash", value: "a:" + hash[:32], wantErr: true},
+		{name: "not hex", value: "a:" + strings.Repeat("z", 64), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, err := ParseAPIKeys(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAPIKeys error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(clients) != len(tt.wantClients) {
+				t.Fatalf("ParseAPIKeys = %d clients, want %d", len(clients), len(tt.wantClients))
+			}
+
+			for i, client := range clients {
+				if client.Client != tt.wantClients[i] || client.Hash != hash {
+					t.Fatalf("client %d = %+v, want %s", i, client, tt.wantClients[i])
+				}
+
+				if len(client.Scopes) != 1 || client.Scopes[0] != ScopeAll {
+					t.Fatalf("client %d scopes = %v, want every scope", i, client.Scopes)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	hash := HashAPIKey("key")
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: `[{"client":"a","hash":"` + hash + `","scopes":["channel:read"]}]`},
+		{name: "no hash", content: `[{"client":"a"}]`, wantErr: true},
+		{name: "plain key", content: `[{"client":"a","hash":"secret"}]`, wantErr: true},
+		{name: "not json", content: `a:` + hash, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "api-keys.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("write api keys: %v", err)
+			}
+
+			_, err := LoadAPIKeys(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadAPIKeys error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
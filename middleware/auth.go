@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/respond"
+	"go-poc/utils/activity"
+	"go-poc/utils/config"
+)
+
+const callerKey = "caller"
+
+// MaxCallerIDLength fits the client_id VARCHAR(100) of the audit logs.
+const MaxCallerIDLength = 100
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Caller is the authenticated client of a request. Nil ChannelIDs means
+// every channel.
+type Caller struct {
+	ID         string      `json:"id"`
+	Method     string      `json:"method"`
+	Scopes     []string    `json:"scopes"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+}
+
+// Authenticator checks one kind of credential. It returns ErrNoCredentials
+// when the request does not carry that kind so the next one can try.
+type Authenticator interface {
+	Authenticate(c *gin.Context) (*Caller, error)
+}
+
+// Auth rejects the requests no authenticator accepts, the caller of the
+// others is kept on the gin context for Context. Without authenticators
+// every request passes.
+func Auth(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(authenticators) == 0 {
+			c.Next()
+			return
+		}
+
+		for _, authenticator := range authenticators {
+			caller, err := authenticator.Authenticate(c)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+
+			if err != nil {
+				unauthorized(c, err.Error())
+				return
+			}
+
+			if len(caller.ID) > MaxCallerIDLength {
+				unauthorized(c, ErrInvalidCredentials.Error()+": caller id is too long")
+				return
+			}
+
+			c.Set(callerKey, caller)
+			c.Next()
+			return
+		}
+
+		unauthorized(c, ErrNoCredentials.Error())
+	}
+}
+
+func unauthorized(c *gin.Context, desc string) {
+	trxID, _ := activity.GetTransactionID(Context(c, "auth"))
+	respond.Error(c, trxID, http.StatusUnauthorized, respond.ErrUnauthorized, desc)
+	c.Abort()
+}
+
+func GetCaller(c *gin.Context) (*Caller, bool) {
+	value, exist := c.Get(callerKey)
+	if !exist {
+		return nil, false
+	}
+
+	caller, ok := value.(*Caller)
+	return caller, ok
+}
+
+// Context starts the activity of a handler from the request context set by
+// RequestContext, the authenticated caller becomes its client id and its
+// channels the ones the usecases may touch.
+func Context(c *gin.Context, action string) context.Context {
+	ctx := activity.WithAction(c.Request.Context(), action)
+	if _, ok := activity.GetTransactionID(ctx); !ok {
+		ctx = activity.WithTransactionID(ctx, uuid.New().String())
+	}
+
+	if caller, ok := GetCaller(c); ok {
+		ctx = activity.WithClientID(ctx, caller.ID)
+		if caller.ChannelIDs != nil {
+			ctx = activity.WithChannelIDs(ctx, caller.ChannelIDs)
+		}
+	}
+
+	return ctx
+}
+
+// Authenticators builds the authenticators configured by AUTH_API_KEYS,
+// AUTH_API_KEYS_FILE, AUTH_JWT_SECRET and AUTH_JWT_PUBLIC_KEY_FILE.
+func Authenticators(cfg config.Auth) ([]Authenticator, error) {
+	authenticators := []Authenticator{}
+
+	clients := []APIKeyClient{}
+	if cfg.APIKeys != "" {
+		keys, err := ParseAPIKeys(cfg.APIKeys)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "parse api keys error")
+		}
+
+		clients = append(clients, keys...)
+	}
+
+	if cfg.APIKeysFile != "" {
+		keys, err := LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "load api keys error")
+		}
+
+		clients = append(clients, keys...)
+	}
+
+	if len(clients) > 0 {
+		authenticators = append(authenticators, NewAPIKey(clients))
+	}
+
+	jwtConfig := JWTConfig{
+		Secret:   cfg.JWTSecret,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "read jwt public key error")
+		}
+
+		jwtConfig.PublicKey = strings.TrimSpace(string(data))
+	}
+
+	if jwtConfig.Secret != "" || jwtConfig.PublicKey != "" {
+		authenticator, err := NewJWT(jwtConfig)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "new jwt authenticator error")
+		}
+
+		authenticators = append(authenticators, authenticator)
+	}
+
+	return authenticators, nil
+}
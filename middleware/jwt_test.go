@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func bearerContext(token string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	return token
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal rsa public key: %v", err)
+	}
+
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	claims := func(audience string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "client",
+			"aud":   audience,
+			"exp":   expiresAt,
+			"scope": "channel:read channel:write",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  JWTConfig
+		token   string
+		wantErr error
+	}{
+		{
+			name:   "hs256 with secret",
+			config: JWTConfig{Secret: testSecret},
+			token:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("")),
+		},
+		{
+			name:   "rs256 with public key",
+			config: JWTConfig{PublicKey: publicKey},
+			token:  sign(t, jwt.SigningMethodRS256, privateKey, claims("")),
+		},
+		{
+			name:    "no token",
+			config:  JWTConfig{Secret: testSecret},
+			wantErr: ErrNoCredentials,
+		},
+		{
+			name:    "hs256 signed with the public key",
+			config:  JWTConfig{PublicKey: publicKey},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(publicKey), claims("")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "rs256 without a public key",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodRS256, privateKey, claims("")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "hs384 is not accepted",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodHS384, []byte(testSecret), claims("")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "unsigned token",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "wrong secret",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte("other-secret"), claims("")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:   "matching audience",
+			config: JWTConfig{Secret: testSecret, Audience: "go-poc"},
+			token:  sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("go-poc")),
+		},
+		{
+			name:    "other audience",
+			config:  JWTConfig{Secret: testSecret, Audience: "go-poc"},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims("other")),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "no audience",
+			config:  JWTConfig{Secret: testSecret, Audience: "go-poc"},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": "client", "exp": expiresAt}),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "no expiry",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": "client"}),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "expired",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"sub": "client", "exp": time.Now().Add(-time.Hour).Unix()}),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "no subject",
+			config:  JWTConfig{Secret: testSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.MapClaims{"exp": expiresAt}),
+			wantErr: ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewJWT(tt.config)
+			if err != nil {
+				t.Fatalf("NewJWT: %v", err)
+			}
+
+			caller, err := auth.Authenticate(bearerContext(tt.token))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+
+			if caller.ID != "client" || caller.Method != MethodJWT {
+				t.Fatalf("caller = %+v, want client by %s", caller, MethodJWT)
+			}
+		})
+	}
+}
+
+func TestNewJWT(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  JWTConfig
+		wantErr bool
+	}{
+		{name: "secret", config: JWTConfig{Secret: testSecret}},
+		{name: "no key", config: JWTConfig{}, wantErr: true},
+		{name: "invalid public key", config: JWTConfig{PublicKey: "not a pem"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJWT(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewJWT error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"go-poc/utils/activity"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDHeaders are read in order, the first valid one becomes the
+// transaction id.
+var requestIDHeaders = []string{RequestIDHeader, "X-Correlation-ID", "X-Transaction-ID"}
+
+// validRequestID fits the transaction_id VARCHAR(100) of the audit logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,100}$`)
+
+// RequestContext starts the activity of a request from its context, so a
+// client disconnect or the server shutdown cancels the work done for it. The
+// transaction id comes from the request headers or is generated, and is
+// echoed in X-Request-ID.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trxID := requestID(c)
+		ctx := activity.WithTransactionID(c.Request.Context(), trxID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, trxID)
+		c.Next()
+	}
+}
+
+func requestID(c *gin.Context) string {
+	for _, header := range requestIDHeaders {
+		if value := c.GetHeader(header); validRequestID.MatchString(value) {
+			return value
+		}
+	}
+
+	return uuid.New().String()
+}
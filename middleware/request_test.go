@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "request id", headers: map[string]string{RequestIDHeader: "abc-123"}, want: "abc-123"},
+		{name: "correlation id", headers: map[string]string{"X-Correlation-ID": "trace:1.2"}, want: "trace:1.2"},
+		{name: "request id first", headers: map[string]string{RequestIDHeader: "first", "X-Transaction-ID": "second"}, want: "first"},
+		{name: "invalid request id falls through", headers: map[string]string{RequestIDHeader: "a b", "X-Transaction-ID": "second"}, want: "second"},
+		{name: "longest id", headers: map[string]string{RequestIDHeader: strings.Repeat("a", 100)}, want: strings.Repeat("a", 100)},
+		{name: "longer than the audit column", headers: map[string]string{RequestIDHeader: strings.Repeat("a", 101)}},
+		{name: "no header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			for header, value := range tt.headers {
+				c.Request.Header.Set(header, value)
+			}
+
+			got := requestID(c)
+			if tt.want == "" {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Fatalf("requestID = %q, want a generated uuid", got)
+				}
+
+				return
+			}
+
+			if got != tt.want {
+				t.Fatalf("requestID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type callerAuthenticator struct {
+	caller *Caller
+}
+
+func (a callerAuthenticator) Authenticate(c *gin.Context) (*Caller, error) {
+	return a.caller, nil
+}
+
+func TestAuthCallerIDLength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		callerID   string
+		wantStatus int
+	}{
+		{name: "short id", callerID: "client", wantStatus: http.StatusOK},
+		{name: "longest id", callerID: strings.Repeat("a", MaxCallerIDLength), wantStatus: http.StatusOK},
+		{name: "longer than the audit column", callerID: strings.Repeat("a", MaxCallerIDLength+1), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", Auth(callerAuthenticator{caller: &Caller{ID: tt.callerID}}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"go-poc/respond"
+	"go-poc/utils/activity"
+)
+
+// ScopeAll grants every scope.
+const ScopeAll = "*"
+
+// HasScope is true when the caller was granted scope or every scope.
+func (caller *Caller) HasScope(scope string) bool {
+	for _, granted := range caller.Scopes {
+		if granted == ScopeAll || granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Require lets the request through only when the caller holds every given
+// scope. It runs after Auth, without an authenticated caller auth is off and
+// the request passes.
+func Require(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := GetCaller(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		missing := []string{}
+		for _, scope := range scopes {
+			if !caller.HasScope(scope) {
+				missing = append(missing, scope)
+			}
+		}
+
+		if len(missing) > 0 {
+			trxID, _ := activity.GetTransactionID(Context(c, "authorize"))
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, "missing scope "+strings.Join(missing, " "))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
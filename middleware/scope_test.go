@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		caller     *Caller
+		scopes     []string
+		wantStatus int
+	}{
+		{name: "auth off", scopes: []string{"channel:write"}, wantStatus: http.StatusOK},
+		{name: "granted scope", caller: &Caller{Scopes: []string{"channel:write"}}, scopes: []string{"channel:write"}, wantStatus: http.StatusOK},
+		{name: "every scope", caller: &Caller{Scopes: []string{ScopeAll}}, scopes: []string{"channel:write", "location:write"}, wantStatus: http.StatusOK},
+		{name: "no scope required", caller: &Caller{}, wantStatus: http.StatusOK},
+		{name: "other scope", caller: &Caller{Scopes: []string{"channel:read"}}, scopes: []string{"channel:write"}, wantStatus: http.StatusForbidden},
+		{name: "one of two scopes", caller: &Caller{Scopes: []string{"channel:write"}}, scopes: []string{"channel:write", "location:write"}, wantStatus: http.StatusForbidden},
+		{name: "no scopes", caller: &Caller{}, scopes: []string{"channel:read"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.caller != nil {
+					c.Set(callerKey, tt.caller)
+				}
+			})
+			router.GET("/", Require(tt.scopes...), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
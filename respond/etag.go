@@ -0,0 +1,61 @@
+package respond
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidETag = errors.New("invalid etag")
+)
+
+// ETag formats an entity version as a strong validator.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseETag reads the version back from an If-Match value, a weak validator
+// is accepted since the version alone identifies the representation.
+func ParseETag(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, ErrInvalidETag
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil {
+		return 0, ErrInvalidETag
+	}
+
+	return version, nil
+}
+
+// MatchETag reports whether an If-None-Match header matches etag, the
+// header may hold a list of validators or the "*" wildcard.
+func MatchETag(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatchVersion reads the version of an If-Match header, nil when the
+// header is absent or the "*" wildcard.
+func IfMatchVersion(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	version, err := ParseETag(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
@@ -0,0 +1,101 @@
+package respond
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr error
+	}{
+		{name: "strong", value: `"3"`, want: 3},
+		{name: "weak", value: `W/"3"`, want: 3},
+		{name: "surrounding spaces", value: ` "12" `, want: 12},
+		{name: "round trip", value: ETag(42), want: 42},
+		{name: "unquoted", value: `3`, wantErr: ErrInvalidETag},
+		{name: "half quoted", value: `"3`, wantErr: ErrInvalidETag},
+		{name: "lone quote", value: `"`, wantErr: ErrInvalidETag},
+		{name: "empty quotes", value: `""`, wantErr: ErrInvalidETag},
+		{name: "not a number", value: `"abc"`, wantErr: ErrInvalidETag},
+		{name: "empty", value: ``, wantErr: ErrInvalidETag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseETag(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseETag(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("ParseETag(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	etag := ETag(3)
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "same", header: `"3"`, want: true},
+		{name: "weak", header: `W/"3"`, want: true},
+		{name: "wildcard", header: `*`, want: true},
+		{name: "in a list", header: `"1", "3"`, want: true},
+		{name: "weak in a list", header: `"1",W/"3"`, want: true},
+		{name: "other version", header: `"4"`, want: false},
+		{name: "other versions", header: `"1", "2"`, want: false},
+		{name: "unquoted", header: `3`, want: false},
+		{name: "empty", header: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchETag(tt.header, etag); got != tt.want {
+				t.Fatalf("MatchETag(%q, %q) = %v, want %v", tt.header, etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    *int
+		wantErr error
+	}{
+		{name: "absent", header: ``},
+		{name: "blank", header: `  `},
+		{name: "wildcard", header: `*`},
+		{name: "version", header: `"7"`, want: intPointer(7)},
+		{name: "weak version", header: `W/"7"`, want: intPointer(7)},
+		{name: "zero", header: `"0"`, want: intPointer(0)},
+		{name: "list", header: `"1", "2"`, wantErr: ErrInvalidETag},
+		{name: "unquoted", header: `7`, wantErr: ErrInvalidETag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IfMatchVersion(tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("IfMatchVersion(%q) error = %v, want %v", tt.header, err, tt.wantErr)
+			}
+
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("IfMatchVersion(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
@@ -5,6 +5,11 @@ var (
 	ErrInternal   = "ErrInternal"
 	ErrBadRequest = "ErrBadRequest"
 	ErrNotFound   = "ErrNotFound"
+
+	ErrPreconditionFailed = "ErrPreconditionFailed"
+	ErrUnauthorized       = "ErrUnauthorized"
+	ErrForbidden          = "ErrForbidden"
+	ErrConflict           = "ErrConflict"
 )
 
 type ErrorAPIModel struct {
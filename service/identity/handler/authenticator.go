@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/usecase"
+)
+
+type clientAuthenticator struct {
+	usecase usecase.Client
+}
+
+// NewClientAuthenticator accepts the X-API-Key of the identity clients, the
+// static keys of AUTH_API_KEYS have no prefix and are left to the next
+// authenticator.
+func NewClientAuthenticator(
+	usecase usecase.Client,
+) middleware.Authenticator {
+	return &clientAuthenticator{
+		usecase: usecase,
+	}
+}
+
+func (a *clientAuthenticator) Authenticate(c *gin.Context) (*middleware.Caller, error) {
+	key := c.GetHeader(middleware.APIKeyHeader)
+	if _, _, ok := model.SplitClientKey(key); !ok {
+		return nil, middleware.ErrNoCredentials
+	}
+
+	clientData, err := a.usecase.Authenticate(c.Request.Context(), key)
+	if err != nil {
+		if err == model.ErrInvalidKey {
+			return nil, fmt.Errorf("%w: %v", middleware.ErrInvalidCredentials, err)
+		}
+
+		return nil, fmt.Errorf("authenticate client error: %v", stacktrace.RootCause(err))
+	}
+
+	return &middleware.Caller{
+		ID:         clientData.Name,
+		Method:     middleware.MethodAPIKey,
+		Scopes:     clientData.Scopes,
+		ChannelIDs: clientData.ChannelIDs,
+	}, nil
+}
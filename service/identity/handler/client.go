@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type ClientHandler struct {
+	usecase usecase.Client
+}
+
+func NewClient(
+	usecase usecase.Client,
+) ClientHandler {
+	return ClientHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ClientHandler) HandleCreate(c *gin.Context) {
+	ctx := middleware.Context(c, "client_create")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.ClientInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, input)
+	data, err := h.usecase.Create(ctx, input)
+	if err != nil {
+		if err == model.ErrClientExists {
+			respond.Error(c, trxID, http.StatusConflict, respond.ErrConflict, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error client create", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusCreated, data)
+}
+
+func (h *ClientHandler) HandleGrant(c *gin.Context) {
+	ctx := middleware.Context(c, "client_grant")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.GrantInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, input)
+	data, err := h.usecase.Grant(ctx, input)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "client not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error client grant", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ClientHandler) HandleRotate(c *gin.Context) {
+	ctx := middleware.Context(c, "client_rotate")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.ClientRotateInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, input)
+	data, err := h.usecase.Rotate(ctx, input.ID)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "client not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error client rotate", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ClientHandler) HandleRevoke(c *gin.Context) {
+	ctx := middleware.Context(c, "client_revoke")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.ClientFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Revoke(ctx, filter)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "client not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error client revoke", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
+
+func (h *ClientHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "client_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.ClientFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error client pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ClientHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "client_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.ClientURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "client not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error client find by id", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+// SessionHandler serves the public login routes, the inputs carry secrets
+// so they are never added to the log payload.
+type SessionHandler struct {
+	usecase usecase.Session
+}
+
+func NewSession(
+	usecase usecase.Session,
+) SessionHandler {
+	return SessionHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *SessionHandler) HandleLogin(c *gin.Context) {
+	ctx := middleware.Context(c, "session_login")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.LoginInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.Login(ctx, input)
+	if err != nil {
+		h.respondError(c, ctx, err, "error session login")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SessionHandler) HandleRefresh(c *gin.Context) {
+	ctx := middleware.Context(c, "session_refresh")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.RefreshInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.Refresh(ctx, input)
+	if err != nil {
+		h.respondError(c, ctx, err, "error session refresh")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SessionHandler) HandleLogout(c *gin.Context) {
+	ctx := middleware.Context(c, "session_logout")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.RefreshInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	if err := h.usecase.Logout(ctx, input); err != nil {
+		h.respondError(c, ctx, err, "error session logout")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
+
+func (h *SessionHandler) respondError(c *gin.Context, ctx context.Context, err error, message string) {
+	trxID, _ := activity.GetTransactionID(ctx)
+	if err == model.ErrInvalidLogin || err == model.ErrInvalidRefreshToken {
+		respond.Error(c, trxID, http.StatusUnauthorized, respond.ErrUnauthorized, err.Error())
+		return
+	}
+
+	if err == model.ErrSigningDisabled {
+		respond.Error(c, trxID, http.StatusServiceUnavailable, respond.ErrInternal, err.Error())
+		return
+	}
+
+	log.WithContext(ctx).Error(message, err)
+	respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type UserHandler struct {
+	usecase usecase.User
+}
+
+func NewUser(
+	usecase usecase.User,
+) UserHandler {
+	return UserHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *UserHandler) HandleRegister(c *gin.Context) {
+	ctx := middleware.Context(c, "user_register")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.UserInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.Register(ctx, input)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidUser) {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+			return
+		}
+
+		if err == model.ErrUserExists {
+			respond.Error(c, trxID, http.StatusConflict, respond.ErrConflict, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error user register", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusCreated, data)
+}
+
+func (h *UserHandler) HandleGrant(c *gin.Context) {
+	ctx := middleware.Context(c, "user_grant")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.GrantInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, input)
+	data, err := h.usecase.Grant(ctx, input)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "user not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error user grant", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *UserHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "user_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.UserFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error user pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *UserHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "user_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.UserURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "user not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error user find by id", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS identity_users
+(
+    id CHAR(36) primary key NOT NULL,
+    username VARCHAR(16) UNIQUE NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    mobile_number VARCHAR(14) NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    scopes VARCHAR(1024) DEFAULT '' NOT NULL,
+    channel_ids TEXT NULL,
+    active BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS identity_clients;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS identity_clients
+(
+    id CHAR(36) primary key NOT NULL,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    key_prefix CHAR(16) UNIQUE NOT NULL,
+    key_hash VARCHAR(255) NOT NULL,
+    scopes VARCHAR(1024) DEFAULT '' NOT NULL,
+    channel_ids TEXT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS identity_sessions;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS identity_sessions
+(
+    id CHAR(36) primary key NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES identity_users(id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+
+CREATE INDEX idx_identity_sessions_user ON identity_sessions (user_id);
\ No newline at end of file
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils"
+)
+
+var (
+	ErrClientExists = errors.New("client name already registered")
+	ErrInvalidKey   = errors.New("invalid api key")
+)
+
+// Client is an API client calling with a key `<prefix>.<secret>`, the
+// prefix finds the client and only the bcrypt hash of the secret is kept.
+type Client struct {
+	ID         uuid.UUID   `json:"id" db:"id"`
+	Name       string      `json:"name" db:"name"`
+	KeyPrefix  string      `json:"key_prefix" db:"key_prefix"`
+	KeyHash    string      `json:"-" db:"key_hash"`
+	Scopes     []string    `json:"scopes" db:"scopes"`
+	ChannelIDs []uuid.UUID `json:"channel_ids" db:"channel_ids"`
+	RevokedAt  *time.Time  `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// NewClient returns the client and its key, the key is not stored and
+// cannot be shown again.
+func NewClient(v ClientInput) (*Client, string, error) {
+	scopes := v.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	client := &Client{
+		ID:         uuid.New(),
+		Name:       v.Name,
+		Scopes:     scopes,
+		ChannelIDs: v.ChannelIDs,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	key, err := client.Rotate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, key, nil
+}
+
+// Rotate replaces the key, the previous one stops working at once.
+func (m *Client) Rotate() (string, error) {
+	prefix := utils.GenerateSecureToken(8)
+	secret := utils.GenerateSecureToken(32)
+	if prefix == "" || secret == "" {
+		return "", errors.New("generate api key error")
+	}
+
+	hash, err := utils.HashPassword(secret)
+	if err != nil {
+		return "", err
+	}
+
+	m.KeyPrefix = prefix
+	m.KeyHash = hash
+	m.UpdatedAt = time.Now()
+
+	return prefix + "." + secret, nil
+}
+
+func (m *Client) Revoke() {
+	now := time.Now()
+	m.RevokedAt = &now
+	m.UpdatedAt = now
+}
+
+func (m *Client) Grant(v GrantInput) {
+	m.Scopes = v.Scopes
+	if m.Scopes == nil {
+		m.Scopes = []string{}
+	}
+	m.ChannelIDs = v.ChannelIDs
+	m.UpdatedAt = time.Now()
+}
+
+// SplitClientKey tells the keys of a Client from the static ones of
+// AUTH_API_KEYS, which have no prefix.
+func SplitClientKey(key string) (prefix, secret string, ok bool) {
+	prefix, secret, ok = strings.Cut(key, ".")
+	if !ok || len(prefix) != 16 || secret == "" {
+		return "", "", false
+	}
+
+	return prefix, secret, true
+}
+
+type ClientInput struct {
+	Name       string      `json:"name" binding:"required"`
+	Scopes     []string    `json:"scopes"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+}
+
+// ClientKey is returned once when a key is created or rotated.
+type ClientKey struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Key  string    `json:"key"`
+}
+
+type ClientFilter struct {
+	IDs   []uuid.UUID `json:"ids"`
+	Names []string    `json:"names"`
+	// IncludeRevoked also returns the revoked clients.
+	IncludeRevoked bool `json:"include_revoked"`
+}
+
+type ClientRotateInput struct {
+	ID uuid.UUID `json:"id" binding:"required"`
+}
+
+type ClientURI struct {
+	ID string `uri:"id" binding:"required"`
+}
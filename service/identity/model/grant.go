@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GrantInput sets the scopes and channels of a user or a client, null
+// channel_ids means every channel.
+type GrantInput struct {
+	ID         uuid.UUID   `json:"id" binding:"required"`
+	Scopes     []string    `json:"scopes"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+	Active     *bool       `json:"active"`
+}
+
+// JoinScopes stores the scopes space separated, the way the scope claim of
+// a JWT carries them.
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func SplitScopes(value string) []string {
+	return strings.Fields(value)
+}
+
+// EncodeChannelIDs keeps nil as NULL so every channel stays apart from no
+// channel at all.
+func EncodeChannelIDs(ids []uuid.UUID) (*string, error) {
+	if ids == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	value := string(data)
+	return &value, nil
+}
+
+func DecodeChannelIDs(value *string) ([]uuid.UUID, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	ids := []uuid.UUID{}
+	if err := json.Unmarshal([]byte(*value), &ids); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
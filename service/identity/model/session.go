@@ -0,0 +1,87 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"go-poc/utils"
+)
+
+const TokenTypeBearer = "Bearer"
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSigningDisabled     = errors.New("token signing is not configured, set AUTH_JWT_SECRET")
+)
+
+// Session is the refresh token of a login, only its sha256 is kept. A
+// refresh revokes it and opens a new one.
+type Session struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NewSession returns the session and its refresh token.
+func NewSession(userID uuid.UUID, ttl time.Duration) (*Session, string, error) {
+	token := utils.GenerateSecureToken(32)
+	if token == "" {
+		return nil, "", errors.New("generate refresh token error")
+	}
+
+	return &Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}, token, nil
+}
+
+func (m *Session) Valid(now time.Time) bool {
+	return m.RevokedAt == nil && now.Before(m.ExpiresAt)
+}
+
+func (m *Session) Revoke() {
+	now := time.Now()
+	m.RevokedAt = &now
+}
+
+// HashToken is enough for the random refresh tokens, bcrypt would only slow
+// down the lookup.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AccessClaims are the claims the JWT authenticator of the middleware reads,
+// null channel_ids means every channel.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Scope      string      `json:"scope"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+}
+
+type LoginInput struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils"
+)
+
+var (
+	ErrInvalidUser  = errors.New("invalid user")
+	ErrUserExists   = errors.New("username or email already registered")
+	ErrInvalidLogin = errors.New("invalid username or password")
+)
+
+// User signs in with a password, a registered user has no scope until one
+// is granted.
+type User struct {
+	ID           uuid.UUID   `json:"id" db:"id"`
+	Username     string      `json:"username" db:"username"`
+	Email        string      `json:"email" db:"email"`
+	MobileNumber *string     `json:"mobile_number" db:"mobile_number"`
+	PasswordHash string      `json:"-" db:"password_hash"`
+	Scopes       []string    `json:"scopes" db:"scopes"`
+	ChannelIDs   []uuid.UUID `json:"channel_ids" db:"channel_ids"`
+	Active       bool        `json:"active" db:"active"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+func NewUser(v UserInput) (*User, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	hash, err := utils.HashPassword(v.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var mobileNumber *string
+	if v.MobileNumber != "" {
+		mobileNumber = &v.MobileNumber
+	}
+
+	return &User{
+		ID:           uuid.New(),
+		Username:     v.Username,
+		Email:        v.Email,
+		MobileNumber: mobileNumber,
+		PasswordHash: hash,
+		Scopes:       []string{},
+		ChannelIDs:   []uuid.UUID{},
+		Active:       true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}, nil
+}
+
+func (m *User) Grant(v GrantInput) {
+	m.Scopes = v.Scopes
+	if m.Scopes == nil {
+		m.Scopes = []string{}
+	}
+	m.ChannelIDs = v.ChannelIDs
+	if v.Active != nil {
+		m.Active = *v.Active
+	}
+	m.UpdatedAt = time.Now()
+}
+
+type UserInput struct {
+	Username     string `json:"username" binding:"required"`
+	Email        string `json:"email" binding:"required"`
+	MobileNumber string `json:"mobile_number"`
+	Password     string `json:"password" binding:"required"`
+}
+
+// Validate runs the username, email, password and mobile number rules of
+// utils, the mobile number is optional.
+func (v UserInput) Validate() error {
+	checks := []error{
+		utils.CheckUsername(v.Username),
+		utils.CheckEmail(v.Email),
+		utils.CheckPassword(v.Password),
+	}
+
+	if v.MobileNumber != "" {
+		checks = append(checks, utils.CheckMobileNumber(v.MobileNumber))
+	}
+
+	for _, err := range checks {
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidUser, err)
+		}
+	}
+
+	return nil
+}
+
+type UserFilter struct {
+	IDs       []uuid.UUID `json:"ids"`
+	Usernames []string    `json:"usernames"`
+	Emails    []string    `json:"emails"`
+}
+
+type UserURI struct {
+	ID string `uri:"id" binding:"required"`
+}
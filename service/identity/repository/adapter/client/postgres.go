@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+)
+
+type postgresRepository struct {
+	db utils.DBExecutor
+}
+
+func NewPostgresRepository(db utils.DBExecutor) port.ClientMainRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Client) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	channelIDs, err := model.EncodeChannelIDs(data.ChannelIDs)
+	if err != nil {
+		return stacktrace.Propagate(err, "encode channel ids error")
+	}
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Insert("identity_clients").Rows(
+		goqu.Record{
+			"id":          data.ID,
+			"name":        data.Name,
+			"key_prefix":  data.KeyPrefix,
+			"key_hash":    data.KeyHash,
+			"scopes":      model.JoinScopes(data.Scopes),
+			"channel_ids": channelIDs,
+			"revoked_at":  data.RevokedAt,
+			"created_at":  data.CreatedAt,
+			"updated_at":  data.UpdatedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) Update(ctx context.Context, data *model.Client) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	channelIDs, err := model.EncodeChannelIDs(data.ChannelIDs)
+	if err != nil {
+		return stacktrace.Propagate(err, "encode channel ids error")
+	}
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("identity_clients").Set(
+		goqu.Record{
+			"key_prefix":  data.KeyPrefix,
+			"key_hash":    data.KeyHash,
+			"scopes":      model.JoinScopes(data.Scopes),
+			"channel_ids": channelIDs,
+			"revoked_at":  data.RevokedAt,
+			"updated_at":  data.UpdatedAt,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Client, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_clients")
+	dataset = dataset.Where(goqu.Ex{"id": id})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	result, err = scanClient(repo.db.QueryRowContext(ctx, query))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *postgresRepository) FindByKeyPrefix(ctx context.Context, prefix string) (result *model.Client, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_clients")
+	dataset = dataset.Where(goqu.Ex{"key_prefix": prefix, "revoked_at": nil})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	result, err = scanClient(repo.db.QueryRowContext(ctx, query))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *postgresRepository) FindByFilter(ctx context.Context, filter model.ClientFilter, lock bool) (result []*model.Client, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_clients")
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	clients := []*model.Client{}
+	for res.Next() {
+		item, err := scanClient(res)
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return clients, nil
+}
+
+func (repo *postgresRepository) FindPage(ctx context.Context, filter model.ClientFilter, offset, limit int64) (result []*model.Client, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_clients")
+	dataset = repo.addFilter(dataset, filter).Order(goqu.C("created_at").Asc()).Offset(uint(offset)).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	clients := []*model.Client{}
+	for res.Next() {
+		item, err := scanClient(res)
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return clients, nil
+}
+
+func (repo *postgresRepository) FindTotalByFilter(ctx context.Context, filter model.ClientFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_clients")
+	dataset = dataset.Select(goqu.COUNT("*"))
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "dataset error")
+	}
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "scan error")
+	}
+
+	return total, nil
+}
+
+func (repo *postgresRepository) addFilter(dataset *goqu.SelectDataset, filter model.ClientFilter) *goqu.SelectDataset {
+	if len(filter.IDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	}
+
+	if len(filter.Names) != 0 {
+		dataset = dataset.Where(goqu.Ex{"name": filter.Names})
+	}
+
+	if !filter.IncludeRevoked {
+		dataset = dataset.Where(goqu.Ex{"revoked_at": nil})
+	}
+
+	return dataset
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"go-poc/service/identity/model"
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClient reads a `SELECT *` row, scopes and channel_ids are stored as
+// text.
+func scanClient(row scanner) (*model.Client, error) {
+	item := &model.Client{}
+	var scopes string
+	var channelIDs *string
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.KeyPrefix,
+		&item.KeyHash,
+		&scopes,
+		&channelIDs,
+		&item.RevokedAt,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Scopes = model.SplitScopes(scopes)
+	item.ChannelIDs, err = model.DecodeChannelIDs(channelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+)
+
+type postgresRepository struct {
+	db utils.DBExecutor
+}
+
+func NewPostgresRepository(db utils.DBExecutor) port.SessionMainRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Session) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Insert("identity_sessions").Rows(
+		goqu.Record{
+			"id":         data.ID,
+			"user_id":    data.UserID,
+			"token_hash": data.TokenHash,
+			"expires_at": data.ExpiresAt,
+			"revoked_at": data.RevokedAt,
+			"created_at": data.CreatedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) Update(ctx context.Context, data *model.Session) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("identity_sessions").Set(
+		goqu.Record{
+			"expires_at": data.ExpiresAt,
+			"revoked_at": data.RevokedAt,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) FindByTokenHash(ctx context.Context, hash string, lock bool) (result *model.Session, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("identity_sessions")
+	dataset = dataset.Where(goqu.Ex{"token_hash": hash})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	row := repo.db.QueryRowContext(ctx, query)
+	result = &model.Session{}
+	err = row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.TokenHash,
+		&result.ExpiresAt,
+		&result.RevokedAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *postgresRepository) RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("identity_sessions").Set(
+		goqu.Record{
+			"revoked_at": at,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"user_id": userID, "revoked_at": nil})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
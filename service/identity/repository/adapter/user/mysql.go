@@ -0,0 +1,232 @@
+package user
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+)
+
+type mysqlRepository struct {
+	db utils.DBExecutor
+}
+
+func NewMySQLRepository(db utils.DBExecutor) port.UserMainRepository {
+	return &mysqlRepository{
+		db: db,
+	}
+}
+
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.User) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	channelIDs, err := model.EncodeChannelIDs(data.ChannelIDs)
+	if err != nil {
+		return stacktrace.Propagate(err, "encode channel ids error")
+	}
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Insert("identity_users").Rows(
+		goqu.Record{
+			"id":            data.ID,
+			"username":      data.Username,
+			"email":         data.Email,
+			"mobile_number": data.MobileNumber,
+			"password_hash": data.PasswordHash,
+			"scopes":        model.JoinScopes(data.Scopes),
+			"channel_ids":   channelIDs,
+			"active":        data.Active,
+			"created_at":    data.CreatedAt,
+			"updated_at":    data.UpdatedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) Update(ctx context.Context, data *model.User) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	channelIDs, err := model.EncodeChannelIDs(data.ChannelIDs)
+	if err != nil {
+		return stacktrace.Propagate(err, "encode channel ids error")
+	}
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("identity_users").Set(
+		goqu.Record{
+			"email":         data.Email,
+			"mobile_number": data.MobileNumber,
+			"password_hash": data.PasswordHash,
+			"scopes":        model.JoinScopes(data.Scopes),
+			"channel_ids":   channelIDs,
+			"active":        data.Active,
+			"updated_at":    data.UpdatedAt,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.User, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("identity_users")
+	dataset = dataset.Where(goqu.Ex{"id": id})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	result, err = scanUser(repo.db.QueryRowContext(ctx, query))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *mysqlRepository) FindByFilter(ctx context.Context, filter model.UserFilter, lock bool) (result []*model.User, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("identity_users")
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	users := []*model.User{}
+	for res.Next() {
+		item, err := scanUser(res)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return users, nil
+}
+
+func (repo *mysqlRepository) FindPage(ctx context.Context, filter model.UserFilter, offset, limit int64) (result []*model.User, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("identity_users")
+	dataset = repo.addFilter(dataset, filter).Order(goqu.C("created_at").Asc()).Offset(uint(offset)).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	users := []*model.User{}
+	for res.Next() {
+		item, err := scanUser(res)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return users, nil
+}
+
+func (repo *mysqlRepository) FindTotalByFilter(ctx context.Context, filter model.UserFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("identity_users")
+	dataset = dataset.Select(goqu.COUNT("*"))
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "dataset error")
+	}
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "scan error")
+	}
+
+	return total, nil
+}
+
+func (repo *mysqlRepository) addFilter(dataset *goqu.SelectDataset, filter model.UserFilter) *goqu.SelectDataset {
+	if len(filter.IDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	}
+
+	if len(filter.Usernames) != 0 {
+		dataset = dataset.Where(goqu.Ex{"username": filter.Usernames})
+	}
+
+	if len(filter.Emails) != 0 {
+		dataset = dataset.Where(goqu.Ex{"email": filter.Emails})
+	}
+
+	return dataset
+}
@@ -0,0 +1,39 @@
+package user
+
+import (
+	"go-poc/service/identity/model"
+)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a `SELECT *` row, scopes and channel_ids are stored as text.
+func scanUser(row scanner) (*model.User, error) {
+	item := &model.User{}
+	var scopes string
+	var channelIDs *string
+	err := row.Scan(
+		&item.ID,
+		&item.Username,
+		&item.Email,
+		&item.MobileNumber,
+		&item.PasswordHash,
+		&scopes,
+		&channelIDs,
+		&item.Active,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Scopes = model.SplitScopes(scopes)
+	item.ChannelIDs, err = model.DecodeChannelIDs(channelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/identity/model"
+)
+
+type ClientMainRepository interface {
+	Create(ctx context.Context, data *model.Client) error
+	Update(ctx context.Context, data *model.Client) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Client, error)
+	FindByKeyPrefix(ctx context.Context, prefix string) (*model.Client, error)
+	FindByFilter(ctx context.Context, filter model.ClientFilter, lock bool) ([]*model.Client, error)
+	FindPage(ctx context.Context, filter model.ClientFilter, offset, limit int64) ([]*model.Client, error)
+	FindTotalByFilter(ctx context.Context, filter model.ClientFilter) (int64, error)
+}
@@ -0,0 +1,12 @@
+package port
+
+import "context"
+
+type InTransaction func(repoRegistry MainRepository) (interface{}, error)
+
+type MainRepository interface {
+	User() UserMainRepository
+	Client() ClientMainRepository
+	Session() SessionMainRepository
+	DoInTransaction(ctx context.Context, txFunc InTransaction) (out interface{}, err error)
+}
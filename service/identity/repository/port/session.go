@@ -0,0 +1,17 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/identity/model"
+)
+
+type SessionMainRepository interface {
+	Create(ctx context.Context, data *model.Session) error
+	Update(ctx context.Context, data *model.Session) error
+	FindByTokenHash(ctx context.Context, hash string, lock bool) (*model.Session, error)
+	RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
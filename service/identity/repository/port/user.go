@@ -0,0 +1,17 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/identity/model"
+)
+
+type UserMainRepository interface {
+	Create(ctx context.Context, data *model.User) error
+	Update(ctx context.Context, data *model.User) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	FindByFilter(ctx context.Context, filter model.UserFilter, lock bool) ([]*model.User, error)
+	FindPage(ctx context.Context, filter model.UserFilter, offset, limit int64) ([]*model.User, error)
+	FindTotalByFilter(ctx context.Context, filter model.UserFilter) (int64, error)
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/tracing"
+)
+
+type Client interface {
+	Create(ctx context.Context, input model.ClientInput) (*model.ClientKey, error)
+	Grant(ctx context.Context, input model.GrantInput) (*model.Client, error)
+	Rotate(ctx context.Context, id uuid.UUID) (*model.ClientKey, error)
+	Revoke(ctx context.Context, filter model.ClientFilter) error
+	Authenticate(ctx context.Context, key string) (*model.Client, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Client, error)
+	FindPage(ctx context.Context, filter model.ClientFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type verifiedClient struct {
+	client    model.Client
+	expiresAt time.Time
+}
+
+type clientService struct {
+	main port.MainRepository
+	// verified remembers the sha256 of the keys that already matched for
+	// cacheTTL, bcrypt is too slow to run on every request
+	verified sync.Map
+	cacheTTL time.Duration
+}
+
+func NewClient(
+	main port.MainRepository,
+	cfg config.Identity,
+) Client {
+	return withClientMetrics(&clientService{
+		main:     main,
+		cacheTTL: cfg.KeyCacheTTL,
+	})
+}
+
+// Create returns the only copy of the key of the new client.
+func (s *clientService) Create(ctx context.Context, input model.ClientInput) (*model.ClientKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.Create")
+	defer span.End()
+
+	clientData, key, err := model.NewClient(input)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "new client error")
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		clientRepository := repoRegistry.Client()
+		clients, err := clientRepository.FindByFilter(ctx, model.ClientFilter{Names: []string{input.Name}, IncludeRevoked: true}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find client by filter error")
+		}
+
+		if len(clients) > 0 {
+			return nil, model.ErrClientExists
+		}
+
+		if err := clientRepository.Create(ctx, clientData); err != nil {
+			return nil, stacktrace.Propagate(err, "create client error")
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return nil, err
+	}
+
+	return &model.ClientKey{
+		ID:   clientData.ID,
+		Name: clientData.Name,
+		Key:  key,
+	}, nil
+}
+
+func (s *clientService) Grant(ctx context.Context, input model.GrantInput) (*model.Client, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.Grant")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		clientData, err := s.findForUpdate(ctx, repoRegistry, input.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		clientData.Grant(input)
+		if err := repoRegistry.Client().Update(ctx, clientData); err != nil {
+			return nil, stacktrace.Propagate(err, "update client error")
+		}
+
+		return clientData, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	clientData := out.(*model.Client)
+	s.forget(clientData.ID)
+
+	return clientData, nil
+}
+
+// Rotate replaces the key of a client, the previous key stops working on
+// this instance at once and on the others once their cache expires.
+func (s *clientService) Rotate(ctx context.Context, id uuid.UUID) (*model.ClientKey, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.Rotate")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		clientData, err := s.findForUpdate(ctx, repoRegistry, id)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := clientData.Rotate()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "rotate client key error")
+		}
+
+		if err := repoRegistry.Client().Update(ctx, clientData); err != nil {
+			return nil, stacktrace.Propagate(err, "update client error")
+		}
+
+		return &model.ClientKey{
+			ID:   clientData.ID,
+			Name: clientData.Name,
+			Key:  key,
+		}, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	s.forget(id)
+
+	return out.(*model.ClientKey), nil
+}
+
+func (s *clientService) Revoke(ctx context.Context, filter model.ClientFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.Revoke")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		clientRepository := repoRegistry.Client()
+		clients, err := clientRepository.FindByFilter(ctx, model.ClientFilter{IDs: filter.IDs}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find client by filter error")
+		}
+
+		if len(clients) == 0 {
+			return nil, stacktrace.Propagate(sql.ErrNoRows, "client not found")
+		}
+
+		for _, clientData := range clients {
+			clientData.Revoke()
+			if err := clientRepository.Update(ctx, clientData); err != nil {
+				return nil, stacktrace.Propagate(err, "update client error")
+			}
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	for _, id := range filter.IDs {
+		s.forget(id)
+	}
+
+	return nil
+}
+
+// Authenticate returns the active client a key belongs to or
+// model.ErrInvalidKey.
+func (s *clientService) Authenticate(ctx context.Context, key string) (*model.Client, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.Authenticate")
+	defer span.End()
+
+	prefix, secret, ok := model.SplitClientKey(key)
+	if !ok {
+		return nil, model.ErrInvalidKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	digest := hex.EncodeToString(sum[:])
+	if value, ok := s.verified.Load(digest); ok {
+		verified := value.(verifiedClient)
+		if time.Now().Before(verified.expiresAt) {
+			return &verified.client, nil
+		}
+
+		s.verified.Delete(digest)
+	}
+
+	clientData, err := s.main.Client().FindByKeyPrefix(ctx, prefix)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			return nil, model.ErrInvalidKey
+		}
+
+		return nil, stacktrace.Propagate(err, "find client by key prefix error")
+	}
+
+	if !utils.CheckPasswordHash(secret, clientData.KeyHash) {
+		return nil, model.ErrInvalidKey
+	}
+
+	s.verified.Store(digest, verifiedClient{
+		client:    *clientData,
+		expiresAt: time.Now().Add(s.cacheTTL),
+	})
+
+	return clientData, nil
+}
+
+func (s *clientService) FindByID(ctx context.Context, id uuid.UUID) (*model.Client, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.FindByID")
+	defer span.End()
+
+	clientRepository := s.main.Client()
+	clientData, err := clientRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find client by id error")
+	}
+
+	return clientData, nil
+}
+
+func (s *clientService) FindPage(ctx context.Context, filter model.ClientFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Client.FindPage")
+	defer span.End()
+
+	clientRepository := s.main.Client()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := clientRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find client page error")
+	}
+
+	total, err := clientRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total client by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
+
+// findForUpdate locks a client that is not revoked.
+func (s *clientService) findForUpdate(ctx context.Context, repoRegistry port.MainRepository, id uuid.UUID) (*model.Client, error) {
+	clients, err := repoRegistry.Client().FindByFilter(ctx, model.ClientFilter{IDs: []uuid.UUID{id}}, true)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find client by filter error")
+	}
+
+	if len(clients) == 0 {
+		return nil, stacktrace.Propagate(sql.ErrNoRows, "client not found")
+	}
+
+	return clients[0], nil
+}
+
+// forget drops the cached keys of a client so a rotation or a revocation
+// applies on this instance at once.
+func (s *clientService) forget(id uuid.UUID) {
+	s.verified.Range(func(digest, value interface{}) bool {
+		if value.(verifiedClient).client.ID == id {
+			s.verified.Delete(digest)
+		}
+
+		return true
+	})
+}
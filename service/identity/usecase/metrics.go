@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/identity/model"
+	"go-poc/utils"
+	"go-poc/utils/metrics"
+)
+
+type clientMetrics struct {
+	next Client
+}
+
+// withClientMetrics counts the calls of each Client method by outcome.
+func withClientMetrics(next Client) Client {
+	return &clientMetrics{
+		next: next,
+	}
+}
+
+func (m *clientMetrics) Create(ctx context.Context, input model.ClientInput) (*model.ClientKey, error) {
+	out, err := m.next.Create(ctx, input)
+	metrics.ObserveUsecase("identity", "client", "create", 0, err)
+
+	return out, err
+}
+
+func (m *clientMetrics) Grant(ctx context.Context, input model.GrantInput) (*model.Client, error) {
+	out, err := m.next.Grant(ctx, input)
+	metrics.ObserveUsecase("identity", "client", "grant", 0, err)
+
+	return out, err
+}
+
+func (m *clientMetrics) Rotate(ctx context.Context, id uuid.UUID) (*model.ClientKey, error) {
+	out, err := m.next.Rotate(ctx, id)
+	metrics.ObserveUsecase("identity", "client", "rotate", 0, err)
+
+	return out, err
+}
+
+func (m *clientMetrics) Revoke(ctx context.Context, filter model.ClientFilter) error {
+	err := m.next.Revoke(ctx, filter)
+	metrics.ObserveUsecase("identity", "client", "revoke", 0, err)
+
+	return err
+}
+
+func (m *clientMetrics) Authenticate(ctx context.Context, key string) (*model.Client, error) {
+	out, err := m.next.Authenticate(ctx, key)
+	metrics.ObserveUsecase("identity", "client", "authenticate", 0, err)
+
+	return out, err
+}
+
+func (m *clientMetrics) FindByID(ctx context.Context, id uuid.UUID) (*model.Client, error) {
+	out, err := m.next.FindByID(ctx, id)
+	metrics.ObserveUsecase("identity", "client", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *clientMetrics) FindPage(ctx context.Context, filter model.ClientFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("identity", "client", "find_page", 0, err)
+
+	return out, err
+}
+
+type sessionMetrics struct {
+	next Session
+}
+
+// withSessionMetrics counts the calls of each Session method by outcome.
+func withSessionMetrics(next Session) Session {
+	return &sessionMetrics{
+		next: next,
+	}
+}
+
+func (m *sessionMetrics) Login(ctx context.Context, input model.LoginInput) (*model.Token, error) {
+	out, err := m.next.Login(ctx, input)
+	metrics.ObserveUsecase("identity", "session", "login", 0, err)
+
+	return out, err
+}
+
+func (m *sessionMetrics) Refresh(ctx context.Context, input model.RefreshInput) (*model.Token, error) {
+	out, err := m.next.Refresh(ctx, input)
+	metrics.ObserveUsecase("identity", "session", "refresh", 0, err)
+
+	return out, err
+}
+
+func (m *sessionMetrics) Logout(ctx context.Context, input model.RefreshInput) error {
+	err := m.next.Logout(ctx, input)
+	metrics.ObserveUsecase("identity", "session", "logout", 0, err)
+
+	return err
+}
+
+type userMetrics struct {
+	next User
+}
+
+// withUserMetrics counts the calls of each User method by outcome.
+func withUserMetrics(next User) User {
+	return &userMetrics{
+		next: next,
+	}
+}
+
+func (m *userMetrics) Register(ctx context.Context, input model.UserInput) (*model.User, error) {
+	out, err := m.next.Register(ctx, input)
+	metrics.ObserveUsecase("identity", "user", "register", 0, err)
+
+	return out, err
+}
+
+func (m *userMetrics) Grant(ctx context.Context, input model.GrantInput) (*model.User, error) {
+	out, err := m.next.Grant(ctx, input)
+	metrics.ObserveUsecase("identity", "user", "grant", 0, err)
+
+	return out, err
+}
+
+func (m *userMetrics) FindByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	out, err := m.next.FindByID(ctx, id)
+	metrics.ObserveUsecase("identity", "user", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *userMetrics) FindPage(ctx context.Context, filter model.UserFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("identity", "user", "find_page", 0, err)
+
+	return out, err
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/tracing"
+)
+
+// dummyPasswordHash is the bcrypt of a discarded random password at the cost
+// of utils.HashPassword, an unknown username is checked against it.
+const dummyPasswordHash = "$2a$14$xXNJUxW/FNmSVy/K73EVruc/ThBiuahZFIfhJCwU.EPtmVp0Yeagq"
+
+type Session interface {
+	Login(ctx context.Context, input model.LoginInput) (*model.Token, error)
+	Refresh(ctx context.Context, input model.RefreshInput) (*model.Token, error)
+	Logout(ctx context.Context, input model.RefreshInput) error
+}
+
+type sessionService struct {
+	main       port.MainRepository
+	secret     []byte
+	issuer     string
+	audience   string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewSession signs the access tokens with the JWT secret of auth so the JWT
+// authenticator accepts them.
+func NewSession(
+	main port.MainRepository,
+	cfg config.Identity,
+	auth config.Auth,
+) Session {
+	return withSessionMetrics(&sessionService{
+		main:       main,
+		secret:     []byte(auth.JWTSecret),
+		issuer:     auth.JWTIssuer,
+		audience:   auth.JWTAudience,
+		accessTTL:  cfg.AccessTokenTTL,
+		refreshTTL: cfg.RefreshTokenTTL,
+	})
+}
+
+func (s *sessionService) Login(ctx context.Context, input model.LoginInput) (*model.Token, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Session.Login")
+	defer span.End()
+
+	if len(s.secret) == 0 {
+		return nil, model.ErrSigningDisabled
+	}
+
+	users, err := s.main.User().FindByFilter(ctx, model.UserFilter{Usernames: []string{input.Username}}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find user by filter error")
+	}
+
+	// the password is always checked, an unknown or inactive user answers
+	// as slowly as a wrong password and no username can be told apart
+	passwordHash := dummyPasswordHash
+	if len(users) > 0 {
+		passwordHash = users[0].PasswordHash
+	}
+
+	if !utils.CheckPasswordHash(input.Password, passwordHash) || len(users) == 0 || !users[0].Active {
+		return nil, model.ErrInvalidLogin
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		return s.issue(ctx, repoRegistry, users[0])
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(*model.Token), nil
+}
+
+// Refresh trades a refresh token for a new pair, the user is read again so
+// a grant or a deactivation applies from the next refresh.
+func (s *sessionService) Refresh(ctx context.Context, input model.RefreshInput) (*model.Token, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.Session.Refresh")
+	defer span.End()
+
+	if len(s.secret) == 0 {
+		return nil, model.ErrSigningDisabled
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		sessionData, err := s.revoke(ctx, repoRegistry, input.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+
+		userData, err := repoRegistry.User().FindByID(ctx, sessionData.UserID)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find user by id error")
+		}
+
+		if !userData.Active {
+			return nil, model.ErrInvalidRefreshToken
+		}
+
+		return s.issue(ctx, repoRegistry, userData)
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(*model.Token), nil
+}
+
+func (s *sessionService) Logout(ctx context.Context, input model.RefreshInput) error {
+	ctx, span := tracing.StartSpan(ctx, "identity.Session.Logout")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		return s.revoke(ctx, repoRegistry, input.RefreshToken)
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// revoke closes the session of a refresh token still valid.
+func (s *sessionService) revoke(ctx context.Context, repoRegistry port.MainRepository, refreshToken string) (*model.Session, error) {
+	sessionRepository := repoRegistry.Session()
+	sessionData, err := sessionRepository.FindByTokenHash(ctx, model.HashToken(refreshToken), true)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			return nil, model.ErrInvalidRefreshToken
+		}
+
+		return nil, stacktrace.Propagate(err, "find session by token error")
+	}
+
+	if !sessionData.Valid(time.Now()) {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	sessionData.Revoke()
+	if err := sessionRepository.Update(ctx, sessionData); err != nil {
+		return nil, stacktrace.Propagate(err, "update session error")
+	}
+
+	return sessionData, nil
+}
+
+// issue opens a session and signs an access token carrying the scopes and
+// channels of the user.
+func (s *sessionService) issue(ctx context.Context, repoRegistry port.MainRepository, userData *model.User) (*model.Token, error) {
+	sessionData, refreshToken, err := model.NewSession(userData.ID, s.refreshTTL)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "new session error")
+	}
+
+	if err := repoRegistry.Session().Create(ctx, sessionData); err != nil {
+		return nil, stacktrace.Propagate(err, "create session error")
+	}
+
+	now := time.Now()
+	claims := model.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userData.Username,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+		Scope:      model.JoinScopes(userData.Scopes),
+		ChannelIDs: userData.ChannelIDs,
+	}
+
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "sign access token error")
+	}
+
+	return &model.Token{
+		AccessToken:  accessToken,
+		TokenType:    model.TokenTypeBearer,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
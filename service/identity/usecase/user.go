@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/identity/model"
+	"go-poc/service/identity/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/tracing"
+)
+
+type User interface {
+	Register(ctx context.Context, input model.UserInput) (*model.User, error)
+	Grant(ctx context.Context, input model.GrantInput) (*model.User, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	FindPage(ctx context.Context, filter model.UserFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type userService struct {
+	main port.MainRepository
+}
+
+func NewUser(
+	main port.MainRepository,
+) User {
+	return withUserMetrics(&userService{
+		main: main,
+	})
+}
+
+// Register validates the input with the utils checks, the user starts
+// active without any scope.
+func (s *userService) Register(ctx context.Context, input model.UserInput) (*model.User, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.User.Register")
+	defer span.End()
+
+	userData, err := model.NewUser(input)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidUser) {
+			return nil, err
+		}
+
+		return nil, stacktrace.Propagate(err, "new user error")
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		userRepository := repoRegistry.User()
+
+		for _, filter := range []model.UserFilter{{Usernames: []string{input.Username}}, {Emails: []string{input.Email}}} {
+			users, err := userRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find user by filter error")
+			}
+
+			if len(users) > 0 {
+				return nil, model.ErrUserExists
+			}
+		}
+
+		if err := userRepository.Create(ctx, userData); err != nil {
+			return nil, stacktrace.Propagate(err, "create user error")
+		}
+
+		return userData, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return nil, err
+	}
+
+	return userData, nil
+}
+
+// Grant replaces the scopes and channels of a user, deactivating it also
+// revokes its refresh tokens.
+func (s *userService) Grant(ctx context.Context, input model.GrantInput) (*model.User, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.User.Grant")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		userRepository := repoRegistry.User()
+		users, err := userRepository.FindByFilter(ctx, model.UserFilter{IDs: []uuid.UUID{input.ID}}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find user by filter error")
+		}
+
+		if len(users) == 0 {
+			return nil, stacktrace.Propagate(sql.ErrNoRows, "user not found")
+		}
+
+		userData := users[0]
+		userData.Grant(input)
+		if err := userRepository.Update(ctx, userData); err != nil {
+			return nil, stacktrace.Propagate(err, "update user error")
+		}
+
+		if !userData.Active {
+			if err := repoRegistry.Session().RevokeByUser(ctx, userData.ID, time.Now()); err != nil {
+				return nil, stacktrace.Propagate(err, "revoke session error")
+			}
+		}
+
+		return userData, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(*model.User), nil
+}
+
+func (s *userService) FindByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.User.FindByID")
+	defer span.End()
+
+	userRepository := s.main.User()
+	userData, err := userRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find user by id error")
+	}
+
+	return userData, nil
+}
+
+func (s *userService) FindPage(ctx context.Context, filter model.UserFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "identity.User.FindPage")
+	defer span.End()
+
+	userRepository := s.main.User()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := userRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find user page error")
+	}
+
+	total, err := userRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total user by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"database/sql"
 	"net/http"
 	"strconv"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
 
+	"go-poc/middleware"
 	"go-poc/respond"
 	"go-poc/service/inventory/model"
 	"go-poc/service/inventory/usecase"
@@ -28,7 +30,7 @@ func NewLocation(
 }
 
 func (h *LocationHandler) HandleUpsert(c *gin.Context) {
-	ctx := activity.NewContext("location_upsert")
+	ctx := middleware.Context(c, "location_upsert")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	var inputs []model.LocationInput
@@ -37,9 +39,35 @@ func (h *LocationHandler) HandleUpsert(c *gin.Context) {
 		return
 	}
 
+	// If-Match turns the upsert of a single location into a conditional update
+	version, err := respond.IfMatchVersion(c.GetHeader("If-Match"))
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	if version != nil {
+		if len(inputs) != 1 {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "if-match needs a single item")
+			return
+		}
+
+		inputs[0].Version = version
+	}
+
 	outputs, err := h.usecase.Upsert(ctx, inputs)
 	if err != nil {
 		if len(outputs) > 0 {
+			if err == model.ErrVersionConflict {
+				status := http.StatusConflict
+				if version != nil {
+					status = http.StatusPreconditionFailed
+				}
+
+				respond.Invalid(c, trxID, status, outputs)
+				return
+			}
+
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error location upsert %v", outputs))
 			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
 			return
@@ -54,7 +82,7 @@ func (h *LocationHandler) HandleUpsert(c *gin.Context) {
 }
 
 func (h *LocationHandler) HandleAllByFilter(c *gin.Context) {
-	ctx := activity.NewContext("location_all_by_filter")
+	ctx := middleware.Context(c, "location_all_by_filter")
 	trxID, _ := activity.GetTransactionID(ctx)
 	filter := model.LocationFilter{}
 	if err := c.BindJSON(&filter); err != nil {
@@ -63,7 +91,7 @@ func (h *LocationHandler) HandleAllByFilter(c *gin.Context) {
 	}
 
 	ctx = activity.WithPayload(ctx, filter)
-	items, err := h.usecase.FindByFilter(filter)
+	items, err := h.usecase.FindByFilter(ctx, filter)
 	if err != nil {
 		log.WithContext(ctx).Error("error location all by filter", err)
 		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
@@ -79,7 +107,7 @@ func (h *LocationHandler) HandleAllByFilter(c *gin.Context) {
 }
 
 func (h *LocationHandler) HandlePagination(c *gin.Context) {
-	ctx := activity.NewContext("location_pagination")
+	ctx := middleware.Context(c, "location_pagination")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	page := 1
@@ -98,7 +126,7 @@ func (h *LocationHandler) HandlePagination(c *gin.Context) {
 		return
 	}
 
-	data, err := h.usecase.FindPage(filter, int64(page), int64(limit))
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
 	if err != nil {
 		log.WithContext(ctx).Error("error location pagination", err)
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
@@ -109,7 +137,7 @@ func (h *LocationHandler) HandlePagination(c *gin.Context) {
 }
 
 func (h *LocationHandler) HandleFindByID(c *gin.Context) {
-	ctx := activity.NewContext("location_find_by_id")
+	ctx := middleware.Context(c, "location_find_by_id")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	uri := model.LocationURI{}
@@ -124,18 +152,92 @@ func (h *LocationHandler) HandleFindByID(c *gin.Context) {
 		return
 	}
 
-	data, err := h.usecase.FindByID(id)
+	data, err := h.usecase.FindByID(ctx, id)
 	if err != nil {
 		log.WithContext(ctx).Error("error location find by id", err)
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
 		return
 	}
 
+	etag := respond.ETag(data.Version)
+	c.Header("ETag", etag)
+	if respond.MatchETag(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *LocationHandler) HandleHistory(c *gin.Context) {
+	ctx := middleware.Context(c, "location_history")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.LocationURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	data, err := h.usecase.History(ctx, id, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error location history", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
 	respond.Success(c, trxID, http.StatusOK, data)
 }
 
+func (h *LocationHandler) HandleRestore(c *gin.Context) {
+	ctx := middleware.Context(c, "location_restore")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.LocationFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Restore(ctx, filter)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "location not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error location restore", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
+
 func (h *LocationHandler) HandleDelete(c *gin.Context) {
-	ctx := activity.NewContext("location_delete")
+	ctx := middleware.Context(c, "location_delete")
 	trxID, _ := activity.GetTransactionID(ctx)
 	filter := model.LocationFilter{}
 	if err := c.BindJSON(&filter); err != nil {
@@ -150,8 +252,34 @@ func (h *LocationHandler) HandleDelete(c *gin.Context) {
 		return
 	}
 
-	err := h.usecase.Delete(filter)
+	version, err := respond.IfMatchVersion(c.GetHeader("If-Match"))
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	if version != nil {
+		if len(filter.IDs) != 1 {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "if-match needs a single id")
+			return
+		}
+
+		err = h.usecase.DeleteIfVersion(ctx, filter.IDs[0], *version)
+	} else {
+		err = h.usecase.Delete(ctx, filter)
+	}
+
 	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "location not found")
+			return
+		}
+
+		if err == model.ErrVersionConflict {
+			respond.Error(c, trxID, http.StatusPreconditionFailed, respond.ErrPreconditionFailed, err.Error())
+			return
+		}
+
 		log.WithContext(ctx).Error("error location delete", err)
 		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
 		return
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/inventory/model"
+	"go-poc/service/inventory/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type SourcingHandler struct {
+	usecase usecase.Sourcing
+}
+
+func NewSourcing(
+	usecase usecase.Sourcing,
+) SourcingHandler {
+	return SourcingHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *SourcingHandler) HandleUpsert(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_upsert")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	var inputs []model.SourcingInput
+	if err := c.BindJSON(&inputs); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	outputs, err := h.usecase.Upsert(ctx, inputs)
+	if err != nil {
+		if len(outputs) > 0 {
+			if err == model.ErrVersionConflict {
+				respond.Invalid(c, trxID, http.StatusConflict, outputs)
+				return
+			}
+
+			// resending the batch cannot succeed, the caller has to fix it
+			if err == model.ErrDuplicateSourcing {
+				respond.Invalid(c, trxID, http.StatusBadRequest, outputs)
+				return
+			}
+
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error sourcing upsert %v", outputs))
+			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
+			return
+		}
+
+		log.WithContext(ctx).Error("error sourcing upsert", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusCreated, nil)
+}
+
+func (h *SourcingHandler) HandleAllByFilter(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_all_by_filter")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.SourcingFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+	items, err := h.usecase.FindByFilter(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error sourcing all by filter", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	if len(items) == 0 {
+		respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "sourcing not found")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, items)
+}
+
+func (h *SourcingHandler) HandleStock(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_stock")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.SourcingFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+	items, err := h.usecase.FindStock(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error sourcing stock", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	if len(items) == 0 {
+		respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "sourcing not found")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, items)
+}
+
+func (h *SourcingHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.SourcingFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error sourcing pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SourcingHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.SourcingURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		log.WithContext(ctx).Error("error sourcing find by id", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SourcingHandler) HandleDelete(c *gin.Context) {
+	ctx := middleware.Context(c, "sourcing_delete")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.SourcingFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Delete(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error sourcing delete", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
+
+func (h *SourcingHandler) HandleReserve(c *gin.Context) {
+	h.handleAdjustQty(c, "sourcing_reserve", h.usecase.Reserve)
+}
+
+func (h *SourcingHandler) HandleRelease(c *gin.Context) {
+	h.handleAdjustQty(c, "sourcing_release", h.usecase.Release)
+}
+
+func (h *SourcingHandler) HandleCommit(c *gin.Context) {
+	h.handleAdjustQty(c, "sourcing_commit", h.usecase.Commit)
+}
+
+func (h *SourcingHandler) handleAdjustQty(
+	c *gin.Context,
+	action string,
+	adjust func(ctx context.Context, inputs []model.SourcingQtyInput) ([]model.SourcingOutput, error),
+) {
+	ctx := middleware.Context(c, action)
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	var inputs []model.SourcingQtyInput
+	if err := c.BindJSON(&inputs); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, inputs)
+
+	if len(inputs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "inputs empty")
+		return
+	}
+
+	outputs, err := adjust(ctx, inputs)
+	if err != nil {
+		if len(outputs) > 0 {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error %s %v", action, outputs))
+			respond.Invalid(c, trxID, http.StatusUnprocessableEntity, outputs)
+			return
+		}
+
+		log.WithContext(ctx).Error("error "+action, err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
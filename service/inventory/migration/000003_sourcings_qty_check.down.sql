@@ -0,0 +1 @@
+ALTER TABLE sourcings DROP CONSTRAINT chk_sourcings_qty;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- rows written before the check may break it, normalize them first so the
+-- constraint can be added: reserved stays within the total and saleable is
+-- what is left
+UPDATE sourcings SET qty_total = 0 WHERE qty_total < 0;
+UPDATE sourcings SET qty_reserved = 0 WHERE qty_reserved < 0;
+UPDATE sourcings SET qty_reserved = qty_total WHERE qty_reserved > qty_total;
+UPDATE sourcings SET qty_saleable = qty_total - qty_reserved WHERE qty_saleable <> qty_total - qty_reserved;
+
+ALTER TABLE sourcings ADD CONSTRAINT chk_sourcings_qty CHECK (qty_reserved >= 0 AND qty_saleable >= 0 AND qty_saleable = qty_total - qty_reserved);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS sourcings_sku
+(
+    id CHAR(36) primary key NOT NULL,
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    qty_total INT NOT NULL,
+    qty_reserved INT NOT NULL,
+    qty_saleable INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+INSERT INTO sourcings_sku (id, sku, qty_total, qty_reserved, qty_saleable, created_at, updated_at)
+SELECT MIN(id), sku, SUM(qty_total), SUM(qty_reserved), SUM(qty_saleable), MIN(created_at), MAX(updated_at)
+FROM sourcings
+GROUP BY sku;
+
+DROP TABLE sourcings;
+ALTER TABLE sourcings_sku RENAME TO sourcings;
+ALTER TABLE sourcings ADD CONSTRAINT chk_sourcings_qty CHECK (qty_reserved >= 0 AND qty_saleable >= 0 AND qty_saleable = qty_total - qty_reserved);
@@ -0,0 +1,32 @@
+ALTER TABLE sourcings DROP CONSTRAINT chk_sourcings_qty;
+
+CREATE TABLE IF NOT EXISTS sourcings_location
+(
+    id CHAR(36) primary key NOT NULL,
+    location_id CHAR(36) NOT NULL,
+    sku VARCHAR(100) NOT NULL,
+    qty_total INT NOT NULL,
+    qty_reserved INT NOT NULL,
+    qty_saleable INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT uq_sourcings_location_sku UNIQUE (location_id, sku),
+    CONSTRAINT fk_sourcings_location FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE RESTRICT ON UPDATE RESTRICT,
+    CONSTRAINT chk_sourcings_qty CHECK (qty_reserved >= 0 AND qty_saleable >= 0 AND qty_saleable = qty_total - qty_reserved)
+);
+
+-- existing stock has no location yet, park it in a DEFAULT location
+INSERT INTO locations (id, code, created_at, updated_at)
+SELECT '00000000-0000-0000-0000-000000000000', 'DEFAULT', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM sourcings
+WHERE NOT EXISTS (SELECT 1 FROM locations WHERE code = 'DEFAULT')
+LIMIT 1;
+
+INSERT INTO sourcings_location (id, location_id, sku, qty_total, qty_reserved, qty_saleable, created_at, updated_at)
+SELECT s.id, l.id, s.sku, s.qty_total, s.qty_reserved, s.qty_saleable, s.created_at, s.updated_at
+FROM sourcings s
+CROSS JOIN locations l
+WHERE l.code = 'DEFAULT';
+
+DROP TABLE sourcings;
+ALTER TABLE sourcings_location RENAME TO sourcings;
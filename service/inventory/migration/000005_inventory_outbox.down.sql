@@ -0,0 +1 @@
+DROP TABLE IF EXISTS inventory_outbox;
\ No newline at end of file
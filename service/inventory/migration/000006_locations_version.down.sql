@@ -0,0 +1 @@
+ALTER TABLE locations DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE sourcings DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE locations ADD COLUMN deleted_at TIMESTAMP NULL;
\ No newline at end of file
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAuditSnapshot(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  *string
+	}{
+		{name: "untyped nil"},
+		{name: "nil pointer", value: (*Location)(nil)},
+		{name: "value", value: map[string]int{"version": 2}, want: snapshotOf(`{"version":2}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auditSnapshot(tt.value)
+			if err != nil {
+				t.Fatalf("auditSnapshot: %v", err)
+			}
+
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("auditSnapshot = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuditCreate(t *testing.T) {
+	audit, err := NewAudit(context.Background(), EntityLocation, uuid.New(), AuditActionCreate, (*Location)(nil), &Location{})
+	if err != nil {
+		t.Fatalf("NewAudit: %v", err)
+	}
+
+	if audit.Before != nil {
+		t.Fatalf("before = %s, want NULL", *audit.Before)
+	}
+
+	if audit.After == nil {
+		t.Fatalf("after is NULL, want the created row")
+	}
+}
+
+func snapshotOf(value string) *string {
+	return &value
+}
@@ -1,46 +1,84 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrVersionConflict     = errors.New("version conflict")
+	ErrLocationDeleted     = errors.New("location is deleted, restore it")
+	ErrLocationCodeDeleted = errors.New("code belongs to a deleted location, restore or purge it")
+)
+
 type Location struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Code      string    `json:"code" db:"code"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Code      string     `json:"code" db:"code"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	Version   int        `json:"version" db:"version"`
+	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"`
 }
 
-func NewLocation(v LocationInput) *Location {
+// NewLocation keeps the input id when one is given so fixtures and clients
+// can upsert with stable ids.
+// NewLocation creates the location under a new id. keepID keeps the input one
+// instead, only the seeder asks for it so the fixtures can refer to each
+// other.
+func NewLocation(v LocationInput, keepID bool) *Location {
+	id := uuid.New()
+	if keepID && v.ID != uuid.Nil {
+		id = v.ID
+	}
+
 	return &Location{
-		ID:        uuid.New(),
+		ID:        id,
 		Code:      v.Code,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		Version:   1,
 	}
 }
 
 func (m *Location) Update(v LocationInput) {
 	m.Code = v.Code
 	m.UpdatedAt = time.Now()
+	m.Version++
+}
+
+// CheckVersion fails when the client sent the version it read and the row
+// has moved on since.
+func (m *Location) CheckVersion(v LocationInput) error {
+	if v.Version != nil && *v.Version != m.Version {
+		return ErrVersionConflict
+	}
+
+	return nil
 }
 
 type LocationInput struct {
 	ID   uuid.UUID `json:"id"`
 	Code string    `json:"code" binding:"required"`
+	// Version is the version the client read, when set the update only
+	// applies while the row is still at that version.
+	Version *int `json:"version"`
 }
 
 type LocationOutput struct {
-	ID      uuid.UUID `json:"id"`
-	Code    string    `json:"code"`
-	Message string    `json:"message"`
+	ID       uuid.UUID `json:"id"`
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	Conflict bool      `json:"conflict,omitempty"`
+	Version  int       `json:"version,omitempty"`
 }
 
 type LocationFilter struct {
 	IDs   []uuid.UUID `json:"ids"`
 	Codes []string    `json:"codes"`
+	// IncludeDeleted also returns the soft deleted rows.
+	IncludeDeleted bool `json:"include_deleted"`
 }
 
 type LocationURI struct {
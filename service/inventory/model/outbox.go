@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils/event"
+)
+
+const (
+	EventLocationUpserted  = "location.upserted"
+	EventLocationDeleted   = "location.deleted"
+	EventLocationRestored  = "location.restored"
+	EventSourcingUpserted  = "sourcing.upserted"
+	EventSourcingDeleted   = "sourcing.deleted"
+	EventSourcingReserved  = "sourcing.reserved"
+	EventSourcingReleased  = "sourcing.released"
+	EventSourcingCommitted = "sourcing.committed"
+)
+
+type Outbox struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	EventType   string     `json:"event_type" db:"event_type"`
+	AggregateID uuid.UUID  `json:"aggregate_id" db:"aggregate_id"`
+	Payload     string     `json:"payload" db:"payload"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at" db:"delivered_at"`
+}
+
+func NewOutbox(eventType string, aggregateID uuid.UUID, payload interface{}) (*Outbox, error) {
+	value, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outbox{
+		ID:          uuid.New(),
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		Payload:     string(value),
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+func (m *Outbox) Event(service string) event.Event {
+	return event.Event{
+		ID:          m.ID,
+		Service:     service,
+		Type:        m.EventType,
+		AggregateID: m.AggregateID,
+		Payload:     json.RawMessage(m.Payload),
+		OccurredAt:  m.CreatedAt,
+	}
+}
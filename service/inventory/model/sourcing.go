@@ -1,57 +1,181 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidQty           = errors.New("qty must be greater than zero")
+	ErrInsufficientStock    = errors.New("insufficient saleable stock")
+	ErrInsufficientReserved = errors.New("insufficient reserved stock")
+	ErrSourcingNotFound     = errors.New("sourcing not found")
+	ErrDuplicateSourcing    = errors.New("sourcing given more than once in the batch")
+)
+
 type Sourcing struct {
 	ID          uuid.UUID `json:"id" db:"id"`
+	LocationID  uuid.UUID `json:"location_id" db:"location_id"`
 	SKU         string    `json:"sku" db:"sku"`
 	QtyTotal    int       `json:"qty_total" db:"qty_total"`
 	QtyReserved int       `json:"qty_reserved" db:"qty_reserved"`
 	QtySaleable int       `json:"qty_saleable" db:"qty_saleable"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Version     int       `json:"version" db:"version"`
 }
 
 func NewSourcing(v SourcingInput) *Sourcing {
 	return &Sourcing{
 		ID:          uuid.New(),
+		LocationID:  v.LocationID,
 		SKU:         v.SKU,
 		QtyTotal:    v.QtyTotal,
-		QtyReserved: v.QtyReserved,
-		QtySaleable: v.QtySaleable,
+		QtyReserved: 0,
+		QtySaleable: v.QtyTotal,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		Version:     1,
 	}
 }
 
-func (m *Sourcing) Update(v SourcingInput) {
+// Update replaces the physical stock only, reserved qty is owned by
+// Reserve, Release and Commit so a client cannot overwrite it.
+func (m *Sourcing) Update(v SourcingInput) error {
+	if v.QtyTotal < m.QtyReserved {
+		return ErrInsufficientStock
+	}
+
 	m.QtyTotal = v.QtyTotal
-	m.QtyReserved = v.QtyReserved
-	m.QtySaleable = v.QtySaleable
+	m.calculate()
+	m.Bump()
+	return nil
+}
+
+// Reserve holds qty from the saleable stock for an order. Reserve, Release
+// and Commit leave the version to Bump, a row may take several of them in
+// one write.
+func (m *Sourcing) Reserve(qty int) error {
+	if qty <= 0 {
+		return ErrInvalidQty
+	}
+
+	if m.QtySaleable < qty {
+		return ErrInsufficientStock
+	}
+
+	m.QtyReserved += qty
+	m.calculate()
+	return nil
+}
+
+// Release gives reserved qty back to the saleable stock.
+func (m *Sourcing) Release(qty int) error {
+	if qty <= 0 {
+		return ErrInvalidQty
+	}
+
+	if m.QtyReserved < qty {
+		return ErrInsufficientReserved
+	}
+
+	m.QtyReserved -= qty
+	m.calculate()
+	return nil
+}
+
+// Commit deducts reserved qty from the physical stock once the order is fulfilled.
+func (m *Sourcing) Commit(qty int) error {
+	if qty <= 0 {
+		return ErrInvalidQty
+	}
+
+	if m.QtyReserved < qty {
+		return ErrInsufficientReserved
+	}
+
+	m.QtyTotal -= qty
+	m.QtyReserved -= qty
+	m.calculate()
+	return nil
+}
+
+func (m *Sourcing) calculate() {
+	m.QtySaleable = m.QtyTotal - m.QtyReserved
 	m.UpdatedAt = time.Now()
 }
 
+// Bump moves the sourcing to its next version, once per write.
+func (m *Sourcing) Bump() {
+	m.Version++
+}
+
+// CheckVersion fails when the client sent the version it read and the row
+// has moved on since.
+func (m *Sourcing) CheckVersion(v SourcingInput) error {
+	if v.Version != nil && *v.Version != m.Version {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
 type SourcingInput struct {
-	ID          uuid.UUID `json:"id"`
-	SKU         string    `json:"sku" binding:"required"`
-	QtyTotal    int       `json:"qty_total"`
-	QtyReserved int       `json:"qty_reserved"`
-	QtySaleable int       `json:"qty_saleable"`
+	ID         uuid.UUID `json:"id"`
+	LocationID uuid.UUID `json:"location_id" binding:"required"`
+	SKU        string    `json:"sku" binding:"required"`
+	QtyTotal   int       `json:"qty_total" binding:"gte=0"`
+	// Version is the version the client read, when set the update only
+	// applies while the row is still at that version.
+	Version *int `json:"version"`
+}
+
+// SourcingQtyInput moves stock of a SKU, an empty LocationID lets the
+// usecase spread the qty over every location holding the SKU.
+type SourcingQtyInput struct {
+	LocationID uuid.UUID `json:"location_id"`
+	SKU        string    `json:"sku" binding:"required"`
+	Qty        int       `json:"qty" binding:"required,gt=0"`
 }
 
 type SourcingOutput struct {
-	ID      uuid.UUID `json:"id"`
-	SKU     string    `json:"sku"`
-	Message string    `json:"message"`
+	ID         uuid.UUID `json:"id"`
+	LocationID uuid.UUID `json:"location_id"`
+	SKU        string    `json:"sku"`
+	Message    string    `json:"message"`
+	Conflict   bool      `json:"conflict,omitempty"`
+	Version    int       `json:"version,omitempty"`
 }
 
 type SourcingFilter struct {
-	IDs  []uuid.UUID `json:"ids"`
-	SKUs []string    `json:"skus"`
+	IDs         []uuid.UUID `json:"ids"`
+	LocationIDs []uuid.UUID `json:"location_ids"`
+	SKUs        []string    `json:"skus"`
+}
+
+// SourcingStock is the stock of a SKU summed over every location.
+type SourcingStock struct {
+	SKU         string      `json:"sku"`
+	QtyTotal    int         `json:"qty_total"`
+	QtyReserved int         `json:"qty_reserved"`
+	QtySaleable int         `json:"qty_saleable"`
+	Locations   []*Sourcing `json:"locations"`
+}
+
+func NewSourcingStock(sku string) *SourcingStock {
+	return &SourcingStock{
+		SKU:       sku,
+		Locations: []*Sourcing{},
+	}
+}
+
+func (m *SourcingStock) Add(v *Sourcing) {
+	m.QtyTotal += v.QtyTotal
+	m.QtyReserved += v.QtyReserved
+	m.QtySaleable += v.QtySaleable
+	m.Locations = append(m.Locations, v)
 }
 
 type SourcingURI struct {
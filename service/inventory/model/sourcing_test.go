@@ -0,0 +1,96 @@
+package model
+
+import (
+	"testing"
+)
+
+type sourcingQty struct {
+	total    int
+	reserved int
+	saleable int
+}
+
+func TestSourcingQty(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   sourcingQty
+		apply   func(m *Sourcing, qty int) error
+		qty     int
+		wantErr error
+		want    sourcingQty
+	}{
+		{name: "reserve", start: sourcingQty{10, 2, 8}, apply: (*Sourcing).Reserve, qty: 5, want: sourcingQty{10, 7, 3}},
+		{name: "reserve every saleable", start: sourcingQty{10, 2, 8}, apply: (*Sourcing).Reserve, qty: 8, want: sourcingQty{10, 10, 0}},
+		{name: "reserve past saleable", start: sourcingQty{10, 2, 8}, apply: (*Sourcing).Reserve, qty: 9, wantErr: ErrInsufficientStock, want: sourcingQty{10, 2, 8}},
+		{name: "reserve nothing", start: sourcingQty{10, 2, 8}, apply: (*Sourcing).Reserve, qty: 0, wantErr: ErrInvalidQty, want: sourcingQty{10, 2, 8}},
+		{name: "reserve negative", start: sourcingQty{10, 2, 8}, apply: (*Sourcing).Reserve, qty: -1, wantErr: ErrInvalidQty, want: sourcingQty{10, 2, 8}},
+		{name: "release", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Release, qty: 3, want: sourcingQty{10, 1, 9}},
+		{name: "release every reserved", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Release, qty: 4, want: sourcingQty{10, 0, 10}},
+		{name: "release past reserved", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Release, qty: 5, wantErr: ErrInsufficientReserved, want: sourcingQty{10, 4, 6}},
+		{name: "release nothing", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Release, qty: 0, wantErr: ErrInvalidQty, want: sourcingQty{10, 4, 6}},
+		{name: "commit", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Commit, qty: 3, want: sourcingQty{7, 1, 6}},
+		{name: "commit every reserved", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Commit, qty: 4, want: sourcingQty{6, 0, 6}},
+		{name: "commit past reserved", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Commit, qty: 5, wantErr: ErrInsufficientReserved, want: sourcingQty{10, 4, 6}},
+		{name: "commit nothing", start: sourcingQty{10, 4, 6}, apply: (*Sourcing).Commit, qty: 0, wantErr: ErrInvalidQty, want: sourcingQty{10, 4, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourcing := Sourcing{
+				QtyTotal:    tt.start.total,
+				QtyReserved: tt.start.reserved,
+				QtySaleable: tt.start.saleable,
+				Version:     1,
+			}
+
+			if err := tt.apply(&sourcing, tt.qty); err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := sourcingQty{sourcing.QtyTotal, sourcing.QtyReserved, sourcing.QtySaleable}
+			if got != tt.want {
+				t.Fatalf("qty = %+v, want %+v", got, tt.want)
+			}
+
+			// the version moves once per write, with Bump
+			if sourcing.Version != 1 {
+				t.Fatalf("version = %d, want 1", sourcing.Version)
+			}
+		})
+	}
+}
+
+func TestSourcingUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		start       sourcingQty
+		qtyTotal    int
+		wantErr     error
+		want        sourcingQty
+		wantVersion int
+	}{
+		{name: "more stock", start: sourcingQty{10, 4, 6}, qtyTotal: 20, want: sourcingQty{20, 4, 16}, wantVersion: 2},
+		{name: "down to reserved", start: sourcingQty{10, 4, 6}, qtyTotal: 4, want: sourcingQty{4, 4, 0}, wantVersion: 2},
+		{name: "below reserved", start: sourcingQty{10, 4, 6}, qtyTotal: 3, wantErr: ErrInsufficientStock, want: sourcingQty{10, 4, 6}, wantVersion: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourcing := Sourcing{
+				QtyTotal:    tt.start.total,
+				QtyReserved: tt.start.reserved,
+				QtySaleable: tt.start.saleable,
+				Version:     1,
+			}
+
+			if err := sourcing.Update(SourcingInput{QtyTotal: tt.qtyTotal}); err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := sourcingQty{sourcing.QtyTotal, sourcing.QtyReserved, sourcing.QtySaleable}
+			if got != tt.want || sourcing.Version != tt.wantVersion {
+				t.Fatalf("qty = %+v version %d, want %+v version %d", got, sourcing.Version, tt.want, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package location
+
+import (
+	"go-poc/utils/cachekey"
+)
+
+// cacheKeys is the keyspace of model.Location, see cachekey.Keyspace for the version.
+var cacheKeys = cachekey.New("inventory", "location", 1)
@@ -1,13 +1,17 @@
 package location
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/google/uuid"
 	"github.com/rainycape/memcache"
+	"go.opentelemetry.io/otel/trace"
 
 	"go-poc/service/inventory/model"
 	"go-poc/service/inventory/repository/port"
+	"go-poc/utils/metrics"
+	"go-poc/utils/tracing"
 )
 
 type memcacheRepository struct {
@@ -20,13 +24,16 @@ func NewMemcacheRepository(db *memcache.Client) port.LocationCacheRepository {
 	}
 }
 
-func (repo *memcacheRepository) Set(data *model.Location) error {
+func (repo *memcacheRepository) Set(ctx context.Context, data *model.Location) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache set", tracing.CacheMemcache)
+	defer func() { tracing.End(span, err) }()
+
 	dataMarshal, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	err = repo.db.Set(&memcache.Item{Key: data.ID.String(), Value: dataMarshal})
+	err = repo.db.Set(&memcache.Item{Key: cacheKeys.Key(data.ID), Value: dataMarshal})
 	if err != nil {
 		return err
 	}
@@ -34,8 +41,14 @@ func (repo *memcacheRepository) Set(data *model.Location) error {
 	return nil
 }
 
-func (repo *memcacheRepository) Get(id uuid.UUID) (data *model.Location, err error) {
-	result, err := repo.db.Get(id.String())
+func (repo *memcacheRepository) Get(ctx context.Context, id uuid.UUID) (data *model.Location, err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache get", tracing.CacheMemcache)
+	defer func() {
+		metrics.ObserveCacheLookup(cacheKeys.Service(), cacheKeys.Entity(), "memcache", err, memcache.ErrCacheMiss)
+		tracing.EndLookup(span, err, memcache.ErrCacheMiss)
+	}()
+
+	result, err := repo.db.Get(cacheKeys.Key(id))
 	if err != nil {
 		return nil, err
 	}
@@ -48,8 +61,11 @@ func (repo *memcacheRepository) Get(id uuid.UUID) (data *model.Location, err err
 	return data, nil
 }
 
-func (repo *memcacheRepository) Delete(id uuid.UUID) error {
-	err := repo.db.Delete(id.String())
+func (repo *memcacheRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache delete", tracing.CacheMemcache)
+	defer func() { tracing.End(span, err) }()
+
+	err = repo.db.Delete(cacheKeys.Key(id))
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,9 @@
 package location
 
 import (
+	"context"
+	"time"
+
 	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
 	"github.com/google/uuid"
@@ -21,7 +24,10 @@ func NewMySQLRepository(db utils.DBExecutor) port.LocationMainRepository {
 	}
 }
 
-func (repo *mysqlRepository) Create(data *model.Location) error {
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.Location) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Insert("locations").Rows(
 		goqu.Record{
@@ -29,6 +35,8 @@ func (repo *mysqlRepository) Create(data *model.Location) error {
 			"code":       data.Code,
 			"created_at": data.CreatedAt,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+			"deleted_at": data.DeletedAt,
 		},
 	)
 
@@ -37,12 +45,34 @@ func (repo *mysqlRepository) Create(data *model.Location) error {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
-	_, err = stmt.Exec()
+	return nil
+}
+
+func (repo *mysqlRepository) Update(ctx context.Context, data *model.Location) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("locations").Set(
+		goqu.Record{
+			"code":       data.Code,
+			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
 		return stacktrace.Propagate(err, "exec error")
 	}
@@ -50,51 +80,65 @@ func (repo *mysqlRepository) Create(data *model.Location) error {
 	return nil
 }
 
-func (repo *mysqlRepository) Update(data *model.Location) error {
+func (repo *mysqlRepository) UpdateIfVersion(ctx context.Context, data *model.Location, version int) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Update("locations").Set(
 		goqu.Record{
 			"code":       data.Code,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
 		},
 	)
-	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+	dataset = dataset.Where(goqu.Ex{"id": data.ID, "version": version})
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	res, err := repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
-	_, err = stmt.Exec()
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return stacktrace.Propagate(err, "exec error")
+		return stacktrace.Propagate(err, "rows affected error")
+	}
+
+	// another writer moved the version on since the row was read
+	if affected == 0 {
+		return model.ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (repo *mysqlRepository) FindByID(id uuid.UUID) (result *model.Location, err error) {
+func (repo *mysqlRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("locations")
-	dataset = dataset.Where(goqu.Ex{"id": id})
+	dataset = dataset.Where(goqu.Ex{"id": id}, goqu.C("deleted_at").IsNull())
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	row := repo.db.QueryRow(query)
+	row := repo.db.QueryRowContext(ctx, query)
 	result = &model.Location{}
 	err = row.Scan(
 		&result.ID,
 		&result.Code,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
+		&result.DeletedAt,
 	)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "scan error")
@@ -103,7 +147,10 @@ func (repo *mysqlRepository) FindByID(id uuid.UUID) (result *model.Location, err
 	return result, nil
 }
 
-func (repo *mysqlRepository) FindByFilter(filter model.LocationFilter, lock bool) (result []*model.Location, err error) {
+func (repo *mysqlRepository) FindByFilter(ctx context.Context, filter model.LocationFilter, lock bool) (result []*model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("locations")
 	dataset = repo.addFilter(dataset, filter)
@@ -117,10 +164,11 @@ func (repo *mysqlRepository) FindByFilter(filter model.LocationFilter, lock bool
 		query += " FOR UPDATE"
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	locations := []*model.Location{}
 	for res.Next() {
@@ -130,6 +178,8 @@ func (repo *mysqlRepository) FindByFilter(filter model.LocationFilter, lock bool
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -138,10 +188,17 @@ func (repo *mysqlRepository) FindByFilter(filter model.LocationFilter, lock bool
 		locations = append(locations, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return locations, nil
 }
 
-func (repo *mysqlRepository) FindPage(filter model.LocationFilter, offset, limit int64) (result []*model.Location, err error) {
+func (repo *mysqlRepository) FindPage(ctx context.Context, filter model.LocationFilter, offset, limit int64) (result []*model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("locations")
 	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
@@ -151,10 +208,11 @@ func (repo *mysqlRepository) FindPage(filter model.LocationFilter, offset, limit
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	locations := []*model.Location{}
 	for res.Next() {
@@ -164,6 +222,8 @@ func (repo *mysqlRepository) FindPage(filter model.LocationFilter, offset, limit
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -172,10 +232,17 @@ func (repo *mysqlRepository) FindPage(filter model.LocationFilter, offset, limit
 		locations = append(locations, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return locations, nil
 }
 
-func (repo *mysqlRepository) FindTotalByFilter(filter model.LocationFilter) (total int64, err error) {
+func (repo *mysqlRepository) FindTotalByFilter(ctx context.Context, filter model.LocationFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("locations")
 	dataset = dataset.Select(goqu.COUNT("*"))
@@ -186,7 +253,7 @@ func (repo *mysqlRepository) FindTotalByFilter(filter model.LocationFilter) (tot
 		return 0, stacktrace.Propagate(err, "dataset error")
 	}
 
-	err = repo.db.QueryRow(query).Scan(&total)
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
 	if err != nil {
 		return 0, stacktrace.Propagate(err, "scan error")
 	}
@@ -194,19 +261,76 @@ func (repo *mysqlRepository) FindTotalByFilter(filter model.LocationFilter) (tot
 	return total, nil
 }
 
-func (repo *mysqlRepository) Delete(filter model.LocationFilter) error {
+// Delete tombstones the rows, they stay out of every query until restored
+// or purged.
+func (repo *mysqlRepository) Delete(ctx context.Context, filter model.LocationFilter) error {
+	return repo.setDeletedAt(ctx, filter, time.Now())
+}
+
+func (repo *mysqlRepository) Restore(ctx context.Context, filter model.LocationFilter) error {
+	return repo.setDeletedAt(ctx, filter, nil)
+}
+
+// Purge removes the rows tombstoned before the given time for good, a
+// location still holding sourcings is kept until they are moved.
+func (repo *mysqlRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Delete("locations")
+	dataset = dataset.Where(
+		goqu.C("deleted_at").Lt(before),
+		goqu.L("NOT EXISTS ?", dialect.From("sourcings").Select(goqu.L("1")).Where(goqu.I("sourcings.location_id").Eq(goqu.I("locations.id")))),
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "exec error")
+	}
+
+	total, err := res.RowsAffected()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "rows affected error")
+	}
+
+	return total, nil
+}
+
+func (repo *mysqlRepository) setDeletedAt(ctx context.Context, filter model.LocationFilter, deletedAt interface{}) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("locations").Set(
+		goqu.Record{
+			"deleted_at": deletedAt,
+			// a delete or restore is a new version, an ETag read before
+			// it no longer matches
+			"version": goqu.L("version + 1"),
+		},
+	)
 	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	if deletedAt == nil {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNotNull())
+	} else {
+		// keep the first deleted_at so the retention counts from the first delete
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
+	}
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	_, err = repo.db.Query(query)
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "query error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
 	return nil
@@ -221,5 +345,9 @@ func (repo *mysqlRepository) addFilter(dataset *goqu.SelectDataset, filter model
 		dataset = dataset.Where(goqu.Ex{"code": filter.Codes})
 	}
 
+	if !filter.IncludeDeleted {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
+	}
+
 	return dataset
 }
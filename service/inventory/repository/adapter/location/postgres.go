@@ -1,7 +1,9 @@
 package location
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
@@ -23,7 +25,10 @@ func NewPostgresRepository(db utils.DBExecutor) port.LocationMainRepository {
 	}
 }
 
-func (repo *postgresRepository) Create(data *model.Location) error {
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Location) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	fmt.Println("data: ", data)
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Insert("locations").Rows(
@@ -32,6 +37,8 @@ func (repo *postgresRepository) Create(data *model.Location) error {
 			"code":       data.Code,
 			"created_at": data.CreatedAt,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+			"deleted_at": data.DeletedAt,
 		},
 	)
 
@@ -41,12 +48,34 @@ func (repo *postgresRepository) Create(data *model.Location) error {
 	}
 	fmt.Println("query: ", query)
 
-	stmt, err := repo.db.Prepare(query)
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) Update(ctx context.Context, data *model.Location) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("locations").Set(
+		goqu.Record{
+			"code":       data.Code,
+			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	_, err = stmt.Exec()
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
 		return stacktrace.Propagate(err, "exec error")
 	}
@@ -54,51 +83,65 @@ func (repo *postgresRepository) Create(data *model.Location) error {
 	return nil
 }
 
-func (repo *postgresRepository) Update(data *model.Location) error {
+func (repo *postgresRepository) UpdateIfVersion(ctx context.Context, data *model.Location, version int) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Update("locations").Set(
 		goqu.Record{
 			"code":       data.Code,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
 		},
 	)
-	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+	dataset = dataset.Where(goqu.Ex{"id": data.ID, "version": version})
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	res, err := repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
-	_, err = stmt.Exec()
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return stacktrace.Propagate(err, "exec error")
+		return stacktrace.Propagate(err, "rows affected error")
+	}
+
+	// another writer moved the version on since the row was read
+	if affected == 0 {
+		return model.ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (repo *postgresRepository) FindByID(id uuid.UUID) (result *model.Location, err error) {
+func (repo *postgresRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("locations")
-	dataset = dataset.Where(goqu.Ex{"id": id})
+	dataset = dataset.Where(goqu.Ex{"id": id}, goqu.C("deleted_at").IsNull())
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	row := repo.db.QueryRow(query)
+	row := repo.db.QueryRowContext(ctx, query)
 	result = &model.Location{}
 	err = row.Scan(
 		&result.ID,
 		&result.Code,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
+		&result.DeletedAt,
 	)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "scan error")
@@ -107,7 +150,10 @@ func (repo *postgresRepository) FindByID(id uuid.UUID) (result *model.Location,
 	return result, nil
 }
 
-func (repo *postgresRepository) FindByFilter(filter model.LocationFilter, lock bool) (result []*model.Location, err error) {
+func (repo *postgresRepository) FindByFilter(ctx context.Context, filter model.LocationFilter, lock bool) (result []*model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("locations")
 	dataset = repo.addFilter(dataset, filter)
@@ -122,10 +168,11 @@ func (repo *postgresRepository) FindByFilter(filter model.LocationFilter, lock b
 		query += " FOR UPDATE"
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	locations := []*model.Location{}
 	for res.Next() {
@@ -135,6 +182,8 @@ func (repo *postgresRepository) FindByFilter(filter model.LocationFilter, lock b
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -143,10 +192,17 @@ func (repo *postgresRepository) FindByFilter(filter model.LocationFilter, lock b
 		locations = append(locations, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return locations, nil
 }
 
-func (repo *postgresRepository) FindPage(filter model.LocationFilter, offset, limit int64) (result []*model.Location, err error) {
+func (repo *postgresRepository) FindPage(ctx context.Context, filter model.LocationFilter, offset, limit int64) (result []*model.Location, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("locations")
 	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
@@ -156,10 +212,11 @@ func (repo *postgresRepository) FindPage(filter model.LocationFilter, offset, li
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	locations := []*model.Location{}
 	for res.Next() {
@@ -169,6 +226,8 @@ func (repo *postgresRepository) FindPage(filter model.LocationFilter, offset, li
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -177,10 +236,17 @@ func (repo *postgresRepository) FindPage(filter model.LocationFilter, offset, li
 		locations = append(locations, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return locations, nil
 }
 
-func (repo *postgresRepository) FindTotalByFilter(filter model.LocationFilter) (total int64, err error) {
+func (repo *postgresRepository) FindTotalByFilter(ctx context.Context, filter model.LocationFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("locations")
 	dataset = dataset.Select(goqu.COUNT("*"))
@@ -191,7 +257,7 @@ func (repo *postgresRepository) FindTotalByFilter(filter model.LocationFilter) (
 		return 0, stacktrace.Propagate(err, "query error")
 	}
 
-	err = repo.db.QueryRow(query).Scan(&total)
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
 	if err != nil {
 		return 0, stacktrace.Propagate(err, "scan error")
 	}
@@ -199,21 +265,78 @@ func (repo *postgresRepository) FindTotalByFilter(filter model.LocationFilter) (
 	return total, nil
 }
 
-func (repo *postgresRepository) Delete(filter model.LocationFilter) error {
+// Delete tombstones the rows, they stay out of every query until restored
+// or purged.
+func (repo *postgresRepository) Delete(ctx context.Context, filter model.LocationFilter) error {
+	return repo.setDeletedAt(ctx, filter, time.Now())
+}
+
+func (repo *postgresRepository) Restore(ctx context.Context, filter model.LocationFilter) error {
+	return repo.setDeletedAt(ctx, filter, nil)
+}
+
+// Purge removes the rows tombstoned before the given time for good, a
+// location still holding sourcings is kept until they are moved.
+func (repo *postgresRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Delete("locations")
-	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	dataset = dataset.Where(
+		goqu.C("deleted_at").Lt(before),
+		goqu.L("NOT EXISTS ?", dialect.From("sourcings").Select(goqu.L("1")).Where(goqu.I("sourcings.location_id").Eq(goqu.I("locations.id")))),
+	)
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
-		return stacktrace.Propagate(err, "dataset error")
+		return 0, stacktrace.Propagate(err, "query error")
+	}
+
+	res, err := repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "exec error")
+	}
+
+	total, err := res.RowsAffected()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "rows affected error")
+	}
+
+	return total, nil
+}
+
+func (repo *postgresRepository) setDeletedAt(ctx context.Context, filter model.LocationFilter, deletedAt interface{}) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("locations").Set(
+		goqu.Record{
+			"deleted_at": deletedAt,
+			// a delete or restore is a new version, an ETag read before
+			// it no longer matches
+			"version": goqu.L("version + 1"),
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	if deletedAt == nil {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNotNull())
+	} else {
+		// keep the first deleted_at so the retention counts from the first delete
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
 	}
 
-	_, err = repo.db.Query(query)
+	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "query error")
 	}
 
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
 	return nil
 }
 
@@ -226,5 +349,9 @@ func (repo *postgresRepository) addFilter(dataset *goqu.SelectDataset, filter mo
 		dataset = dataset.Where(goqu.Ex{"code": filter.Codes})
 	}
 
+	if !filter.IncludeDeleted {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
+	}
+
 	return dataset
 }
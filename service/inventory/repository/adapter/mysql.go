@@ -1,14 +1,18 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/pkg/errors"
 
+	"go-poc/service/inventory/repository/adapter/audit"
 	"go-poc/service/inventory/repository/adapter/location"
+	"go-poc/service/inventory/repository/adapter/outbox"
 	"go-poc/service/inventory/repository/adapter/sourcing"
 	"go-poc/service/inventory/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/tracing"
 )
 
 type mysqlRegistry struct {
@@ -26,21 +30,35 @@ func (r mysqlRegistry) Location() port.LocationMainRepository {
 	if r.dbexecutor != nil {
 		return location.NewMySQLRepository(r.dbexecutor)
 	}
-	return location.NewMySQLRepository(r.db)
+	return location.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
 }
 
 func (r mysqlRegistry) Sourcing() port.SourcingMainRepository {
 	if r.dbexecutor != nil {
 		return sourcing.NewMySQLRepository(r.dbexecutor)
 	}
-	return sourcing.NewMySQLRepository(r.db)
+	return sourcing.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
 }
 
-func (r mysqlRegistry) DoInTransaction(txFunc port.InTransaction) (out interface{}, err error) {
+func (r mysqlRegistry) Outbox() port.OutboxMainRepository {
+	if r.dbexecutor != nil {
+		return outbox.NewMySQLRepository(r.dbexecutor)
+	}
+	return outbox.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) Audit() port.AuditMainRepository {
+	if r.dbexecutor != nil {
+		return audit.NewMySQLRepository(r.dbexecutor)
+	}
+	return audit.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
 	var tx *sql.Tx
 	registry := r
 	if r.dbexecutor == nil {
-		tx, err = r.db.Begin()
+		tx, err = r.db.BeginTx(ctx, nil)
 		if err != nil {
 			return
 		}
@@ -67,7 +85,7 @@ func (r mysqlRegistry) DoInTransaction(txFunc port.InTransaction) (out interface
 		}()
 		registry = mysqlRegistry{
 			db:         r.db,
-			dbexecutor: tx,
+			dbexecutor: tracing.DB(tx, tracing.DBMySQL),
 		}
 	}
 	out, err = txFunc(registry)
@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/inventory/model"
+	"go-poc/service/inventory/repository/port"
+	"go-poc/utils"
+)
+
+type mysqlRepository struct {
+	db utils.DBExecutor
+}
+
+func NewMySQLRepository(db utils.DBExecutor) port.OutboxMainRepository {
+	return &mysqlRepository{
+		db: db,
+	}
+}
+
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.Outbox) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Insert("inventory_outbox").Rows(
+		goqu.Record{
+			"id":           data.ID,
+			"event_type":   data.EventType,
+			"aggregate_id": data.AggregateID,
+			"payload":      data.Payload,
+			"created_at":   data.CreatedAt,
+			"delivered_at": data.DeliveredAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) FindPending(ctx context.Context, limit int64) (result []*model.Outbox, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("inventory_outbox")
+	dataset = dataset.Where(goqu.C("delivered_at").IsNull()).Order(goqu.C("created_at").Asc()).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	// concurrent relays skip the rows another relay is already publishing
+	query += " FOR UPDATE SKIP LOCKED"
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	outboxes := []*model.Outbox{}
+	for res.Next() {
+		item := &model.Outbox{}
+		err := res.Scan(
+			&item.ID,
+			&item.EventType,
+			&item.AggregateID,
+			&item.Payload,
+			&item.CreatedAt,
+			&item.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		outboxes = append(outboxes, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return outboxes, nil
+}
+
+func (repo *mysqlRepository) MarkDelivered(ctx context.Context, ids []uuid.UUID, deliveredAt time.Time) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("inventory_outbox").Set(
+		goqu.Record{
+			"delivered_at": deliveredAt,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": ids})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
@@ -1,14 +1,18 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/pkg/errors"
 
+	"go-poc/service/inventory/repository/adapter/audit"
 	"go-poc/service/inventory/repository/adapter/location"
+	"go-poc/service/inventory/repository/adapter/outbox"
 	"go-poc/service/inventory/repository/adapter/sourcing"
 	"go-poc/service/inventory/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/tracing"
 )
 
 type postgresRegistry struct {
@@ -26,21 +30,35 @@ func (r postgresRegistry) Location() port.LocationMainRepository {
 	if r.dbexecutor != nil {
 		return location.NewPostgresRepository(r.dbexecutor)
 	}
-	return location.NewPostgresRepository(r.db)
+	return location.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
 }
 
 func (r postgresRegistry) Sourcing() port.SourcingMainRepository {
 	if r.dbexecutor != nil {
-		return sourcing.NewMySQLRepository(r.dbexecutor)
+		return sourcing.NewPostgresRepository(r.dbexecutor)
 	}
-	return sourcing.NewMySQLRepository(r.db)
+	return sourcing.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
 }
 
-func (r postgresRegistry) DoInTransaction(txFunc port.InTransaction) (out interface{}, err error) {
+func (r postgresRegistry) Outbox() port.OutboxMainRepository {
+	if r.dbexecutor != nil {
+		return outbox.NewPostgresRepository(r.dbexecutor)
+	}
+	return outbox.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) Audit() port.AuditMainRepository {
+	if r.dbexecutor != nil {
+		return audit.NewPostgresRepository(r.dbexecutor)
+	}
+	return audit.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
 	var tx *sql.Tx
 	registry := r
 	if r.dbexecutor == nil {
-		tx, err = r.db.Begin()
+		tx, err = r.db.BeginTx(ctx, nil)
 		if err != nil {
 			return
 		}
@@ -67,7 +85,7 @@ func (r postgresRegistry) DoInTransaction(txFunc port.InTransaction) (out interf
 		}()
 		registry = postgresRegistry{
 			db:         r.db,
-			dbexecutor: tx,
+			dbexecutor: tracing.DB(tx, tracing.DBPostgres),
 		}
 	}
 	out, err = txFunc(registry)
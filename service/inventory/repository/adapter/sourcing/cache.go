@@ -0,0 +1,8 @@
+package sourcing
+
+import (
+	"go-poc/utils/cachekey"
+)
+
+// cacheKeys is the keyspace of model.Sourcing, see cachekey.Keyspace for the version.
+var cacheKeys = cachekey.New("inventory", "sourcing", 1)
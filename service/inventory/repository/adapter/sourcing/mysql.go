@@ -1,6 +1,7 @@
 package sourcing
 
 import (
+	"context"
 	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
 	"github.com/google/uuid"
@@ -21,17 +22,22 @@ func NewMySQLRepository(db utils.DBExecutor) port.SourcingMainRepository {
 	}
 }
 
-func (repo *mysqlRepository) Create(data *model.Sourcing) error {
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.Sourcing) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Insert("sourcings").Rows(
 		goqu.Record{
 			"id":           data.ID,
+			"location_id":  data.LocationID,
 			"sku":          data.SKU,
 			"qty_total":    data.QtyTotal,
 			"qty_reserved": data.QtyReserved,
 			"qty_saleable": data.QtySaleable,
 			"created_at":   data.CreatedAt,
 			"updated_at":   data.UpdatedAt,
+			"version":      data.Version,
 		},
 	)
 
@@ -40,12 +46,36 @@ func (repo *mysqlRepository) Create(data *model.Sourcing) error {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) Update(ctx context.Context, data *model.Sourcing) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("sourcings").Set(
+		goqu.Record{
+			"qty_total":    data.QtyTotal,
+			"qty_reserved": data.QtyReserved,
+			"qty_saleable": data.QtySaleable,
+			"updated_at":   data.UpdatedAt,
+			"version":      data.Version,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	_, err = stmt.Exec()
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
 		return stacktrace.Propagate(err, "exec error")
 	}
@@ -53,7 +83,10 @@ func (repo *mysqlRepository) Create(data *model.Sourcing) error {
 	return nil
 }
 
-func (repo *mysqlRepository) Update(data *model.Sourcing) error {
+func (repo *mysqlRepository) UpdateIfVersion(ctx context.Context, data *model.Sourcing, version int) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Update("sourcings").Set(
 		goqu.Record{
@@ -61,29 +94,38 @@ func (repo *mysqlRepository) Update(data *model.Sourcing) error {
 			"qty_reserved": data.QtyReserved,
 			"qty_saleable": data.QtySaleable,
 			"updated_at":   data.UpdatedAt,
+			"version":      data.Version,
 		},
 	)
-	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+	dataset = dataset.Where(goqu.Ex{"id": data.ID, "version": version})
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	res, err := repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
-	_, err = stmt.Exec()
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return stacktrace.Propagate(err, "exec error")
+		return stacktrace.Propagate(err, "rows affected error")
+	}
+
+	// another writer moved the version on since the row was read
+	if affected == 0 {
+		return model.ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (repo *mysqlRepository) FindByID(id uuid.UUID) (result *model.Sourcing, err error) {
+func (repo *mysqlRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Sourcing, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("sourcings")
 	dataset = dataset.Where(goqu.Ex{"id": id})
@@ -93,16 +135,18 @@ func (repo *mysqlRepository) FindByID(id uuid.UUID) (result *model.Sourcing, err
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	row := repo.db.QueryRow(query)
+	row := repo.db.QueryRowContext(ctx, query)
 	result = &model.Sourcing{}
 	err = row.Scan(
 		&result.ID,
+		&result.LocationID,
 		&result.SKU,
 		&result.QtyTotal,
 		&result.QtyReserved,
 		&result.QtySaleable,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
 	)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "scan error")
@@ -111,7 +155,10 @@ func (repo *mysqlRepository) FindByID(id uuid.UUID) (result *model.Sourcing, err
 	return result, nil
 }
 
-func (repo *mysqlRepository) FindByFilter(filter model.SourcingFilter, lock bool) (result []*model.Sourcing, err error) {
+func (repo *mysqlRepository) FindByFilter(ctx context.Context, filter model.SourcingFilter, lock bool) (result []*model.Sourcing, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("sourcings")
 	dataset = repo.addFilter(dataset, filter)
@@ -125,22 +172,25 @@ func (repo *mysqlRepository) FindByFilter(filter model.SourcingFilter, lock bool
 		query += " FOR UPDATE"
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	Sourcings := []*model.Sourcing{}
 	for res.Next() {
 		item := &model.Sourcing{}
 		err := res.Scan(
 			&item.ID,
+			&item.LocationID,
 			&item.SKU,
 			&item.QtyTotal,
 			&item.QtyReserved,
 			&item.QtySaleable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
 		)
 		if err != nil {
 			return nil, err
@@ -149,10 +199,17 @@ func (repo *mysqlRepository) FindByFilter(filter model.SourcingFilter, lock bool
 		Sourcings = append(Sourcings, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return Sourcings, nil
 }
 
-func (repo *mysqlRepository) FindPage(filter model.SourcingFilter, offset, limit int64) (result []*model.Sourcing, err error) {
+func (repo *mysqlRepository) FindPage(ctx context.Context, filter model.SourcingFilter, offset, limit int64) (result []*model.Sourcing, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("sourcings")
 	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
@@ -162,22 +219,25 @@ func (repo *mysqlRepository) FindPage(filter model.SourcingFilter, offset, limit
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	Sourcings := []*model.Sourcing{}
 	for res.Next() {
 		item := &model.Sourcing{}
 		err := res.Scan(
 			&item.ID,
+			&item.LocationID,
 			&item.SKU,
 			&item.QtyTotal,
 			&item.QtyReserved,
 			&item.QtySaleable,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
 		)
 		if err != nil {
 			return nil, err
@@ -186,10 +246,17 @@ func (repo *mysqlRepository) FindPage(filter model.SourcingFilter, offset, limit
 		Sourcings = append(Sourcings, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return Sourcings, nil
 }
 
-func (repo *mysqlRepository) FindTotalByFilter(filter model.SourcingFilter) (total int64, err error) {
+func (repo *mysqlRepository) FindTotalByFilter(ctx context.Context, filter model.SourcingFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.From("sourcings")
 	dataset = dataset.Select(goqu.COUNT("*"))
@@ -200,7 +267,7 @@ func (repo *mysqlRepository) FindTotalByFilter(filter model.SourcingFilter) (tot
 		return 0, stacktrace.Propagate(err, "dataset error")
 	}
 
-	err = repo.db.QueryRow(query).Scan(&total)
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
 	if err != nil {
 		return 0, stacktrace.Propagate(err, "scan error")
 	}
@@ -208,7 +275,10 @@ func (repo *mysqlRepository) FindTotalByFilter(filter model.SourcingFilter) (tot
 	return total, nil
 }
 
-func (repo *mysqlRepository) Delete(filter model.SourcingFilter) error {
+func (repo *mysqlRepository) Delete(ctx context.Context, filter model.SourcingFilter) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("mysql")
 	dataset := dialect.Delete("sourcings")
 	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
@@ -218,9 +288,9 @@ func (repo *mysqlRepository) Delete(filter model.SourcingFilter) error {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	_, err = repo.db.Query(query)
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "query error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
 	return nil
@@ -231,6 +301,10 @@ func (repo *mysqlRepository) addFilter(dataset *goqu.SelectDataset, filter model
 		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
 	}
 
+	if len(filter.LocationIDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"location_id": filter.LocationIDs})
+	}
+
 	if len(filter.SKUs) != 0 {
 		dataset = dataset.Where(goqu.Ex{"sku": filter.SKUs})
 	}
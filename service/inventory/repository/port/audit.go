@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/inventory/model"
+)
+
+type AuditMainRepository interface {
+	Create(ctx context.Context, data *model.Audit) error
+	FindPageByEntity(ctx context.Context, entityType string, entityID uuid.UUID, offset, limit int64) ([]*model.Audit, error)
+	FindTotalByEntity(ctx context.Context, entityType string, entityID uuid.UUID) (int64, error)
+}
@@ -1,23 +1,29 @@
 package port
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 
 	"go-poc/service/inventory/model"
 )
 
 type LocationMainRepository interface {
-	Create(data *model.Location) error
-	Update(data *model.Location) error
-	FindByID(id uuid.UUID) (*model.Location, error)
-	FindByFilter(filter model.LocationFilter, lock bool) ([]*model.Location, error)
-	FindPage(filter model.LocationFilter, offset, limit int64) ([]*model.Location, error)
-	FindTotalByFilter(filter model.LocationFilter) (int64, error)
-	Delete(filter model.LocationFilter) error
+	Create(ctx context.Context, data *model.Location) error
+	Update(ctx context.Context, data *model.Location) error
+	UpdateIfVersion(ctx context.Context, data *model.Location, version int) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Location, error)
+	FindByFilter(ctx context.Context, filter model.LocationFilter, lock bool) ([]*model.Location, error)
+	FindPage(ctx context.Context, filter model.LocationFilter, offset, limit int64) ([]*model.Location, error)
+	FindTotalByFilter(ctx context.Context, filter model.LocationFilter) (int64, error)
+	Delete(ctx context.Context, filter model.LocationFilter) error
+	Restore(ctx context.Context, filter model.LocationFilter) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
 
 type LocationCacheRepository interface {
-	Set(data *model.Location) error
-	Get(id uuid.UUID) (*model.Location, error)
-	Delete(id uuid.UUID) error
+	Set(ctx context.Context, data *model.Location) error
+	Get(ctx context.Context, id uuid.UUID) (*model.Location, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
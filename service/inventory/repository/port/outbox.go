@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/inventory/model"
+	"go-poc/utils/event"
+)
+
+type OutboxMainRepository interface {
+	Create(ctx context.Context, data *model.Outbox) error
+	FindPending(ctx context.Context, limit int64) ([]*model.Outbox, error)
+	MarkDelivered(ctx context.Context, ids []uuid.UUID, deliveredAt time.Time) error
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, events []event.Event) error
+}
@@ -1,11 +1,15 @@
 package port
 
+import "context"
+
 type InTransaction func(repoRegistry MainRepository) (interface{}, error)
 
 type MainRepository interface {
 	Location() LocationMainRepository
 	Sourcing() SourcingMainRepository
-	DoInTransaction(txFunc InTransaction) (out interface{}, err error)
+	Outbox() OutboxMainRepository
+	Audit() AuditMainRepository
+	DoInTransaction(ctx context.Context, txFunc InTransaction) (out interface{}, err error)
 }
 
 type CacheRepository interface {
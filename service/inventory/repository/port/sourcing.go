@@ -1,23 +1,25 @@
 package port
 
 import (
+	"context"
 	"github.com/google/uuid"
 
 	"go-poc/service/inventory/model"
 )
 
 type SourcingMainRepository interface {
-	Create(data *model.Sourcing) error
-	Update(data *model.Sourcing) error
-	FindByID(id uuid.UUID) (*model.Sourcing, error)
-	FindByFilter(filter model.SourcingFilter, lock bool) ([]*model.Sourcing, error)
-	FindPage(filter model.SourcingFilter, offset, limit int64) ([]*model.Sourcing, error)
-	FindTotalByFilter(filter model.SourcingFilter) (int64, error)
-	Delete(filter model.SourcingFilter) error
+	Create(ctx context.Context, data *model.Sourcing) error
+	Update(ctx context.Context, data *model.Sourcing) error
+	UpdateIfVersion(ctx context.Context, data *model.Sourcing, version int) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Sourcing, error)
+	FindByFilter(ctx context.Context, filter model.SourcingFilter, lock bool) ([]*model.Sourcing, error)
+	FindPage(ctx context.Context, filter model.SourcingFilter, offset, limit int64) ([]*model.Sourcing, error)
+	FindTotalByFilter(ctx context.Context, filter model.SourcingFilter) (int64, error)
+	Delete(ctx context.Context, filter model.SourcingFilter) error
 }
 
 type SourcingCacheRepository interface {
-	Set(data *model.Sourcing) error
-	Get(id uuid.UUID) (*model.Sourcing, error)
-	Delete(id uuid.UUID) error
+	Set(ctx context.Context, data *model.Sourcing) error
+	Get(ctx context.Context, id uuid.UUID) (*model.Sourcing, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
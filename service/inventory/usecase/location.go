@@ -2,9 +2,9 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"os"
-	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
@@ -13,39 +13,64 @@ import (
 	"go-poc/service/inventory/model"
 	"go-poc/service/inventory/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/activity"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
 	"go-poc/utils/log"
-)
-
-const (
-	Cachelocation = "cache_location"
+	"go-poc/utils/tracing"
 )
 
 type Location interface {
 	Upsert(ctx context.Context, inputs []model.LocationInput) (outputs []model.LocationOutput, err error)
-	Delete(filter model.LocationFilter) error
-	FindByID(ID uuid.UUID) (*model.Location, error)
-	FindByFilter(filter model.LocationFilter) ([]*model.Location, error)
-	FindPage(filter model.LocationFilter, page, limit int64) (utils.Pagination, error)
+	Delete(ctx context.Context, filter model.LocationFilter) error
+	DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error
+	Restore(ctx context.Context, filter model.LocationFilter) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.Location, error)
+	FindByFilter(ctx context.Context, filter model.LocationFilter) ([]*model.Location, error)
+	FindPage(ctx context.Context, filter model.LocationFilter, page, limit int64) (utils.Pagination, error)
+	History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error)
+	WarmCache(ctx context.Context, batchSize int64) (int, error)
 }
 
-type service struct {
-	main  port.MainRepository
-	cache port.CacheRepository
+type locationService struct {
+	main    port.MainRepository
+	cache   port.CacheRepository
+	workers int
 }
 
 func NewLocation(
 	main port.MainRepository,
 	cache port.CacheRepository,
+	cfg config.Inventory,
 ) Location {
-	return &service{
-		main:  main,
-		cache: cache,
-	}
+	return withLocationMetrics(&locationService{
+		main:    main,
+		cache:   cache,
+		workers: cfg.LocationWorkers,
+	})
 }
 
-func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (outputs []model.LocationOutput, err error) {
+func (s *locationService) Upsert(ctx context.Context, inputs []model.LocationInput) (outputs []model.LocationOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.Upsert")
+	defer span.End()
+
+	// the written locations are cached once committed, so no reader sees
+	// a row that may still be rolled back
+	writtenChan := make(chan *model.Location, len(inputs))
 	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
 		locationRepository := repoRegistry.Location()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		deleted, err := deletedLocationInputs(ctx, locationRepository, inputs)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find deleted location error")
+		}
+
+		if len(deleted) > 0 {
+			return deleted, locationUpsertError(deleted)
+		}
 		ids := []uuid.UUID{}
 
 		for _, input := range inputs {
@@ -58,7 +83,7 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 				IDs: ids,
 			}
 
-			locations, err = locationRepository.FindByFilter(filter, true)
+			locations, err = locationRepository.FindByFilter(ctx, filter, true)
 			if err != nil {
 				return nil, stacktrace.Propagate(err, "find location by filter error")
 			}
@@ -69,13 +94,7 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 			locationMap[locationData.ID] = *locationData
 		}
 
-		upsertLocationWorker := 5
-		if os.Getenv("UPDATE_LOCATION_WORKER") != "" {
-			upsertLocationWorkerEnv, err := strconv.Atoi(os.Getenv("UPDATE_LOCATION_WORKER"))
-			if err == nil {
-				upsertLocationWorker = upsertLocationWorkerEnv
-			}
-		}
+		upsertLocationWorker := s.workers
 
 		outputChan := make(chan model.LocationOutput, len(inputs))
 		workerSemaphore := semaphore.NewWeighted(int64(upsertLocationWorker))
@@ -86,12 +105,51 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 				continue
 			}
 
+			done := lifecycle.Track()
 			go func(inputDataInWorker model.LocationInput) {
+				defer done()
 				defer workerSemaphore.Release(1)
 				if locationData, exist := locationMap[inputDataInWorker.ID]; exist {
+					if err := locationData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.LocationOutput{
+							ID:       locationData.ID,
+							Code:     locationData.Code,
+							Message:  err.Error(),
+							Conflict: true,
+							Version:  locationData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					locationBefore := locationData
 					locationData.Update(inputDataInWorker)
-					err := locationRepository.Update(&locationData)
+					err := updateLocation(ctx, locationRepository, &locationData, inputDataInWorker.Version)
 					if err != nil {
+						output := model.LocationOutput{
+							ID:       locationData.ID,
+							Code:     locationData.Code,
+							Message:  stacktrace.RootCause(err).Error(),
+							Conflict: stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventLocationUpserted, locationData.ID, &locationData); err != nil {
+						output := model.LocationOutput{
+							ID:      locationData.ID,
+							Code:    locationData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityLocation, locationData.ID, model.AuditActionUpdate, &locationBefore, &locationData); err != nil {
 						output := model.LocationOutput{
 							ID:      locationData.ID,
 							Code:    locationData.Code,
@@ -101,10 +159,24 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 						outputChan <- output
 						return
 					}
-					go s.cache.Location().Set(&locationData)
+
+					writtenChan <- &locationData
 				} else {
-					locationData := model.NewLocation(inputDataInWorker)
-					err := locationRepository.Create(locationData)
+					// a version only matches a location that exists
+					if inputDataInWorker.Version != nil {
+						output := model.LocationOutput{
+							ID:       inputDataInWorker.ID,
+							Code:     inputDataInWorker.Code,
+							Message:  model.ErrVersionConflict.Error(),
+							Conflict: true,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					locationData := model.NewLocation(inputDataInWorker, activity.IsSeed(ctx))
+					err := locationRepository.Create(ctx, locationData)
 					if err != nil {
 						output := model.LocationOutput{
 							ID:      locationData.ID,
@@ -115,7 +187,30 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 						outputChan <- output
 						return
 					}
-					go s.cache.Location().Set(locationData)
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventLocationUpserted, locationData.ID, locationData); err != nil {
+						output := model.LocationOutput{
+							ID:      locationData.ID,
+							Code:    locationData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityLocation, locationData.ID, model.AuditActionCreate, nil, locationData); err != nil {
+						output := model.LocationOutput{
+							ID:      locationData.ID,
+							Code:    locationData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- locationData
 				}
 			}(inputData)
 		}
@@ -130,14 +225,14 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 		}
 
 		if len(outputs) > 0 {
-			return outputs, errors.New("internal server error")
+			return outputs, locationUpsertError(outputs)
 		}
 
 		return nil, nil
 	}
 
 	var out interface{}
-	out, err = s.main.DoInTransaction(t)
+	out, err = s.main.DoInTransaction(ctx, t)
 	if err != nil {
 		if out != nil {
 			res := out.([]model.LocationOutput)
@@ -147,39 +242,213 @@ func (s *service) Upsert(ctx context.Context, inputs []model.LocationInput) (out
 		return nil, err
 	}
 
+	close(writtenChan)
+	for locationData := range writtenChan {
+		go lifecycle.Background(s.cache.Location().Set)(ctx, locationData)
+	}
+
 	return nil, nil
 }
 
-func (s *service) Delete(filter model.LocationFilter) error {
-	locationRepository := s.main.Location()
+func (s *locationService) Delete(ctx context.Context, filter model.LocationFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.Delete")
+	defer span.End()
+
+	return s.delete(ctx, filter, nil)
+}
+
+// DeleteIfVersion deletes the location only while it is still at version, the
+// row is locked so no update slips in between the check and the delete.
+func (s *locationService) DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.DeleteIfVersion")
+	defer span.End()
 
-	if err := locationRepository.Delete(filter); err != nil {
-		return stacktrace.Propagate(err, "delete location error")
+	filter := model.LocationFilter{
+		IDs: []uuid.UUID{id},
+	}
+
+	return s.delete(ctx, filter, &version)
+}
+
+func (s *locationService) delete(ctx context.Context, filter model.LocationFilter, version *int) error {
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		locationRepository := repoRegistry.Location()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		// only the live rows matching the filter are deleted, the events
+		// and audit rows are written for those alone
+		locations, err := locationRepository.FindByFilter(ctx, model.LocationFilter{IDs: filter.IDs, Codes: filter.Codes}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find location by filter error")
+		}
+
+		if version != nil {
+			if len(locations) == 0 {
+				return nil, stacktrace.Propagate(sql.ErrNoRows, "location not found")
+			}
+
+			for _, locationData := range locations {
+				if locationData.Version != *version {
+					return nil, model.ErrVersionConflict
+				}
+			}
+		}
+
+		if len(locations) == 0 {
+			return []uuid.UUID{}, nil
+		}
+
+		ids := []uuid.UUID{}
+		for _, locationData := range locations {
+			ids = append(ids, locationData.ID)
+		}
+
+		if err := locationRepository.Delete(ctx, model.LocationFilter{IDs: ids}); err != nil {
+			return nil, stacktrace.Propagate(err, "delete location error")
+		}
+
+		for _, id := range ids {
+			if err := writeOutbox(ctx, outboxRepository, model.EventLocationDeleted, id, map[string]uuid.UUID{"id": id}); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+		}
+
+		deleted, err := locationRepository.FindByFilter(ctx, model.LocationFilter{IDs: ids, IncludeDeleted: true}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find location by filter error")
+		}
+
+		deletedMap := make(map[uuid.UUID]*model.Location)
+		for _, locationData := range deleted {
+			deletedMap[locationData.ID] = locationData
+		}
+
+		for _, locationData := range locations {
+			if err := writeAudit(ctx, auditRepository, model.EntityLocation, locationData.ID, model.AuditActionDelete, locationData, deletedMap[locationData.ID]); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return ids, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	// the rows deleted by code are evicted too, a cached row would keep
+	// serving the version and ETag from before the delete
+	for _, id := range out.([]uuid.UUID) {
+		go lifecycle.Background(s.cache.Location().Delete)(ctx, id)
 	}
 
 	return nil
 }
 
-func (s *service) FindByID(id uuid.UUID) (*model.Location, error) {
-	locationDataCache, err := s.cache.Location().Get(id)
+// Restore brings soft deleted locations back, ids that are not deleted are
+// left as they are.
+func (s *locationService) Restore(ctx context.Context, filter model.LocationFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.Restore")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		locationRepository := repoRegistry.Location()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		found, err := locationRepository.FindByFilter(ctx, model.LocationFilter{IDs: filter.IDs, IncludeDeleted: true}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find location by filter error")
+		}
+
+		deletedMap := make(map[uuid.UUID]*model.Location)
+		ids := []uuid.UUID{}
+		for _, locationData := range found {
+			if locationData.DeletedAt != nil {
+				deletedMap[locationData.ID] = locationData
+				ids = append(ids, locationData.ID)
+			}
+		}
+
+		if len(ids) == 0 {
+			return nil, stacktrace.Propagate(sql.ErrNoRows, "location not found")
+		}
+
+		if err := locationRepository.Restore(ctx, model.LocationFilter{IDs: ids}); err != nil {
+			return nil, stacktrace.Propagate(err, "restore location error")
+		}
+
+		locations, err := locationRepository.FindByFilter(ctx, model.LocationFilter{IDs: ids}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find location by filter error")
+		}
+
+		for _, locationData := range locations {
+			if err := writeOutbox(ctx, outboxRepository, model.EventLocationRestored, locationData.ID, locationData); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+
+			if err := writeAudit(ctx, auditRepository, model.EntityLocation, locationData.ID, model.AuditActionRestore, deletedMap[locationData.ID], locationData); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return locations, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	for _, locationData := range out.([]*model.Location) {
+		go lifecycle.Background(s.cache.Location().Set)(ctx, locationData)
+	}
+
+	return nil
+}
+
+// Purge hard deletes the locations soft deleted before the given time.
+func (s *locationService) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.Purge")
+	defer span.End()
+
+	total, err := s.main.Location().Purge(ctx, before)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "purge location error")
+	}
+
+	return total, nil
+}
+
+func (s *locationService) FindByID(ctx context.Context, id uuid.UUID) (*model.Location, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.FindByID")
+	defer span.End()
+
+	locationDataCache, err := s.cache.Location().Get(ctx, id)
 	if err == nil {
 		return locationDataCache, nil
 	}
 
 	locationRepository := s.main.Location()
-	locationData, err := locationRepository.FindByID(id)
+	locationData, err := locationRepository.FindByID(ctx, id)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "find location by id error")
 	}
 
-	go s.cache.Location().Set(locationData)
+	go lifecycle.Background(s.cache.Location().Set)(ctx, locationData)
 
 	return locationData, nil
 }
 
-func (s *service) FindByFilter(filter model.LocationFilter) ([]*model.Location, error) {
+func (s *locationService) FindByFilter(ctx context.Context, filter model.LocationFilter) ([]*model.Location, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.FindByFilter")
+	defer span.End()
+
 	locationRepository := s.main.Location()
-	results, err := locationRepository.FindByFilter(filter, false)
+	results, err := locationRepository.FindByFilter(ctx, filter, false)
 	if err != nil {
 		return []*model.Location{}, stacktrace.Propagate(err, "find location by filter error")
 	}
@@ -187,19 +456,152 @@ func (s *service) FindByFilter(filter model.LocationFilter) ([]*model.Location,
 	return results, nil
 }
 
-func (s *service) FindPage(filter model.LocationFilter, page, limit int64) (utils.Pagination, error) {
+func (s *locationService) FindPage(ctx context.Context, filter model.LocationFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.FindPage")
+	defer span.End()
+
 	locationRepository := s.main.Location()
 	paginateEmpty := utils.PaginateEmpty()
 
-	data, err := locationRepository.FindPage(filter, utils.GetOffset(page, limit), limit)
+	data, err := locationRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
 	if err != nil {
 		return paginateEmpty, stacktrace.Propagate(err, "find location page error")
 	}
 
-	total, err := locationRepository.FindTotalByFilter(filter)
+	total, err := locationRepository.FindTotalByFilter(ctx, filter)
 	if err != nil {
 		return paginateEmpty, stacktrace.Propagate(err, "find total location by filter error")
 	}
 
 	return utils.PaginatePageLimit(data, total, page, limit), nil
 }
+
+// History pages through the audit log of one location, newest change first.
+func (s *locationService) History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.History")
+	defer span.End()
+
+	auditRepository := s.main.Audit()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := auditRepository.FindPageByEntity(ctx, model.EntityLocation, id, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find location history error")
+	}
+
+	total, err := auditRepository.FindTotalByEntity(ctx, model.EntityLocation, id)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total location history error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
+
+// WarmCache loads every location into the cache, batchSize rows at a time.
+func (s *locationService) WarmCache(ctx context.Context, batchSize int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Location.WarmCache")
+	defer span.End()
+
+	locationRepository := s.main.Location()
+	total := 0
+	for offset := int64(0); ; offset += batchSize {
+		locations, err := locationRepository.FindPage(ctx, model.LocationFilter{}, offset, batchSize)
+		if err != nil {
+			return total, stacktrace.Propagate(err, "find location page error")
+		}
+
+		for _, locationData := range locations {
+			if err := s.cache.Location().Set(ctx, locationData); err != nil {
+				return total, stacktrace.Propagate(err, "set location cache error")
+			}
+		}
+
+		total += len(locations)
+		if int64(len(locations)) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// updateLocation applies the update only while the row is still at the
+// version the client read, when it sent one.
+func updateLocation(ctx context.Context, locationRepository port.LocationMainRepository, locationData *model.Location, version *int) error {
+	if version != nil {
+		return locationRepository.UpdateIfVersion(ctx, locationData, *version)
+	}
+
+	return locationRepository.Update(ctx, locationData)
+}
+
+// deletedLocationInputs answers the inputs reusing the id or the code of a
+// soft deleted location, both stay taken until the location is restored or purged.
+func deletedLocationInputs(ctx context.Context, locationRepository port.LocationMainRepository, inputs []model.LocationInput) ([]model.LocationOutput, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	ids := []uuid.UUID{}
+	codes := []string{}
+	for _, input := range inputs {
+		ids = append(ids, input.ID)
+		codes = append(codes, input.Code)
+	}
+
+	// the filter matches ids and codes together, so each is looked up alone
+	byID, err := locationRepository.FindByFilter(ctx, model.LocationFilter{IDs: ids, IncludeDeleted: true}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find location by filter error")
+	}
+
+	byCode, err := locationRepository.FindByFilter(ctx, model.LocationFilter{Codes: codes, IncludeDeleted: true}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find location by filter error")
+	}
+
+	deletedIDs := make(map[uuid.UUID]bool)
+	for _, locationData := range byID {
+		if locationData.DeletedAt != nil {
+			deletedIDs[locationData.ID] = true
+		}
+	}
+
+	deletedCodes := make(map[string]uuid.UUID)
+	for _, locationData := range byCode {
+		if locationData.DeletedAt != nil {
+			deletedCodes[locationData.Code] = locationData.ID
+		}
+	}
+
+	outputs := []model.LocationOutput{}
+	for _, input := range inputs {
+		message := ""
+		if deletedIDs[input.ID] {
+			message = model.ErrLocationDeleted.Error()
+		} else if id, exist := deletedCodes[input.Code]; exist && id != input.ID {
+			message = model.ErrLocationCodeDeleted.Error()
+		}
+
+		if message != "" {
+			outputs = append(outputs, model.LocationOutput{
+				ID:       input.ID,
+				Code:     input.Code,
+				Message:  message,
+				Conflict: true,
+			})
+		}
+	}
+
+	return outputs, nil
+}
+
+// locationUpsertError reports a version conflict when every failed item is one,
+// any other failure stays an internal error.
+func locationUpsertError(outputs []model.LocationOutput) error {
+	for _, output := range outputs {
+		if !output.Conflict {
+			return errors.New("internal server error")
+		}
+	}
+
+	return model.ErrVersionConflict
+}
@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/inventory/model"
+	"go-poc/utils"
+	"go-poc/utils/metrics"
+)
+
+type locationMetrics struct {
+	next Location
+}
+
+// withLocationMetrics counts the calls of each Location method by outcome.
+func withLocationMetrics(next Location) Location {
+	return &locationMetrics{
+		next: next,
+	}
+}
+
+func (m *locationMetrics) Upsert(ctx context.Context, inputs []model.LocationInput) ([]model.LocationOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("inventory", "location", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *locationMetrics) Delete(ctx context.Context, filter model.LocationFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("inventory", "location", "delete", 0, err)
+
+	return err
+}
+
+func (m *locationMetrics) DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error {
+	err := m.next.DeleteIfVersion(ctx, id, version)
+	metrics.ObserveUsecase("inventory", "location", "delete_if_version", 0, err)
+
+	return err
+}
+
+func (m *locationMetrics) Restore(ctx context.Context, filter model.LocationFilter) error {
+	err := m.next.Restore(ctx, filter)
+	metrics.ObserveUsecase("inventory", "location", "restore", 0, err)
+
+	return err
+}
+
+func (m *locationMetrics) Purge(ctx context.Context, before time.Time) (int64, error) {
+	out, err := m.next.Purge(ctx, before)
+	metrics.ObserveUsecase("inventory", "location", "purge", 0, err)
+
+	return out, err
+}
+
+func (m *locationMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.Location, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("inventory", "location", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *locationMetrics) FindByFilter(ctx context.Context, filter model.LocationFilter) ([]*model.Location, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("inventory", "location", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *locationMetrics) FindPage(ctx context.Context, filter model.LocationFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("inventory", "location", "find_page", 0, err)
+
+	return out, err
+}
+
+func (m *locationMetrics) History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.History(ctx, id, page, limit)
+	metrics.ObserveUsecase("inventory", "location", "history", 0, err)
+
+	return out, err
+}
+
+func (m *locationMetrics) WarmCache(ctx context.Context, batchSize int64) (int, error) {
+	out, err := m.next.WarmCache(ctx, batchSize)
+	metrics.ObserveUsecase("inventory", "location", "warm_cache", 0, err)
+
+	return out, err
+}
+
+type relayMetrics struct {
+	next Relay
+}
+
+// withRelayMetrics counts the calls of each Relay method by outcome.
+func withRelayMetrics(next Relay) Relay {
+	return &relayMetrics{
+		next: next,
+	}
+}
+
+func (m *relayMetrics) Relay(ctx context.Context, limit int64) (int, error) {
+	out, err := m.next.Relay(ctx, limit)
+	metrics.ObserveUsecase("inventory", "relay", "relay", 0, err)
+
+	return out, err
+}
+
+type sourcingMetrics struct {
+	next Sourcing
+}
+
+// withSourcingMetrics counts the calls of each Sourcing method by outcome.
+func withSourcingMetrics(next Sourcing) Sourcing {
+	return &sourcingMetrics{
+		next: next,
+	}
+}
+
+func (m *sourcingMetrics) Upsert(ctx context.Context, inputs []model.SourcingInput) ([]model.SourcingOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("inventory", "sourcing", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *sourcingMetrics) Delete(ctx context.Context, filter model.SourcingFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("inventory", "sourcing", "delete", 0, err)
+
+	return err
+}
+
+func (m *sourcingMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.Sourcing, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("inventory", "sourcing", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *sourcingMetrics) FindByFilter(ctx context.Context, filter model.SourcingFilter) ([]*model.Sourcing, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("inventory", "sourcing", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *sourcingMetrics) FindPage(ctx context.Context, filter model.SourcingFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("inventory", "sourcing", "find_page", 0, err)
+
+	return out, err
+}
+
+func (m *sourcingMetrics) FindStock(ctx context.Context, filter model.SourcingFilter) ([]*model.SourcingStock, error) {
+	out, err := m.next.FindStock(ctx, filter)
+	metrics.ObserveUsecase("inventory", "sourcing", "find_stock", 0, err)
+
+	return out, err
+}
+
+func (m *sourcingMetrics) Reserve(ctx context.Context, inputs []model.SourcingQtyInput) ([]model.SourcingOutput, error) {
+	outputs, err := m.next.Reserve(ctx, inputs)
+	metrics.ObserveUsecase("inventory", "sourcing", "reserve", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *sourcingMetrics) Release(ctx context.Context, inputs []model.SourcingQtyInput) ([]model.SourcingOutput, error) {
+	outputs, err := m.next.Release(ctx, inputs)
+	metrics.ObserveUsecase("inventory", "sourcing", "release", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *sourcingMetrics) Commit(ctx context.Context, inputs []model.SourcingQtyInput) ([]model.SourcingOutput, error) {
+	outputs, err := m.next.Commit(ctx, inputs)
+	metrics.ObserveUsecase("inventory", "sourcing", "commit", len(outputs), err)
+
+	return outputs, err
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/inventory/model"
+	"go-poc/service/inventory/repository/port"
+	"go-poc/utils/event"
+	"go-poc/utils/tracing"
+)
+
+type Relay interface {
+	Relay(ctx context.Context, limit int64) (int, error)
+}
+
+type relayService struct {
+	service   string
+	main      port.MainRepository
+	publisher port.EventPublisher
+}
+
+func NewRelay(
+	service string,
+	main port.MainRepository,
+	publisher port.EventPublisher,
+) Relay {
+	return withRelayMetrics(&relayService{
+		service:   service,
+		main:      main,
+		publisher: publisher,
+	})
+}
+
+// Relay publishes up to limit pending outbox rows and marks them delivered.
+// The rows stay locked until the publisher answers, a failed publish leaves
+// them pending so delivery is at least once.
+func (s *relayService) Relay(ctx context.Context, limit int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Relay.Relay")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		outboxRepository := repoRegistry.Outbox()
+		outboxes, err := outboxRepository.FindPending(ctx, limit)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find pending outbox error")
+		}
+
+		if len(outboxes) == 0 {
+			return 0, nil
+		}
+
+		ids := []uuid.UUID{}
+		events := []event.Event{}
+		for _, outboxData := range outboxes {
+			ids = append(ids, outboxData.ID)
+			events = append(events, outboxData.Event(s.service))
+		}
+
+		if err := s.publisher.Publish(ctx, events); err != nil {
+			return nil, stacktrace.Propagate(err, "publish event error")
+		}
+
+		if err := outboxRepository.MarkDelivered(ctx, ids, time.Now()); err != nil {
+			return nil, stacktrace.Propagate(err, "mark outbox delivered error")
+		}
+
+		return len(outboxes), nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return out.(int), nil
+}
+
+// writeOutbox records an event through the repository of the change it
+// describes, inside DoInTransaction both are committed or rolled back together.
+func writeOutbox(ctx context.Context, outboxRepository port.OutboxMainRepository, eventType string, aggregateID uuid.UUID, payload interface{}) error {
+	outboxData, err := model.NewOutbox(eventType, aggregateID, payload)
+	if err != nil {
+		return stacktrace.Propagate(err, "new outbox error")
+	}
+
+	if err := outboxRepository.Create(ctx, outboxData); err != nil {
+		return stacktrace.Propagate(err, "create outbox error")
+	}
+
+	return nil
+}
@@ -0,0 +1,629 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sync/semaphore"
+
+	"go-poc/service/inventory/model"
+	"go-poc/service/inventory/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+	"go-poc/utils/tracing"
+)
+
+type Sourcing interface {
+	Upsert(ctx context.Context, inputs []model.SourcingInput) (outputs []model.SourcingOutput, err error)
+	Delete(ctx context.Context, filter model.SourcingFilter) error
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.Sourcing, error)
+	FindByFilter(ctx context.Context, filter model.SourcingFilter) ([]*model.Sourcing, error)
+	FindPage(ctx context.Context, filter model.SourcingFilter, page, limit int64) (utils.Pagination, error)
+	FindStock(ctx context.Context, filter model.SourcingFilter) ([]*model.SourcingStock, error)
+	Reserve(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error)
+	Release(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error)
+	Commit(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error)
+}
+
+type sourcingService struct {
+	main    port.MainRepository
+	cache   port.CacheRepository
+	workers int
+}
+
+func NewSourcing(
+	main port.MainRepository,
+	cache port.CacheRepository,
+	cfg config.Inventory,
+) Sourcing {
+	return withSourcingMetrics(&sourcingService{
+		main:    main,
+		cache:   cache,
+		workers: cfg.SourcingWorkers,
+	})
+}
+
+func (s *sourcingService) Upsert(ctx context.Context, inputs []model.SourcingInput) (outputs []model.SourcingOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.Upsert")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		sourcingRepository := repoRegistry.Sourcing()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+		ids := []uuid.UUID{}
+		locationIDs := []uuid.UUID{}
+		skus := []string{}
+
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+			locationIDs = append(locationIDs, input.LocationID)
+			skus = append(skus, input.SKU)
+		}
+
+		sourcings := []*model.Sourcing{}
+		if len(ids) > 0 {
+			filter := model.SourcingFilter{
+				IDs: ids,
+			}
+
+			sourcings, err = sourcingRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find sourcing by filter error")
+			}
+
+			// a sourcing is also identified by its location and sku
+			filter = model.SourcingFilter{
+				LocationIDs: locationIDs,
+				SKUs:        skus,
+			}
+
+			sourcingsByKey, err := sourcingRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find sourcing by filter error")
+			}
+
+			sourcings = append(sourcings, sourcingsByKey...)
+		}
+
+		sourcingMap := make(map[uuid.UUID]model.Sourcing)
+		sourcingKeyMap := make(map[sourcingKey]uuid.UUID)
+		for _, sourcingData := range sourcings {
+			sourcingMap[sourcingData.ID] = *sourcingData
+			sourcingKeyMap[sourcingKey{sourcingData.LocationID, sourcingData.SKU}] = sourcingData.ID
+		}
+
+		// the ids are resolved on a copy, the caller keeps its inputs, and a
+		// sourcing given twice is rejected before two workers race on its row
+		resolved := make([]model.SourcingInput, len(inputs))
+		duplicates := []model.SourcingOutput{}
+		seenIDs := make(map[uuid.UUID]bool)
+		seenKeys := make(map[sourcingKey]bool)
+		for i, input := range inputs {
+			key := sourcingKey{input.LocationID, input.SKU}
+			if _, exist := sourcingMap[input.ID]; !exist {
+				if id, exist := sourcingKeyMap[key]; exist {
+					input.ID = id
+				}
+			}
+
+			if (input.ID != uuid.Nil && seenIDs[input.ID]) || seenKeys[key] {
+				duplicates = append(duplicates, model.SourcingOutput{
+					ID:         input.ID,
+					LocationID: input.LocationID,
+					SKU:        input.SKU,
+					Message:    model.ErrDuplicateSourcing.Error(),
+				})
+			}
+
+			seenIDs[input.ID] = true
+			seenKeys[key] = true
+			resolved[i] = input
+		}
+
+		if len(duplicates) > 0 {
+			return duplicates, model.ErrDuplicateSourcing
+		}
+
+		upsertSourcingWorker := s.workers
+
+		outputChan := make(chan model.SourcingOutput, len(resolved))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertSourcingWorker))
+		for _, inputData := range resolved {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.SourcingInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				if sourcingData, exist := sourcingMap[inputDataInWorker.ID]; exist {
+					if err := sourcingData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    err.Error(),
+							Conflict:   true,
+							Version:    sourcingData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					sourcingBefore := sourcingData
+					if err := sourcingData.Update(inputDataInWorker); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    err.Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					err := updateSourcing(ctx, sourcingRepository, &sourcingData, inputDataInWorker.Version)
+					if err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+							Conflict:   stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventSourcingUpserted, sourcingData.ID, &sourcingData); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntitySourcing, sourcingData.ID, model.AuditActionUpdate, &sourcingBefore, &sourcingData); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.Sourcing().Set)(ctx, &sourcingData)
+				} else {
+					sourcingData := model.NewSourcing(inputDataInWorker)
+					err := sourcingRepository.Create(ctx, sourcingData)
+					if err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventSourcingUpserted, sourcingData.ID, sourcingData); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntitySourcing, sourcingData.ID, model.AuditActionCreate, nil, sourcingData); err != nil {
+						output := model.SourcingOutput{
+							ID:         sourcingData.ID,
+							LocationID: sourcingData.LocationID,
+							SKU:        sourcingData.SKU,
+							Message:    stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.Sourcing().Set)(ctx, sourcingData)
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertSourcingWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, sourcingUpsertError(outputs)
+		}
+
+		return nil, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.SourcingOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *sourcingService) Delete(ctx context.Context, filter model.SourcingFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.Delete")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		sourcingRepository := repoRegistry.Sourcing()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		sourcings, err := sourcingRepository.FindByFilter(ctx, model.SourcingFilter{IDs: filter.IDs}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find sourcing by filter error")
+		}
+
+		if err := sourcingRepository.Delete(ctx, filter); err != nil {
+			return nil, stacktrace.Propagate(err, "delete sourcing error")
+		}
+
+		for _, id := range filter.IDs {
+			if err := writeOutbox(ctx, outboxRepository, model.EventSourcingDeleted, id, map[string]uuid.UUID{"id": id}); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+		}
+
+		for _, sourcingData := range sourcings {
+			if err := writeAudit(ctx, auditRepository, model.EntitySourcing, sourcingData.ID, model.AuditActionDelete, sourcingData, nil); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	for _, id := range filter.IDs {
+		go lifecycle.Background(s.cache.Sourcing().Delete)(ctx, id)
+	}
+
+	return nil
+}
+
+func (s *sourcingService) FindByID(ctx context.Context, id uuid.UUID) (*model.Sourcing, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.FindByID")
+	defer span.End()
+
+	sourcingDataCache, err := s.cache.Sourcing().Get(ctx, id)
+	if err == nil {
+		return sourcingDataCache, nil
+	}
+
+	sourcingRepository := s.main.Sourcing()
+	sourcingData, err := sourcingRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find sourcing by id error")
+	}
+
+	go lifecycle.Background(s.cache.Sourcing().Set)(ctx, sourcingData)
+
+	return sourcingData, nil
+}
+
+func (s *sourcingService) FindByFilter(ctx context.Context, filter model.SourcingFilter) ([]*model.Sourcing, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.FindByFilter")
+	defer span.End()
+
+	sourcingRepository := s.main.Sourcing()
+	results, err := sourcingRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.Sourcing{}, stacktrace.Propagate(err, "find sourcing by filter error")
+	}
+
+	return results, nil
+}
+
+func (s *sourcingService) FindPage(ctx context.Context, filter model.SourcingFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.FindPage")
+	defer span.End()
+
+	sourcingRepository := s.main.Sourcing()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := sourcingRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find sourcing page error")
+	}
+
+	total, err := sourcingRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total sourcing by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
+
+func (s *sourcingService) FindStock(ctx context.Context, filter model.SourcingFilter) ([]*model.SourcingStock, error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.FindStock")
+	defer span.End()
+
+	sourcingRepository := s.main.Sourcing()
+	sourcings, err := sourcingRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.SourcingStock{}, stacktrace.Propagate(err, "find sourcing by filter error")
+	}
+
+	results := []*model.SourcingStock{}
+	stockMap := make(map[string]*model.SourcingStock)
+	for _, sourcingData := range sourcings {
+		stock, exist := stockMap[sourcingData.SKU]
+		if !exist {
+			stock = model.NewSourcingStock(sourcingData.SKU)
+			stockMap[sourcingData.SKU] = stock
+			results = append(results, stock)
+		}
+
+		stock.Add(sourcingData)
+	}
+
+	return results, nil
+}
+
+func (s *sourcingService) Reserve(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.Reserve")
+	defer span.End()
+
+	return s.adjustQty(ctx, inputs, qtyOperation{
+		eventType:    model.EventSourcingReserved,
+		apply:        (*model.Sourcing).Reserve,
+		capacity:     func(data *model.Sourcing) int { return data.QtySaleable },
+		insufficient: model.ErrInsufficientStock,
+	})
+}
+
+func (s *sourcingService) Release(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.Release")
+	defer span.End()
+
+	return s.adjustQty(ctx, inputs, qtyOperation{
+		eventType:    model.EventSourcingReleased,
+		apply:        (*model.Sourcing).Release,
+		capacity:     func(data *model.Sourcing) int { return data.QtyReserved },
+		insufficient: model.ErrInsufficientReserved,
+	})
+}
+
+func (s *sourcingService) Commit(ctx context.Context, inputs []model.SourcingQtyInput) (outputs []model.SourcingOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "inventory.Sourcing.Commit")
+	defer span.End()
+
+	return s.adjustQty(ctx, inputs, qtyOperation{
+		eventType:    model.EventSourcingCommitted,
+		apply:        (*model.Sourcing).Commit,
+		capacity:     func(data *model.Sourcing) int { return data.QtyReserved },
+		insufficient: model.ErrInsufficientReserved,
+	})
+}
+
+type sourcingKey struct {
+	locationID uuid.UUID
+	sku        string
+}
+
+type qtyOperation struct {
+	eventType    string
+	apply        func(data *model.Sourcing, qty int) error
+	capacity     func(data *model.Sourcing) int
+	insufficient error
+}
+
+// adjustQty locks the sourcings of every requested SKU and applies the stock
+// operation to each of them. A single failing SKU rolls back the whole batch,
+// so an order is never partially reserved.
+func (s *sourcingService) adjustQty(
+	ctx context.Context,
+	inputs []model.SourcingQtyInput,
+	operation qtyOperation,
+) (outputs []model.SourcingOutput, err error) {
+	keys := []sourcingKey{}
+	qtyMap := make(map[sourcingKey]int)
+	skus := []string{}
+	skuMap := make(map[string]bool)
+	for _, input := range inputs {
+		key := sourcingKey{input.LocationID, input.SKU}
+		if _, exist := qtyMap[key]; !exist {
+			keys = append(keys, key)
+		}
+		qtyMap[key] += input.Qty
+
+		if !skuMap[input.SKU] {
+			skuMap[input.SKU] = true
+			skus = append(skus, input.SKU)
+		}
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		sourcingRepository := repoRegistry.Sourcing()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		filter := model.SourcingFilter{
+			SKUs: skus,
+		}
+
+		sourcings, err := sourcingRepository.FindByFilter(ctx, filter, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find sourcing by filter error")
+		}
+
+		sourcingMap := make(map[string][]*model.Sourcing)
+		beforeMap := make(map[uuid.UUID]model.Sourcing)
+		for _, sourcingData := range sourcings {
+			sourcingMap[sourcingData.SKU] = append(sourcingMap[sourcingData.SKU], sourcingData)
+			beforeMap[sourcingData.ID] = *sourcingData
+		}
+
+		failures := []model.SourcingOutput{}
+		updatedMap := make(map[uuid.UUID]*model.Sourcing)
+		for _, key := range keys {
+			candidates := []*model.Sourcing{}
+			for _, sourcingData := range sourcingMap[key.sku] {
+				if key.locationID == uuid.Nil || sourcingData.LocationID == key.locationID {
+					candidates = append(candidates, sourcingData)
+				}
+			}
+
+			if len(candidates) == 0 {
+				failures = append(failures, model.SourcingOutput{
+					LocationID: key.locationID,
+					SKU:        key.sku,
+					Message:    model.ErrSourcingNotFound.Error(),
+				})
+				continue
+			}
+
+			// take from the locations with the most room first
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return operation.capacity(candidates[i]) > operation.capacity(candidates[j])
+			})
+
+			remaining := qtyMap[key]
+			for _, sourcingData := range candidates {
+				qty := operation.capacity(sourcingData)
+				if qty > remaining {
+					qty = remaining
+				}
+
+				if qty <= 0 {
+					continue
+				}
+
+				if err := operation.apply(sourcingData, qty); err != nil {
+					failures = append(failures, model.SourcingOutput{
+						ID:         sourcingData.ID,
+						LocationID: sourcingData.LocationID,
+						SKU:        sourcingData.SKU,
+						Message:    err.Error(),
+					})
+					break
+				}
+
+				remaining -= qty
+				updatedMap[sourcingData.ID] = sourcingData
+			}
+
+			if remaining > 0 {
+				failures = append(failures, model.SourcingOutput{
+					LocationID: key.locationID,
+					SKU:        key.sku,
+					Message:    operation.insufficient.Error(),
+				})
+			}
+		}
+
+		if len(failures) > 0 {
+			return failures, operation.insufficient
+		}
+
+		updated := []*model.Sourcing{}
+		for _, sourcingData := range sourcings {
+			if _, exist := updatedMap[sourcingData.ID]; !exist {
+				continue
+			}
+
+			sourcingData.Bump()
+			if err := sourcingRepository.Update(ctx, sourcingData); err != nil {
+				return nil, stacktrace.Propagate(err, "update sourcing error")
+			}
+
+			if err := writeOutbox(ctx, outboxRepository, operation.eventType, sourcingData.ID, sourcingData); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+
+			sourcingBefore := beforeMap[sourcingData.ID]
+			if err := writeAudit(ctx, auditRepository, model.EntitySourcing, sourcingData.ID, model.AuditActionUpdate, &sourcingBefore, sourcingData); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+
+			updated = append(updated, sourcingData)
+		}
+
+		return updated, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.SourcingOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	for _, sourcingData := range out.([]*model.Sourcing) {
+		go lifecycle.Background(s.cache.Sourcing().Set)(ctx, sourcingData)
+	}
+
+	return nil, nil
+}
+
+// updateSourcing applies the update only while the row is still at the
+// version the client read, when it sent one.
+func updateSourcing(ctx context.Context, sourcingRepository port.SourcingMainRepository, sourcingData *model.Sourcing, version *int) error {
+	if version != nil {
+		return sourcingRepository.UpdateIfVersion(ctx, sourcingData, *version)
+	}
+
+	return sourcingRepository.Update(ctx, sourcingData)
+}
+
+// sourcingUpsertError reports a version conflict when every failed item is one,
+// any other failure stays an internal error.
+func sourcingUpsertError(outputs []model.SourcingOutput) error {
+	for _, output := range outputs {
+		if !output.Conflict {
+			return errors.New("internal server error")
+		}
+	}
+
+	return model.ErrVersionConflict
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/inventory/model"
+	"go-poc/service/inventory/repository/port"
+)
+
+// fakeSourcingRepository keeps the rows in memory and records the writes,
+// the methods adjustQty does not use are left to the embedded nil port.
+type fakeSourcingRepository struct {
+	port.SourcingMainRepository
+	rows    []model.Sourcing
+	updated []model.Sourcing
+}
+
+func (r *fakeSourcingRepository) FindByFilter(ctx context.Context, filter model.SourcingFilter, lock bool) ([]*model.Sourcing, error) {
+	results := []*model.Sourcing{}
+	for _, row := range r.rows {
+		for _, sku := range filter.SKUs {
+			if row.SKU == sku {
+				row := row
+				results = append(results, &row)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func (r *fakeSourcingRepository) Update(ctx context.Context, data *model.Sourcing) error {
+	r.updated = append(r.updated, *data)
+	return nil
+}
+
+type fakeOutboxRepository struct {
+	port.OutboxMainRepository
+}
+
+func (fakeOutboxRepository) Create(ctx context.Context, data *model.Outbox) error {
+	return nil
+}
+
+type fakeAuditRepository struct {
+	port.AuditMainRepository
+}
+
+func (fakeAuditRepository) Create(ctx context.Context, data *model.Audit) error {
+	return nil
+}
+
+type fakeMainRepository struct {
+	sourcing *fakeSourcingRepository
+}
+
+func (r fakeMainRepository) Location() port.LocationMainRepository { return nil }
+func (r fakeMainRepository) Sourcing() port.SourcingMainRepository { return r.sourcing }
+func (r fakeMainRepository) Outbox() port.OutboxMainRepository     { return fakeOutboxRepository{} }
+func (r fakeMainRepository) Audit() port.AuditMainRepository       { return fakeAuditRepository{} }
+
+// DoInTransaction drops the writes of a failed transaction like a rollback.
+func (r fakeMainRepository) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (interface{}, error) {
+	out, err := txFunc(r)
+	if err != nil {
+		r.sourcing.updated = nil
+	}
+
+	return out, err
+}
+
+type fakeSourcingCache struct {
+	port.SourcingCacheRepository
+}
+
+func (fakeSourcingCache) Set(ctx context.Context, data *model.Sourcing) error {
+	return nil
+}
+
+type fakeCacheRepository struct{}
+
+func (fakeCacheRepository) Location() port.LocationCacheRepository { return nil }
+func (fakeCacheRepository) Sourcing() port.SourcingCacheRepository { return fakeSourcingCache{} }
+
+func TestSourcingAdjustQty(t *testing.T) {
+	locationA := uuid.New()
+	locationB := uuid.New()
+	rowA := model.Sourcing{ID: uuid.New(), LocationID: locationA, SKU: "sku-1", QtyTotal: 10, QtyReserved: 2, QtySaleable: 8, Version: 1}
+	rowB := model.Sourcing{ID: uuid.New(), LocationID: locationB, SKU: "sku-1", QtyTotal: 5, QtyReserved: 0, QtySaleable: 5, Version: 1}
+	rowC := model.Sourcing{ID: uuid.New(), LocationID: locationA, SKU: "sku-2", QtyTotal: 3, QtyReserved: 3, QtySaleable: 0, Version: 1}
+
+	type operation func(s *sourcingService, ctx context.Context, inputs []model.SourcingQtyInput) ([]model.SourcingOutput, error)
+	reserve := (*sourcingService).Reserve
+	release := (*sourcingService).Release
+	commit := (*sourcingService).Commit
+
+	tests := []struct {
+		name    string
+		apply   operation
+		inputs  []model.SourcingQtyInput
+		wantErr error
+		// want is the qty total, reserved and version of every written row
+		want map[uuid.UUID][3]int
+	}{
+		{
+			name:   "reserve at a location",
+			apply:  reserve,
+			inputs: []model.SourcingQtyInput{{LocationID: locationA, SKU: "sku-1", Qty: 5}},
+			want:   map[uuid.UUID][3]int{rowA.ID: {10, 7, 2}},
+		},
+		{
+			name:   "reserve across locations, the most saleable first",
+			apply:  reserve,
+			inputs: []model.SourcingQtyInput{{SKU: "sku-1", Qty: 10}},
+			want:   map[uuid.UUID][3]int{rowA.ID: {10, 10, 2}, rowB.ID: {5, 2, 2}},
+		},
+		{
+			name:    "reserve past the saleable stock",
+			apply:   reserve,
+			inputs:  []model.SourcingQtyInput{{SKU: "sku-1", Qty: 14}},
+			wantErr: model.ErrInsufficientStock,
+		},
+		{
+			name:  "one short sku fails the whole batch",
+			apply: reserve,
+			inputs: []model.SourcingQtyInput{
+				{LocationID: locationA, SKU: "sku-1", Qty: 1},
+				{LocationID: locationA, SKU: "sku-2", Qty: 1},
+			},
+			wantErr: model.ErrInsufficientStock,
+		},
+		{
+			name:    "reserve an unknown sku",
+			apply:   reserve,
+			inputs:  []model.SourcingQtyInput{{SKU: "sku-3", Qty: 1}},
+			wantErr: model.ErrInsufficientStock,
+		},
+		{
+			name:  "one row taken twice is written once",
+			apply: reserve,
+			inputs: []model.SourcingQtyInput{
+				{LocationID: locationA, SKU: "sku-1", Qty: 1},
+				{SKU: "sku-1", Qty: 1},
+			},
+			want: map[uuid.UUID][3]int{rowA.ID: {10, 4, 2}},
+		},
+		{
+			name:   "release",
+			apply:  release,
+			inputs: []model.SourcingQtyInput{{LocationID: locationA, SKU: "sku-1", Qty: 2}},
+			want:   map[uuid.UUID][3]int{rowA.ID: {10, 0, 2}},
+		},
+		{
+			name:    "release past the reserved stock",
+			apply:   release,
+			inputs:  []model.SourcingQtyInput{{LocationID: locationA, SKU: "sku-1", Qty: 3}},
+			wantErr: model.ErrInsufficientReserved,
+		},
+		{
+			name:   "commit",
+			apply:  commit,
+			inputs: []model.SourcingQtyInput{{SKU: "sku-2", Qty: 2}},
+			want:   map[uuid.UUID][3]int{rowC.ID: {1, 1, 2}},
+		},
+		{
+			name:    "commit past the reserved stock",
+			apply:   commit,
+			inputs:  []model.SourcingQtyInput{{SKU: "sku-2", Qty: 4}},
+			wantErr: model.ErrInsufficientReserved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeSourcingRepository{rows: []model.Sourcing{rowA, rowB, rowC}}
+			service := &sourcingService{
+				main:    fakeMainRepository{sourcing: repository},
+				cache:   fakeCacheRepository{},
+				workers: 1,
+			}
+
+			outputs, err := tt.apply(service, context.Background(), tt.inputs)
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if len(outputs) == 0 {
+					t.Fatalf("no failed item reported")
+				}
+
+				if len(repository.updated) > 0 {
+					t.Fatalf("%d rows written by a failed batch", len(repository.updated))
+				}
+
+				return
+			}
+
+			if len(repository.updated) != len(tt.want) {
+				t.Fatalf("%d rows written, want %d", len(repository.updated), len(tt.want))
+			}
+
+			for _, row := range repository.updated {
+				got := [3]int{row.QtyTotal, row.QtyReserved, row.Version}
+				if got != tt.want[row.ID] {
+					t.Fatalf("row %s = %v, want %v", row.ID, got, tt.want[row.ID])
+				}
+
+				if row.QtySaleable != row.QtyTotal-row.QtyReserved {
+					t.Fatalf("row %s saleable = %d, want %d", row.ID, row.QtySaleable, row.QtyTotal-row.QtyReserved)
+				}
+			}
+		})
+	}
+}
@@ -5,37 +5,154 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"go-poc/middleware"
+	identityHandler "go-poc/service/identity/handler"
 	inventoryHandler "go-poc/service/inventory/handler"
 	salesChannelHandler "go-poc/service/saleschannel/handler"
+	systemHandler "go-poc/service/system/handler"
+	webhookHandler "go-poc/service/webhook/handler"
+	"go-poc/utils/metrics"
+)
+
+// Scopes a caller needs, granted per API key or in the scope claim of a JWT.
+const (
+	ScopeChannelRead      = "channel:read"
+	ScopeChannelWrite     = "channel:write"
+	ScopeInventoryRead    = "inventory:read"
+	ScopeInventoryWrite   = "inventory:write"
+	ScopeInventoryReserve = "inventory:reserve"
+	ScopeWebhookRead      = "webhook:read"
+	ScopeWebhookWrite     = "webhook:write"
+	ScopeSystemRead       = "system:read"
+	ScopeIdentityRead     = "identity:read"
+	ScopeIdentityWrite    = "identity:write"
 )
 
 func InitRoute(
 	ctx context.Context,
 	router *gin.Engine,
+	auth gin.HandlerFunc,
 	channelHandler salesChannelHandler.ChannelHandler,
+	channelProductHandler salesChannelHandler.ChannelProductHandler,
+	availabilityHandler salesChannelHandler.AvailabilityHandler,
+	allocationRuleHandler salesChannelHandler.AllocationRuleHandler,
 	locationHandler inventoryHandler.LocationHandler,
+	sourcingHandler inventoryHandler.SourcingHandler,
+	subscriptionHandler webhookHandler.SubscriptionHandler,
+	deliveryHandler webhookHandler.DeliveryHandler,
+	migrationHandler systemHandler.MigrationHandler,
+	healthHandler systemHandler.HealthHandler,
 ) {
-	// API group
-	api := router.Group("/api")
-
-	api.POST("/channel/upsert", channelHandler.HandleUpsert)
-	api.POST("/channel/upsert-batch-fetching", channelHandler.HandleUpsertBatchFetching)
-	api.POST("/channel/upsert-with-transaction", channelHandler.HandleUpsertWithTransaction)
-	api.POST("/channel/upsert-with-lock", channelHandler.HandleUpsertWithLock)
-	api.POST("/channel/filter", channelHandler.HandleAllByFilter)
-	api.POST("/channel/pagination", channelHandler.HandlePagination)
-	api.DELETE("/channel/delete", channelHandler.HandleDelete)
-	api.GET("/channel/:id", channelHandler.HandleFindByID)
-
-	api.POST("/location/upsert", locationHandler.HandleUpsert)
-	api.POST("/location/filter", locationHandler.HandleAllByFilter)
-	api.POST("/location/pagination", locationHandler.HandlePagination)
-	api.DELETE("/location/delete", locationHandler.HandleDelete)
-	api.GET("/location/:id", locationHandler.HandleFindByID)
+	// API group, every route needs an authenticated caller
+	api := router.Group("/api", auth)
+	channelRead := middleware.Require(ScopeChannelRead)
+	channelWrite := middleware.Require(ScopeChannelWrite)
+	inventoryRead := middleware.Require(ScopeInventoryRead)
+	inventoryWrite := middleware.Require(ScopeInventoryWrite)
+	inventoryReserve := middleware.Require(ScopeInventoryReserve)
+	webhookRead := middleware.Require(ScopeWebhookRead)
+	webhookWrite := middleware.Require(ScopeWebhookWrite)
+	systemRead := middleware.Require(ScopeSystemRead)
+
+	api.POST("/channel/upsert", channelWrite, channelHandler.HandleUpsert)
+	api.POST("/channel/upsert-batch-fetching", channelWrite, channelHandler.HandleUpsertBatchFetching)
+	api.POST("/channel/upsert-with-transaction", channelWrite, channelHandler.HandleUpsertWithTransaction)
+	api.POST("/channel/upsert-with-lock", channelWrite, channelHandler.HandleUpsertWithLock)
+	api.POST("/channel/filter", channelRead, channelHandler.HandleAllByFilter)
+	api.POST("/channel/pagination", channelRead, channelHandler.HandlePagination)
+	api.DELETE("/channel/delete", channelWrite, channelHandler.HandleDelete)
+	api.POST("/channel/restore", channelWrite, channelHandler.HandleRestore)
+	api.GET("/channel/:id", channelRead, channelHandler.HandleFindByID)
+	api.GET("/channel/:id/history", channelRead, channelHandler.HandleHistory)
+	api.GET("/channel/:id/availability", channelRead, availabilityHandler.HandleFindByChannelID)
+
+	api.POST("/channel-product/upsert", channelWrite, channelProductHandler.HandleUpsert)
+	api.POST("/channel-product/filter", channelRead, channelProductHandler.HandleAllByFilter)
+	api.POST("/channel-product/pagination", channelRead, channelProductHandler.HandlePagination)
+	api.DELETE("/channel-product/delete", channelWrite, channelProductHandler.HandleDelete)
+	api.GET("/channel-product/:id", channelRead, channelProductHandler.HandleFindByID)
+
+	api.POST("/allocation-rule/upsert", channelWrite, allocationRuleHandler.HandleUpsert)
+	api.POST("/allocation-rule/filter", channelRead, allocationRuleHandler.HandleAllByFilter)
+	api.POST("/allocation-rule/pagination", channelRead, allocationRuleHandler.HandlePagination)
+	api.DELETE("/allocation-rule/delete", channelWrite, allocationRuleHandler.HandleDelete)
+	api.GET("/allocation-rule/:id", channelRead, allocationRuleHandler.HandleFindByID)
+
+	api.POST("/location/upsert", inventoryWrite, locationHandler.HandleUpsert)
+	api.POST("/location/filter", inventoryRead, locationHandler.HandleAllByFilter)
+	api.POST("/location/pagination", inventoryRead, locationHandler.HandlePagination)
+	api.DELETE("/location/delete", inventoryWrite, locationHandler.HandleDelete)
+	api.POST("/location/restore", inventoryWrite, locationHandler.HandleRestore)
+	api.GET("/location/:id", inventoryRead, locationHandler.HandleFindByID)
+	api.GET("/location/:id/history", inventoryRead, locationHandler.HandleHistory)
+
+	api.POST("/sourcing/upsert", inventoryWrite, sourcingHandler.HandleUpsert)
+	api.POST("/sourcing/reserve", inventoryReserve, sourcingHandler.HandleReserve)
+	api.POST("/sourcing/release", inventoryReserve, sourcingHandler.HandleRelease)
+	api.POST("/sourcing/commit", inventoryReserve, sourcingHandler.HandleCommit)
+	api.POST("/sourcing/filter", inventoryRead, sourcingHandler.HandleAllByFilter)
+	api.POST("/sourcing/stock", inventoryRead, sourcingHandler.HandleStock)
+	api.POST("/sourcing/pagination", inventoryRead, sourcingHandler.HandlePagination)
+	api.DELETE("/sourcing/delete", inventoryWrite, sourcingHandler.HandleDelete)
+	api.GET("/sourcing/:id", inventoryRead, sourcingHandler.HandleFindByID)
+
+	api.POST("/webhook/upsert", webhookWrite, subscriptionHandler.HandleUpsert)
+	api.POST("/webhook/rotate", webhookWrite, subscriptionHandler.HandleRotate)
+	api.POST("/webhook/filter", webhookRead, subscriptionHandler.HandleAllByFilter)
+	api.POST("/webhook/pagination", webhookRead, subscriptionHandler.HandlePagination)
+	api.DELETE("/webhook/delete", webhookWrite, subscriptionHandler.HandleDelete)
+	api.GET("/webhook/:id", webhookRead, subscriptionHandler.HandleFindByID)
+
+	api.POST("/webhook-delivery/pagination", webhookRead, deliveryHandler.HandlePagination)
+	api.POST("/webhook-delivery/replay", webhookWrite, deliveryHandler.HandleReplay)
+	api.GET("/webhook-delivery/:id", webhookRead, deliveryHandler.HandleFindByID)
+
+	api.GET("/migration/status", systemRead, migrationHandler.HandleStatus)
 
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
+
+	// probes of the orchestrator, left out of the authentication like /ping
+	router.GET("/healthz", healthHandler.HandleLive)
+	router.GET("/readyz", healthHandler.HandleReady)
+
+	// scraped by Prometheus next to the API, left out of the authentication
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
+
+// InitIdentityRoute serves the identity service, only called when it has a
+// database.
+func InitIdentityRoute(
+	router *gin.Engine,
+	auth gin.HandlerFunc,
+	userHandler identityHandler.UserHandler,
+	sessionHandler identityHandler.SessionHandler,
+	clientHandler identityHandler.ClientHandler,
+) {
+	// API group, every route needs an authenticated caller
+	api := router.Group("/api", auth)
+	// Public group, the routes a caller uses to get its credentials
+	public := router.Group("/api")
+
+	identityRead := middleware.Require(ScopeIdentityRead)
+	identityWrite := middleware.Require(ScopeIdentityWrite)
+
+	public.POST("/identity/register", userHandler.HandleRegister)
+	public.POST("/identity/login", sessionHandler.HandleLogin)
+	public.POST("/identity/refresh", sessionHandler.HandleRefresh)
+	public.POST("/identity/logout", sessionHandler.HandleLogout)
+
+	api.POST("/identity/user/grant", identityWrite, userHandler.HandleGrant)
+	api.POST("/identity/user/pagination", identityRead, userHandler.HandlePagination)
+	api.GET("/identity/user/:id", identityRead, userHandler.HandleFindByID)
+
+	api.POST("/identity/client/create", identityWrite, clientHandler.HandleCreate)
+	api.POST("/identity/client/grant", identityWrite, clientHandler.HandleGrant)
+	api.POST("/identity/client/rotate", identityWrite, clientHandler.HandleRotate)
+	api.POST("/identity/client/revoke", identityWrite, clientHandler.HandleRevoke)
+	api.POST("/identity/client/pagination", identityRead, clientHandler.HandlePagination)
+	api.GET("/identity/client/:id", identityRead, clientHandler.HandleFindByID)
 }
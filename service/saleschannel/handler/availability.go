@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type AvailabilityHandler struct {
+	usecase usecase.Availability
+}
+
+func NewAvailability(
+	usecase usecase.Availability,
+) AvailabilityHandler {
+	return AvailabilityHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *AvailabilityHandler) HandleFindByChannelID(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_availability")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.ChannelURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, uri)
+	items, err := h.usecase.FindByChannelID(ctx, id)
+	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "channel not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel availability", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, items)
+}
@@ -1,16 +1,15 @@
 package handler
 
 import (
+	"database/sql"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/opentracing/opentracing-go"
-	spanLog "github.com/opentracing/opentracing-go/log"
 	"github.com/palantir/stacktrace"
 
-	"go-poc/external"
+	"go-poc/middleware"
 	"go-poc/respond"
 	"go-poc/service/saleschannel/model"
 	"go-poc/service/saleschannel/usecase"
@@ -31,34 +30,38 @@ func NewChannel(
 }
 
 func (h *ChannelHandler) HandleUpsert(c *gin.Context) {
-	ctx := activity.NewContext("channel_upsert")
+	ctx := middleware.Context(c, "channel_upsert")
 	trxID, _ := activity.GetTransactionID(ctx)
 
-	span := external.StartSpanFromRequest(external.Tracer, c.Request, "POST /api/channel/upsert")
-	defer span.Finish()
-	ctx = opentracing.ContextWithSpan(ctx, span)
-
 	var inputs []model.ChannelInput
 	if err := c.BindJSON(&inputs); err != nil {
-		span.SetTag("error", true)
-		span.LogFields(
-			spanLog.String("event", err.Error()),
-			spanLog.String("type", respond.ErrBadRequest),
-		)
-
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
 		return
 	}
 
+	version, ok := ifMatch(c, trxID, inputs)
+	if !ok {
+		return
+	}
+
 	outputs, err := h.usecase.Upsert(ctx, inputs)
 	if err != nil {
-		span.SetTag("error", true)
-		span.LogFields(
-			spanLog.String("event", stacktrace.RootCause(err).Error()),
-			spanLog.String("type", respond.ErrInternal),
-		)
-
 		if len(outputs) > 0 {
+			if err == model.ErrChannelForbidden {
+				respond.Invalid(c, trxID, http.StatusForbidden, outputs)
+				return
+			}
+
+			if err == model.ErrVersionConflict {
+				status := http.StatusConflict
+				if version != nil {
+					status = http.StatusPreconditionFailed
+				}
+
+				respond.Invalid(c, trxID, status, outputs)
+				return
+			}
+
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error channel upsert %v", outputs))
 			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
 			return
@@ -69,42 +72,42 @@ func (h *ChannelHandler) HandleUpsert(c *gin.Context) {
 		return
 	}
 
-	span.LogFields(
-		spanLog.String("event", "channel upsert success"),
-		spanLog.String("type", "Success"),
-	)
 	respond.Success(c, trxID, http.StatusCreated, nil)
 }
 
 func (h *ChannelHandler) HandleUpsertBatchFetching(c *gin.Context) {
-	ctx := activity.NewContext("channel_upsert_batch_fetching")
+	ctx := middleware.Context(c, "channel_upsert_batch_fetching")
 	trxID, _ := activity.GetTransactionID(ctx)
 
-	span := external.StartSpanFromRequest(external.Tracer, c.Request, "POST /api/channel/upsert-batch-fetching")
-	defer span.Finish()
-	ctx = opentracing.ContextWithSpan(ctx, span)
-
 	var inputs []model.ChannelInput
 	if err := c.BindJSON(&inputs); err != nil {
-		span.SetTag("error", true)
-		span.LogFields(
-			spanLog.String("event", err.Error()),
-			spanLog.String("type", respond.ErrBadRequest),
-		)
-
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
 		return
 	}
 
+	version, ok := ifMatch(c, trxID, inputs)
+	if !ok {
+		return
+	}
+
 	outputs, err := h.usecase.UpsertBatchFetching(ctx, inputs)
 	if err != nil {
-		span.SetTag("error", true)
-		span.LogFields(
-			spanLog.String("event", stacktrace.RootCause(err).Error()),
-			spanLog.String("type", respond.ErrInternal),
-		)
-
 		if len(outputs) > 0 {
+			if err == model.ErrChannelForbidden {
+				respond.Invalid(c, trxID, http.StatusForbidden, outputs)
+				return
+			}
+
+			if err == model.ErrVersionConflict {
+				status := http.StatusConflict
+				if version != nil {
+					status = http.StatusPreconditionFailed
+				}
+
+				respond.Invalid(c, trxID, status, outputs)
+				return
+			}
+
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error channel upsert %v", outputs))
 			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
 			return
@@ -115,15 +118,11 @@ func (h *ChannelHandler) HandleUpsertBatchFetching(c *gin.Context) {
 		return
 	}
 
-	span.LogFields(
-		spanLog.String("event", "channel upsert batch fetching success"),
-		spanLog.String("type", "Success"),
-	)
 	respond.Success(c, trxID, http.StatusCreated, nil)
 }
 
 func (h *ChannelHandler) HandleUpsertWithTransaction(c *gin.Context) {
-	ctx := activity.NewContext("channel_upsert_with_transaction")
+	ctx := middleware.Context(c, "channel_upsert_with_transaction")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	var inputs []model.ChannelInput
@@ -132,9 +131,29 @@ func (h *ChannelHandler) HandleUpsertWithTransaction(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatch(c, trxID, inputs)
+	if !ok {
+		return
+	}
+
 	outputs, err := h.usecase.UpsertWithTransaction(ctx, inputs)
 	if err != nil {
 		if len(outputs) > 0 {
+			if err == model.ErrChannelForbidden {
+				respond.Invalid(c, trxID, http.StatusForbidden, outputs)
+				return
+			}
+
+			if err == model.ErrVersionConflict {
+				status := http.StatusConflict
+				if version != nil {
+					status = http.StatusPreconditionFailed
+				}
+
+				respond.Invalid(c, trxID, status, outputs)
+				return
+			}
+
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error channel upsert %v", outputs))
 			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
 			return
@@ -149,7 +168,7 @@ func (h *ChannelHandler) HandleUpsertWithTransaction(c *gin.Context) {
 }
 
 func (h *ChannelHandler) HandleUpsertWithLock(c *gin.Context) {
-	ctx := activity.NewContext("channel_upsert_with_lock")
+	ctx := middleware.Context(c, "channel_upsert_with_lock")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	var inputs []model.ChannelInput
@@ -158,9 +177,29 @@ func (h *ChannelHandler) HandleUpsertWithLock(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatch(c, trxID, inputs)
+	if !ok {
+		return
+	}
+
 	outputs, err := h.usecase.UpsertWithLock(ctx, inputs)
 	if err != nil {
 		if len(outputs) > 0 {
+			if err == model.ErrChannelForbidden {
+				respond.Invalid(c, trxID, http.StatusForbidden, outputs)
+				return
+			}
+
+			if err == model.ErrVersionConflict {
+				status := http.StatusConflict
+				if version != nil {
+					status = http.StatusPreconditionFailed
+				}
+
+				respond.Invalid(c, trxID, status, outputs)
+				return
+			}
+
 			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error channel upsert %v", outputs))
 			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
 			return
@@ -174,8 +213,29 @@ func (h *ChannelHandler) HandleUpsertWithLock(c *gin.Context) {
 	respond.Success(c, trxID, http.StatusCreated, nil)
 }
 
+// ifMatch turns the upsert of a single channel into a conditional update
+// when If-Match is sent, ok is false once the request was answered.
+func ifMatch(c *gin.Context, trxID string, inputs []model.ChannelInput) (version *int, ok bool) {
+	version, err := respond.IfMatchVersion(c.GetHeader("If-Match"))
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return nil, false
+	}
+
+	if version != nil {
+		if len(inputs) != 1 {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "if-match needs a single item")
+			return nil, false
+		}
+
+		inputs[0].Version = version
+	}
+
+	return version, true
+}
+
 func (h *ChannelHandler) HandleAllByFilter(c *gin.Context) {
-	ctx := activity.NewContext("channel_all_by_filter")
+	ctx := middleware.Context(c, "channel_all_by_filter")
 	trxID, _ := activity.GetTransactionID(ctx)
 	filter := model.ChannelFilter{}
 	if err := c.BindJSON(&filter); err != nil {
@@ -184,7 +244,7 @@ func (h *ChannelHandler) HandleAllByFilter(c *gin.Context) {
 	}
 
 	ctx = activity.WithPayload(ctx, filter)
-	items, err := h.usecase.FindByFilter(filter)
+	items, err := h.usecase.FindByFilter(ctx, filter)
 	if err != nil {
 		log.WithContext(ctx).Error("error channel all by filter", err)
 		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
@@ -200,7 +260,7 @@ func (h *ChannelHandler) HandleAllByFilter(c *gin.Context) {
 }
 
 func (h *ChannelHandler) HandlePagination(c *gin.Context) {
-	ctx := activity.NewContext("channel_pagination")
+	ctx := middleware.Context(c, "channel_pagination")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	page := 1
@@ -219,7 +279,7 @@ func (h *ChannelHandler) HandlePagination(c *gin.Context) {
 		return
 	}
 
-	data, err := h.usecase.FindPage(filter, int64(page), int64(limit))
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
 	if err != nil {
 		log.WithContext(ctx).Error("error channel pagination", err)
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
@@ -230,7 +290,7 @@ func (h *ChannelHandler) HandlePagination(c *gin.Context) {
 }
 
 func (h *ChannelHandler) HandleFindByID(c *gin.Context) {
-	ctx := activity.NewContext("channel_find_by_id")
+	ctx := middleware.Context(c, "channel_find_by_id")
 	trxID, _ := activity.GetTransactionID(ctx)
 
 	uri := model.ChannelURI{}
@@ -245,18 +305,107 @@ func (h *ChannelHandler) HandleFindByID(c *gin.Context) {
 		return
 	}
 
-	data, err := h.usecase.FindByID(id)
+	data, err := h.usecase.FindByID(ctx, id)
 	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
 		log.WithContext(ctx).Error("error channel find by id", err)
 		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
 		return
 	}
 
+	etag := respond.ETag(data.Version)
+	c.Header("ETag", etag)
+	if respond.MatchETag(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ChannelHandler) HandleHistory(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_history")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.ChannelURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	data, err := h.usecase.History(ctx, id, int64(page), int64(limit))
+	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel history", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
 	respond.Success(c, trxID, http.StatusOK, data)
 }
 
+func (h *ChannelHandler) HandleRestore(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_restore")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.ChannelFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Restore(ctx, filter)
+	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "channel not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel restore", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
+
 func (h *ChannelHandler) HandleDelete(c *gin.Context) {
-	ctx := activity.NewContext("channel_delete")
+	ctx := middleware.Context(c, "channel_delete")
 	trxID, _ := activity.GetTransactionID(ctx)
 	filter := model.ChannelFilter{}
 	if err := c.BindJSON(&filter); err != nil {
@@ -271,8 +420,39 @@ func (h *ChannelHandler) HandleDelete(c *gin.Context) {
 		return
 	}
 
-	err := h.usecase.Delete(filter)
+	version, err := respond.IfMatchVersion(c.GetHeader("If-Match"))
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	if version != nil {
+		if len(filter.IDs) != 1 {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "if-match needs a single id")
+			return
+		}
+
+		err = h.usecase.DeleteIfVersion(ctx, filter.IDs[0], *version)
+	} else {
+		err = h.usecase.Delete(ctx, filter)
+	}
+
 	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "channel not found")
+			return
+		}
+
+		if err == model.ErrVersionConflict {
+			respond.Error(c, trxID, http.StatusPreconditionFailed, respond.ErrPreconditionFailed, err.Error())
+			return
+		}
+
 		log.WithContext(ctx).Error("error channel delete", err)
 		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
 		return
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type ChannelProductHandler struct {
+	usecase usecase.ChannelProduct
+}
+
+func NewChannelProduct(
+	usecase usecase.ChannelProduct,
+) ChannelProductHandler {
+	return ChannelProductHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ChannelProductHandler) HandleUpsert(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_product_upsert")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	var inputs []model.ChannelProductInput
+	if err := c.BindJSON(&inputs); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	outputs, err := h.usecase.Upsert(ctx, inputs)
+	if err != nil {
+		if len(outputs) > 0 {
+			if err == model.ErrChannelForbidden {
+				respond.Invalid(c, trxID, http.StatusForbidden, outputs)
+				return
+			}
+
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error channel product upsert %v", outputs))
+			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel product upsert", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusCreated, nil)
+}
+
+func (h *ChannelProductHandler) HandleAllByFilter(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_product_all_by_filter")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.ChannelProductFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+	items, err := h.usecase.FindByFilter(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error channel product all by filter", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	if len(items) == 0 {
+		respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "channel product not found")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, items)
+}
+
+func (h *ChannelProductHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_product_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.ChannelProductFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error channel product pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ChannelProductHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_product_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.ChannelProductURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel product find by id", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *ChannelProductHandler) HandleDelete(c *gin.Context) {
+	ctx := middleware.Context(c, "channel_product_delete")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.ChannelProductFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Delete(ctx, filter)
+	if err != nil {
+		if err == model.ErrChannelForbidden {
+			respond.Error(c, trxID, http.StatusForbidden, respond.ErrForbidden, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error channel product delete", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS allocation_rules;
\ No newline at end of file
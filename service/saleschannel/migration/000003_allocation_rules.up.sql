@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS allocation_rules
+(
+    id CHAR(36) primary key NOT NULL,
+    channel_id CHAR(36) NOT NULL,
+    sku VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    value INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (channel_id, sku),
+    FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
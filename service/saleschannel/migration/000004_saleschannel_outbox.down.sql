@@ -0,0 +1 @@
+DROP TABLE IF EXISTS saleschannel_outbox;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS saleschannel_outbox
+(
+    id CHAR(36) primary key NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_id CHAR(36) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6) NOT NULL,
+    delivered_at TIMESTAMP(6) NULL
+);
+
+CREATE INDEX idx_saleschannel_outbox_pending ON saleschannel_outbox (delivered_at, created_at);
@@ -0,0 +1 @@
+ALTER TABLE channels DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE channels ADD COLUMN version INT DEFAULT 1 NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE channels ADD COLUMN deleted_at TIMESTAMP NULL;
\ No newline at end of file
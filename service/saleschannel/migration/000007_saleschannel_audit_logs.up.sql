@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS saleschannel_audit_logs
+(
+    id CHAR(36) primary key NOT NULL,
+    entity_type VARCHAR(100) NOT NULL,
+    entity_id CHAR(36) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    before_data TEXT NULL,
+    after_data TEXT NULL,
+    transaction_id VARCHAR(100) NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX idx_saleschannel_audit_logs_entity ON saleschannel_audit_logs (entity_type, entity_id, created_at);
\ No newline at end of file
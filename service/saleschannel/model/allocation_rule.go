@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// AllocationRuleWildcard applies a rule to every SKU of the channel
+	// without a rule of its own.
+	AllocationRuleWildcard = "*"
+
+	AllocationTypePercent = "percent"
+	AllocationTypeBuffer  = "buffer"
+)
+
+var (
+	ErrInvalidAllocationPercent = errors.New("percent value must be between 0 and 100")
+)
+
+type AllocationRule struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	SKU       string    `json:"sku" db:"sku"`
+	Type      string    `json:"type" db:"type"`
+	Value     int       `json:"value" db:"value"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func NewAllocationRule(v AllocationRuleInput) *AllocationRule {
+	return &AllocationRule{
+		ID:        uuid.New(),
+		ChannelID: v.ChannelID,
+		SKU:       v.SKU,
+		Type:      v.Type,
+		Value:     v.Value,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (m *AllocationRule) Update(v AllocationRuleInput) {
+	m.Type = v.Type
+	m.Value = v.Value
+	m.UpdatedAt = time.Now()
+}
+
+// Apply returns the part of the saleable qty the channel is allowed to sell,
+// percent caps the qty and buffer keeps a fixed qty back.
+func (m *AllocationRule) Apply(qty int) int {
+	if qty <= 0 {
+		return 0
+	}
+
+	switch m.Type {
+	case AllocationTypePercent:
+		return qty * m.Value / 100
+	case AllocationTypeBuffer:
+		if qty < m.Value {
+			return 0
+		}
+		return qty - m.Value
+	}
+
+	return qty
+}
+
+type AllocationRuleInput struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id" binding:"required"`
+	SKU       string    `json:"sku" binding:"required"`
+	Type      string    `json:"type" binding:"required,oneof=percent buffer"`
+	Value     int       `json:"value" binding:"gte=0"`
+}
+
+func (v AllocationRuleInput) Validate() error {
+	if v.Type == AllocationTypePercent && v.Value > 100 {
+		return ErrInvalidAllocationPercent
+	}
+
+	return nil
+}
+
+type AllocationRuleOutput struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	SKU       string    `json:"sku"`
+	Message   string    `json:"message"`
+}
+
+type AllocationRuleFilter struct {
+	IDs        []uuid.UUID `json:"ids"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+	SKUs       []string    `json:"skus"`
+}
+
+type AllocationRuleURI struct {
+	ID string `uri:"id" binding:"required"`
+}
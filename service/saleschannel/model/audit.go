@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils/activity"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+const (
+	EntityChannel        = "channel"
+	EntityChannelProduct = "channel_product"
+	EntityAllocationRule = "allocation_rule"
+)
+
+type Audit struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	EntityType    string    `json:"entity_type" db:"entity_type"`
+	EntityID      uuid.UUID `json:"entity_id" db:"entity_id"`
+	Action        string    `json:"action" db:"action"`
+	Before        *string   `json:"before" db:"before_data"`
+	After         *string   `json:"after" db:"after_data"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	ClientID      string    `json:"client_id" db:"client_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewAudit snapshots before and after as json, a nil before is a create and
+// a nil after a delete. The transaction and client ids come from the
+// activity of ctx.
+func NewAudit(ctx context.Context, entityType string, entityID uuid.UUID, action string, before, after interface{}) (*Audit, error) {
+	beforeValue, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterValue, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	trxID, _ := activity.GetTransactionID(ctx)
+	clientID, _ := activity.GetClientID(ctx)
+
+	return &Audit{
+		ID:            uuid.New(),
+		EntityType:    entityType,
+		EntityID:      entityID,
+		Action:        action,
+		Before:        beforeValue,
+		After:         afterValue,
+		TransactionID: trxID,
+		ClientID:      clientID,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// auditSnapshot is NULL for no value, a nil *Channel included, and the json
+// of the value otherwise.
+func auditSnapshot(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := string(data)
+	return &snapshot, nil
+}
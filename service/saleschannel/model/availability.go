@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// Stock is the saleable qty of a SKU as reported by the inventory service.
+type Stock struct {
+	SKU         string `json:"sku"`
+	QtySaleable int    `json:"qty_saleable"`
+}
+
+type Availability struct {
+	ChannelID        uuid.UUID `json:"channel_id"`
+	ChannelProductID uuid.UUID `json:"channel_product_id"`
+	SKU              string    `json:"sku"`
+	Name             string    `json:"name"`
+	QtySaleable      int       `json:"qty_saleable"`
+	QtyAvailable     int       `json:"qty_available"`
+	AllocationRuleID uuid.UUID `json:"allocation_rule_id"`
+}
@@ -1,46 +1,85 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrVersionConflict    = errors.New("version conflict")
+	ErrChannelForbidden   = errors.New("channel not permitted")
+	ErrChannelDeleted     = errors.New("channel is deleted, restore it")
+	ErrChannelCodeDeleted = errors.New("code belongs to a deleted channel, restore or purge it")
+)
+
 type Channel struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Code      string    `json:"code" db:"code"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Code      string     `json:"code" db:"code"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	Version   int        `json:"version" db:"version"`
+	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"`
 }
 
-func NewChannel(v ChannelInput) *Channel {
+// NewChannel keeps the input id when one is given so fixtures and clients
+// can upsert with stable ids.
+// NewChannel creates the channel under a new id. keepID keeps the input one
+// instead, only the seeder asks for it so the fixtures can refer to each
+// other.
+func NewChannel(v ChannelInput, keepID bool) *Channel {
+	id := uuid.New()
+	if keepID && v.ID != uuid.Nil {
+		id = v.ID
+	}
+
 	return &Channel{
-		ID:        uuid.New(),
+		ID:        id,
 		Code:      v.Code,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		Version:   1,
 	}
 }
 
 func (m *Channel) Update(v ChannelInput) {
 	m.Code = v.Code
 	m.UpdatedAt = time.Now()
+	m.Version++
+}
+
+// CheckVersion fails when the client sent the version it read and the row
+// has moved on since.
+func (m *Channel) CheckVersion(v ChannelInput) error {
+	if v.Version != nil && *v.Version != m.Version {
+		return ErrVersionConflict
+	}
+
+	return nil
 }
 
 type ChannelInput struct {
 	ID   uuid.UUID `json:"id"`
 	Code string    `json:"code" binding:"required"`
+	// Version is the version the client read, when set the update only
+	// applies while the row is still at that version.
+	Version *int `json:"version"`
 }
 
 type ChannelOutput struct {
-	ID      uuid.UUID `json:"id"`
-	Code    string    `json:"code"`
-	Message string    `json:"message"`
+	ID       uuid.UUID `json:"id"`
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	Conflict bool      `json:"conflict,omitempty"`
+	Version  int       `json:"version,omitempty"`
 }
 
 type ChannelFilter struct {
 	IDs   []uuid.UUID `json:"ids"`
 	Codes []string    `json:"codes"`
+	// IncludeDeleted also returns the soft deleted rows.
+	IncludeDeleted bool `json:"include_deleted"`
 }
 
 type ChannelURI struct {
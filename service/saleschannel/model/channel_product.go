@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChannelProduct struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ChannelID uuid.UUID `json:"channel_id" db:"channel_id"`
+	SKU       string    `json:"sku" db:"sku"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func NewChannelProduct(v ChannelProductInput) *ChannelProduct {
+	return &ChannelProduct{
+		ID:        uuid.New(),
+		ChannelID: v.ChannelID,
+		SKU:       v.SKU,
+		Name:      v.Name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (m *ChannelProduct) Update(v ChannelProductInput) {
+	m.Name = v.Name
+	m.UpdatedAt = time.Now()
+}
+
+type ChannelProductInput struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id" binding:"required"`
+	SKU       string    `json:"sku" binding:"required"`
+	Name      string    `json:"name" binding:"required"`
+}
+
+type ChannelProductOutput struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	SKU       string    `json:"sku"`
+	Message   string    `json:"message"`
+}
+
+type ChannelProductFilter struct {
+	IDs        []uuid.UUID `json:"ids"`
+	ChannelIDs []uuid.UUID `json:"channel_ids"`
+	SKUs       []string    `json:"skus"`
+}
+
+type ChannelProductURI struct {
+	ID string `uri:"id" binding:"required"`
+}
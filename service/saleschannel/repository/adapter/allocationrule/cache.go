@@ -0,0 +1,8 @@
+package allocationrule
+
+import (
+	"go-poc/utils/cachekey"
+)
+
+// cacheKeys is the keyspace of model.AllocationRule, see cachekey.Keyspace for the version.
+var cacheKeys = cachekey.New("saleschannel", "allocation_rule", 1)
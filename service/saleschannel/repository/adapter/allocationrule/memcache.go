@@ -0,0 +1,74 @@
+package allocationrule
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/rainycape/memcache"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils/metrics"
+	"go-poc/utils/tracing"
+)
+
+type memcacheRepository struct {
+	db *memcache.Client
+}
+
+func NewMemcacheRepository(db *memcache.Client) port.AllocationRuleCacheRepository {
+	return &memcacheRepository{
+		db: db,
+	}
+}
+
+func (repo *memcacheRepository) Set(ctx context.Context, data *model.AllocationRule) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache set", tracing.CacheMemcache)
+	defer func() { tracing.End(span, err) }()
+
+	dataMarshal, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	err = repo.db.Set(&memcache.Item{Key: cacheKeys.Key(data.ID), Value: dataMarshal})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repo *memcacheRepository) Get(ctx context.Context, id uuid.UUID) (data *model.AllocationRule, err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache get", tracing.CacheMemcache)
+	defer func() {
+		metrics.ObserveCacheLookup(cacheKeys.Service(), cacheKeys.Entity(), "memcache", err, memcache.ErrCacheMiss)
+		tracing.EndLookup(span, err, memcache.ErrCacheMiss)
+	}()
+
+	result, err := repo.db.Get(cacheKeys.Key(id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(result.Value), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (repo *memcacheRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "memcache delete", tracing.CacheMemcache)
+	defer func() { tracing.End(span, err) }()
+
+	err = repo.db.Delete(cacheKeys.Key(id))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
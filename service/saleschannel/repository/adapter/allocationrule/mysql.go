@@ -0,0 +1,266 @@
+package allocationrule
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils"
+)
+
+type mysqlRepository struct {
+	db utils.DBExecutor
+}
+
+func NewMySQLRepository(db utils.DBExecutor) port.AllocationRuleMainRepository {
+	return &mysqlRepository{
+		db: db,
+	}
+}
+
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.AllocationRule) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Insert("allocation_rules").Rows(
+		goqu.Record{
+			"id":         data.ID,
+			"channel_id": data.ChannelID,
+			"sku":        data.SKU,
+			"type":       data.Type,
+			"value":      data.Value,
+			"created_at": data.CreatedAt,
+			"updated_at": data.UpdatedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) Update(ctx context.Context, data *model.AllocationRule) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Update("allocation_rules").Set(
+		goqu.Record{
+			"channel_id": data.ChannelID,
+			"sku":        data.SKU,
+			"type":       data.Type,
+			"value":      data.Value,
+			"updated_at": data.UpdatedAt,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.AllocationRule, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("allocation_rules")
+	dataset = dataset.Where(goqu.Ex{"id": id})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	row := repo.db.QueryRowContext(ctx, query)
+	result = &model.AllocationRule{}
+	err = row.Scan(
+		&result.ID,
+		&result.ChannelID,
+		&result.SKU,
+		&result.Type,
+		&result.Value,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *mysqlRepository) FindByFilter(ctx context.Context, filter model.AllocationRuleFilter, lock bool) (result []*model.AllocationRule, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("allocation_rules")
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	allocationRules := []*model.AllocationRule{}
+	for res.Next() {
+		item := &model.AllocationRule{}
+		err := res.Scan(
+			&item.ID,
+			&item.ChannelID,
+			&item.SKU,
+			&item.Type,
+			&item.Value,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		allocationRules = append(allocationRules, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return allocationRules, nil
+}
+
+func (repo *mysqlRepository) FindPage(ctx context.Context, filter model.AllocationRuleFilter, offset, limit int64) (result []*model.AllocationRule, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("allocation_rules")
+	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	allocationRules := []*model.AllocationRule{}
+	for res.Next() {
+		item := &model.AllocationRule{}
+		err := res.Scan(
+			&item.ID,
+			&item.ChannelID,
+			&item.SKU,
+			&item.Type,
+			&item.Value,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		allocationRules = append(allocationRules, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return allocationRules, nil
+}
+
+func (repo *mysqlRepository) FindTotalByFilter(ctx context.Context, filter model.AllocationRuleFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("allocation_rules")
+	dataset = dataset.Select(goqu.COUNT("*"))
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "dataset error")
+	}
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "scan error")
+	}
+
+	return total, nil
+}
+
+func (repo *mysqlRepository) Delete(ctx context.Context, filter model.AllocationRuleFilter) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Delete("allocation_rules")
+	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) addFilter(dataset *goqu.SelectDataset, filter model.AllocationRuleFilter) *goqu.SelectDataset {
+	if len(filter.IDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	}
+
+	if len(filter.ChannelIDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"channel_id": filter.ChannelIDs})
+	}
+
+	if len(filter.SKUs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"sku": filter.SKUs})
+	}
+
+	return dataset
+}
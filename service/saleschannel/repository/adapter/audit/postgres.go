@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils"
+)
+
+type postgresRepository struct {
+	db utils.DBExecutor
+}
+
+func NewPostgresRepository(db utils.DBExecutor) port.AuditMainRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Audit) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Insert("saleschannel_audit_logs").Rows(
+		goqu.Record{
+			"id":             data.ID,
+			"entity_type":    data.EntityType,
+			"entity_id":      data.EntityID,
+			"action":         data.Action,
+			"before_data":    data.Before,
+			"after_data":     data.After,
+			"transaction_id": data.TransactionID,
+			"client_id":      data.ClientID,
+			"created_at":     data.CreatedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+// FindPageByEntity returns the history of one entity, newest change first.
+func (repo *postgresRepository) FindPageByEntity(ctx context.Context, entityType string, entityID uuid.UUID, offset, limit int64) (result []*model.Audit, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("saleschannel_audit_logs")
+	dataset = dataset.Where(goqu.Ex{"entity_type": entityType, "entity_id": entityID})
+	dataset = dataset.Order(goqu.C("created_at").Desc()).Offset(uint(offset)).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	audits := []*model.Audit{}
+	for res.Next() {
+		item := &model.Audit{}
+		err := res.Scan(
+			&item.ID,
+			&item.EntityType,
+			&item.EntityID,
+			&item.Action,
+			&item.Before,
+			&item.After,
+			&item.TransactionID,
+			&item.ClientID,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		audits = append(audits, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return audits, nil
+}
+
+func (repo *postgresRepository) FindTotalByEntity(ctx context.Context, entityType string, entityID uuid.UUID) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("saleschannel_audit_logs")
+	dataset = dataset.Select(goqu.COUNT("*"))
+	dataset = dataset.Where(goqu.Ex{"entity_type": entityType, "entity_id": entityID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "dataset error")
+	}
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "scan error")
+	}
+
+	return total, nil
+}
@@ -0,0 +1,8 @@
+package channel
+
+import (
+	"go-poc/utils/cachekey"
+)
+
+// cacheKeys is the keyspace of model.Channel, see cachekey.Keyspace for the version.
+var cacheKeys = cachekey.New("saleschannel", "channel", 1)
@@ -1,6 +1,9 @@
 package channel
 
 import (
+	"context"
+	"time"
+
 	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
 	"github.com/google/uuid"
@@ -21,7 +24,10 @@ func NewPostgresRepository(db utils.DBExecutor) port.ChannelMainRepository {
 	}
 }
 
-func (repo *postgresRepository) Create(data *model.Channel) error {
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Channel) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Insert("channels").Rows(
 		goqu.Record{
@@ -29,6 +35,8 @@ func (repo *postgresRepository) Create(data *model.Channel) error {
 			"code":       data.Code,
 			"created_at": data.CreatedAt,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+			"deleted_at": data.DeletedAt,
 		},
 	)
 
@@ -37,12 +45,34 @@ func (repo *postgresRepository) Create(data *model.Channel) error {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) Update(ctx context.Context, data *model.Channel) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("channels").Set(
+		goqu.Record{
+			"code":       data.Code,
+			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	_, err = stmt.Exec()
+	_, err = repo.db.ExecContext(ctx, query)
 	if err != nil {
 		return stacktrace.Propagate(err, "exec error")
 	}
@@ -50,51 +80,65 @@ func (repo *postgresRepository) Create(data *model.Channel) error {
 	return nil
 }
 
-func (repo *postgresRepository) Update(data *model.Channel) error {
+func (repo *postgresRepository) UpdateIfVersion(ctx context.Context, data *model.Channel, version int) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Update("channels").Set(
 		goqu.Record{
 			"code":       data.Code,
 			"updated_at": data.UpdatedAt,
+			"version":    data.Version,
 		},
 	)
-	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+	dataset = dataset.Where(goqu.Ex{"id": data.ID, "version": version})
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "dataset error")
 	}
 
-	stmt, err := repo.db.Prepare(query)
+	res, err := repo.db.ExecContext(ctx, query)
 	if err != nil {
-		return stacktrace.Propagate(err, "prepare error")
+		return stacktrace.Propagate(err, "exec error")
 	}
 
-	_, err = stmt.Exec()
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return stacktrace.Propagate(err, "exec error")
+		return stacktrace.Propagate(err, "rows affected error")
+	}
+
+	// another writer moved the version on since the row was read
+	if affected == 0 {
+		return model.ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (repo *postgresRepository) FindByID(id uuid.UUID) (result *model.Channel, err error) {
+func (repo *postgresRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Channel, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("channels")
-	dataset = dataset.Where(goqu.Ex{"id": id})
+	dataset = dataset.Where(goqu.Ex{"id": id}, goqu.C("deleted_at").IsNull())
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	row := repo.db.QueryRow(query)
+	row := repo.db.QueryRowContext(ctx, query)
 	result = &model.Channel{}
 	err = row.Scan(
 		&result.ID,
 		&result.Code,
 		&result.CreatedAt,
 		&result.UpdatedAt,
+		&result.Version,
+		&result.DeletedAt,
 	)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "scan error")
@@ -103,7 +147,10 @@ func (repo *postgresRepository) FindByID(id uuid.UUID) (result *model.Channel, e
 	return result, nil
 }
 
-func (repo *postgresRepository) FindByFilter(filter model.ChannelFilter, lock bool) (result []*model.Channel, err error) {
+func (repo *postgresRepository) FindByFilter(ctx context.Context, filter model.ChannelFilter, lock bool) (result []*model.Channel, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("channels")
 	dataset = repo.addFilter(dataset, filter)
@@ -117,10 +164,11 @@ func (repo *postgresRepository) FindByFilter(filter model.ChannelFilter, lock bo
 		query += " FOR UPDATE"
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	channels := []*model.Channel{}
 	for res.Next() {
@@ -130,6 +178,8 @@ func (repo *postgresRepository) FindByFilter(filter model.ChannelFilter, lock bo
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -138,10 +188,17 @@ func (repo *postgresRepository) FindByFilter(filter model.ChannelFilter, lock bo
 		channels = append(channels, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return channels, nil
 }
 
-func (repo *postgresRepository) FindPage(filter model.ChannelFilter, offset, limit int64) (result []*model.Channel, err error) {
+func (repo *postgresRepository) FindPage(ctx context.Context, filter model.ChannelFilter, offset, limit int64) (result []*model.Channel, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("channels")
 	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
@@ -151,10 +208,11 @@ func (repo *postgresRepository) FindPage(filter model.ChannelFilter, offset, lim
 		return nil, stacktrace.Propagate(err, "dataset error")
 	}
 
-	res, err := repo.db.Query(query)
+	res, err := repo.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "query error")
 	}
+	defer res.Close()
 
 	channels := []*model.Channel{}
 	for res.Next() {
@@ -164,6 +222,8 @@ func (repo *postgresRepository) FindPage(filter model.ChannelFilter, offset, lim
 			&item.Code,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&item.Version,
+			&item.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -172,10 +232,17 @@ func (repo *postgresRepository) FindPage(filter model.ChannelFilter, offset, lim
 		channels = append(channels, item)
 	}
 
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
 	return channels, nil
 }
 
-func (repo *postgresRepository) FindTotalByFilter(filter model.ChannelFilter) (total int64, err error) {
+func (repo *postgresRepository) FindTotalByFilter(ctx context.Context, filter model.ChannelFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.From("channels")
 	dataset = dataset.Select(goqu.COUNT("*"))
@@ -186,7 +253,7 @@ func (repo *postgresRepository) FindTotalByFilter(filter model.ChannelFilter) (t
 		return 0, stacktrace.Propagate(err, "query error")
 	}
 
-	err = repo.db.QueryRow(query).Scan(&total)
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
 	if err != nil {
 		return 0, stacktrace.Propagate(err, "scan error")
 	}
@@ -194,21 +261,74 @@ func (repo *postgresRepository) FindTotalByFilter(filter model.ChannelFilter) (t
 	return total, nil
 }
 
-func (repo *postgresRepository) Delete(filter model.ChannelFilter) error {
+// Delete tombstones the rows, they stay out of every query until restored
+// or purged.
+func (repo *postgresRepository) Delete(ctx context.Context, filter model.ChannelFilter) error {
+	return repo.setDeletedAt(ctx, filter, time.Now())
+}
+
+func (repo *postgresRepository) Restore(ctx context.Context, filter model.ChannelFilter) error {
+	return repo.setDeletedAt(ctx, filter, nil)
+}
+
+// Purge removes the rows tombstoned before the given time for good.
+func (repo *postgresRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
 	dialect := goqu.Dialect("postgres")
 	dataset := dialect.Delete("channels")
-	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	dataset = dataset.Where(goqu.C("deleted_at").Lt(before))
 
 	query, _, err := dataset.ToSQL()
 	if err != nil {
-		return stacktrace.Propagate(err, "dataset error")
+		return 0, stacktrace.Propagate(err, "query error")
+	}
+
+	res, err := repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "exec error")
+	}
+
+	total, err := res.RowsAffected()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "rows affected error")
+	}
+
+	return total, nil
+}
+
+func (repo *postgresRepository) setDeletedAt(ctx context.Context, filter model.ChannelFilter, deletedAt interface{}) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("channels").Set(
+		goqu.Record{
+			"deleted_at": deletedAt,
+			// a delete or restore is a new version, an ETag read before
+			// it no longer matches
+			"version": goqu.L("version + 1"),
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	if deletedAt == nil {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNotNull())
+	} else {
+		// keep the first deleted_at so the retention counts from the first delete
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
 	}
 
-	_, err = repo.db.Query(query)
+	query, _, err := dataset.ToSQL()
 	if err != nil {
 		return stacktrace.Propagate(err, "query error")
 	}
 
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
 	return nil
 }
 
@@ -221,5 +341,9 @@ func (repo *postgresRepository) addFilter(dataset *goqu.SelectDataset, filter mo
 		dataset = dataset.Where(goqu.Ex{"code": filter.Codes})
 	}
 
+	if !filter.IncludeDeleted {
+		dataset = dataset.Where(goqu.C("deleted_at").IsNull())
+	}
+
 	return dataset
 }
@@ -1,15 +1,19 @@
 package channel
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/go-redis/redis"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 
 	"go-poc/service/saleschannel/model"
 	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils/metrics"
+	"go-poc/utils/tracing"
 )
 
 type redisRepository struct {
@@ -22,13 +26,16 @@ func NewRedisRepository(db *redis.Client) port.ChannelCacheRepository {
 	}
 }
 
-func (repo *redisRepository) Set(data *model.Channel) error {
+func (repo *redisRepository) Set(ctx context.Context, data *model.Channel) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis set", tracing.CacheRedis)
+	defer func() { tracing.End(span, err) }()
+
 	value, err := json.Marshal(*data)
 	if err != nil {
 		return err
 	}
 
-	result := repo.db.Set(data.ID.String(), string(value), time.Duration(time.Hour*24*30))
+	result := repo.db.Set(cacheKeys.Key(data.ID), string(value), time.Duration(time.Hour*24*30))
 	if result.Err() != nil {
 		return result.Err()
 	}
@@ -36,8 +43,14 @@ func (repo *redisRepository) Set(data *model.Channel) error {
 	return nil
 }
 
-func (repo *redisRepository) Get(id uuid.UUID) (data *model.Channel, err error) {
-	result, err := repo.db.Get(id.String()).Result()
+func (repo *redisRepository) Get(ctx context.Context, id uuid.UUID) (data *model.Channel, err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis get", tracing.CacheRedis)
+	defer func() {
+		metrics.ObserveCacheLookup(cacheKeys.Service(), cacheKeys.Entity(), "redis", err, redis.Nil)
+		tracing.EndLookup(span, err, redis.Nil)
+	}()
+
+	result, err := repo.db.Get(cacheKeys.Key(id)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -54,8 +67,11 @@ func (repo *redisRepository) Get(id uuid.UUID) (data *model.Channel, err error)
 	return data, nil
 }
 
-func (repo *redisRepository) Delete(id uuid.UUID) error {
-	result := repo.db.Del(id.String())
+func (repo *redisRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis delete", tracing.CacheRedis)
+	defer func() { tracing.End(span, err) }()
+
+	result := repo.db.Del(cacheKeys.Key(id))
 	if result.Err() != nil {
 		return result.Err()
 	}
@@ -0,0 +1,8 @@
+package channelproduct
+
+import (
+	"go-poc/utils/cachekey"
+)
+
+// cacheKeys is the keyspace of model.ChannelProduct, see cachekey.Keyspace for the version.
+var cacheKeys = cachekey.New("saleschannel", "channel_product", 1)
@@ -0,0 +1,80 @@
+package channelproduct
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils/metrics"
+	"go-poc/utils/tracing"
+)
+
+type redisRepository struct {
+	db *redis.Client
+}
+
+func NewRedisRepository(db *redis.Client) port.ChannelProductCacheRepository {
+	return &redisRepository{
+		db: db,
+	}
+}
+
+func (repo *redisRepository) Set(ctx context.Context, data *model.ChannelProduct) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis set", tracing.CacheRedis)
+	defer func() { tracing.End(span, err) }()
+
+	value, err := json.Marshal(*data)
+	if err != nil {
+		return err
+	}
+
+	result := repo.db.Set(cacheKeys.Key(data.ID), string(value), time.Duration(time.Hour*24*30))
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
+
+func (repo *redisRepository) Get(ctx context.Context, id uuid.UUID) (data *model.ChannelProduct, err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis get", tracing.CacheRedis)
+	defer func() {
+		metrics.ObserveCacheLookup(cacheKeys.Service(), cacheKeys.Entity(), "redis", err, redis.Nil)
+		tracing.EndLookup(span, err, redis.Nil)
+	}()
+
+	result, err := repo.db.Get(cacheKeys.Key(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("not found")
+	}
+
+	err = json.Unmarshal([]byte(result), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (repo *redisRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	_, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "redis delete", tracing.CacheRedis)
+	defer func() { tracing.End(span, err) }()
+
+	result := repo.db.Del(cacheKeys.Key(id))
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils/httpclient"
+)
+
+type httpRepository struct {
+	doer    httpclient.HttpDoer
+	baseURL string
+	apiKey  string
+}
+
+type stockResponse struct {
+	Success bool           `json:"success"`
+	Data    []*model.Stock `json:"data"`
+	Error   *struct {
+		Code string `json:"code"`
+		Desc string `json:"desc"`
+	} `json:"error"`
+}
+
+// NewHTTPRepository sends apiKey in X-API-Key when set, inventory needs it
+// once authentication is on.
+func NewHTTPRepository(doer httpclient.HttpDoer, baseURL, apiKey string) port.InventoryRepository {
+	return &httpRepository{
+		doer:    doer,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+	}
+}
+
+func (repo *httpRepository) FindStock(ctx context.Context, skus []string) ([]*model.Stock, error) {
+	body, err := json.Marshal(map[string][]string{"skus": skus})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "marshal error")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, repo.baseURL+"/api/sourcing/stock", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "request error")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if repo.apiKey != "" {
+		req.Header.Set("X-API-Key", repo.apiKey)
+	}
+
+	respBytes, statusCode, err := repo.doer.Do(ctx, req)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "do request error")
+	}
+
+	// inventory answers 404 when none of the skus has stock
+	if statusCode == http.StatusNotFound {
+		return []*model.Stock{}, nil
+	}
+
+	res := stockResponse{}
+	if err := json.Unmarshal(respBytes, &res); err != nil {
+		return nil, stacktrace.Propagate(err, "unmarshal error")
+	}
+
+	if statusCode != http.StatusOK || !res.Success {
+		if res.Error != nil {
+			return nil, stacktrace.NewError("inventory error %d: %s", statusCode, res.Error.Desc)
+		}
+
+		return nil, stacktrace.NewError("inventory error %d", statusCode)
+	}
+
+	return res.Data, nil
+}
@@ -0,0 +1,43 @@
+package inventory
+
+import (
+	"context"
+
+	"github.com/palantir/stacktrace"
+
+	inventoryModel "go-poc/service/inventory/model"
+	inventoryUsecase "go-poc/service/inventory/usecase"
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+)
+
+type inProcessRepository struct {
+	sourcing inventoryUsecase.Sourcing
+}
+
+func NewInProcessRepository(sourcing inventoryUsecase.Sourcing) port.InventoryRepository {
+	return &inProcessRepository{
+		sourcing: sourcing,
+	}
+}
+
+func (repo *inProcessRepository) FindStock(ctx context.Context, skus []string) ([]*model.Stock, error) {
+	filter := inventoryModel.SourcingFilter{
+		SKUs: skus,
+	}
+
+	stocks, err := repo.sourcing.FindStock(ctx, filter)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find stock error")
+	}
+
+	results := []*model.Stock{}
+	for _, stock := range stocks {
+		results = append(results, &model.Stock{
+			SKU:         stock.SKU,
+			QtySaleable: stock.QtySaleable,
+		})
+	}
+
+	return results, nil
+}
@@ -3,7 +3,9 @@ package adapter
 import (
 	"github.com/rainycape/memcache"
 
+	"go-poc/service/saleschannel/repository/adapter/allocationrule"
 	"go-poc/service/saleschannel/repository/adapter/channel"
+	"go-poc/service/saleschannel/repository/adapter/channelproduct"
 	"go-poc/service/saleschannel/repository/port"
 )
 
@@ -20,3 +22,11 @@ func NewMemcache(db *memcache.Client) port.CacheRepository {
 func (r memcacheRegistry) Channel() port.ChannelCacheRepository {
 	return channel.NewMemcacheRepository(r.db)
 }
+
+func (r memcacheRegistry) ChannelProduct() port.ChannelProductCacheRepository {
+	return channelproduct.NewMemcacheRepository(r.db)
+}
+
+func (r memcacheRegistry) AllocationRule() port.AllocationRuleCacheRepository {
+	return allocationrule.NewMemcacheRepository(r.db)
+}
@@ -1,13 +1,19 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/pkg/errors"
 
+	"go-poc/service/saleschannel/repository/adapter/allocationrule"
+	"go-poc/service/saleschannel/repository/adapter/audit"
 	"go-poc/service/saleschannel/repository/adapter/channel"
+	"go-poc/service/saleschannel/repository/adapter/channelproduct"
+	"go-poc/service/saleschannel/repository/adapter/outbox"
 	"go-poc/service/saleschannel/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/tracing"
 )
 
 type mysqlRegistry struct {
@@ -25,14 +31,42 @@ func (r mysqlRegistry) Channel() port.ChannelMainRepository {
 	if r.dbexecutor != nil {
 		return channel.NewMySQLRepository(r.dbexecutor)
 	}
-	return channel.NewMySQLRepository(r.db)
+	return channel.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
 }
 
-func (r mysqlRegistry) DoInTransaction(txFunc port.InTransaction) (out interface{}, err error) {
+func (r mysqlRegistry) ChannelProduct() port.ChannelProductMainRepository {
+	if r.dbexecutor != nil {
+		return channelproduct.NewMySQLRepository(r.dbexecutor)
+	}
+	return channelproduct.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) AllocationRule() port.AllocationRuleMainRepository {
+	if r.dbexecutor != nil {
+		return allocationrule.NewMySQLRepository(r.dbexecutor)
+	}
+	return allocationrule.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) Outbox() port.OutboxMainRepository {
+	if r.dbexecutor != nil {
+		return outbox.NewMySQLRepository(r.dbexecutor)
+	}
+	return outbox.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) Audit() port.AuditMainRepository {
+	if r.dbexecutor != nil {
+		return audit.NewMySQLRepository(r.dbexecutor)
+	}
+	return audit.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
 	var tx *sql.Tx
 	registry := r
 	if r.dbexecutor == nil {
-		tx, err = r.db.Begin()
+		tx, err = r.db.BeginTx(ctx, nil)
 		if err != nil {
 			return
 		}
@@ -59,7 +93,7 @@ func (r mysqlRegistry) DoInTransaction(txFunc port.InTransaction) (out interface
 		}()
 		registry = mysqlRegistry{
 			db:         r.db,
-			dbexecutor: tx,
+			dbexecutor: tracing.DB(tx, tracing.DBMySQL),
 		}
 	}
 	out, err = txFunc(registry)
@@ -1,13 +1,19 @@
 package adapter
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/pkg/errors"
 
+	"go-poc/service/saleschannel/repository/adapter/allocationrule"
+	"go-poc/service/saleschannel/repository/adapter/audit"
 	"go-poc/service/saleschannel/repository/adapter/channel"
+	"go-poc/service/saleschannel/repository/adapter/channelproduct"
+	"go-poc/service/saleschannel/repository/adapter/outbox"
 	"go-poc/service/saleschannel/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/tracing"
 )
 
 type postgresRegistry struct {
@@ -25,14 +31,42 @@ func (r postgresRegistry) Channel() port.ChannelMainRepository {
 	if r.dbexecutor != nil {
 		return channel.NewPostgresRepository(r.dbexecutor)
 	}
-	return channel.NewPostgresRepository(r.db)
+	return channel.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
 }
 
-func (r postgresRegistry) DoInTransaction(txFunc port.InTransaction) (out interface{}, err error) {
+func (r postgresRegistry) ChannelProduct() port.ChannelProductMainRepository {
+	if r.dbexecutor != nil {
+		return channelproduct.NewPostgresRepository(r.dbexecutor)
+	}
+	return channelproduct.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) AllocationRule() port.AllocationRuleMainRepository {
+	if r.dbexecutor != nil {
+		return allocationrule.NewPostgresRepository(r.dbexecutor)
+	}
+	return allocationrule.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) Outbox() port.OutboxMainRepository {
+	if r.dbexecutor != nil {
+		return outbox.NewPostgresRepository(r.dbexecutor)
+	}
+	return outbox.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) Audit() port.AuditMainRepository {
+	if r.dbexecutor != nil {
+		return audit.NewPostgresRepository(r.dbexecutor)
+	}
+	return audit.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
 	var tx *sql.Tx
 	registry := r
 	if r.dbexecutor == nil {
-		tx, err = r.db.Begin()
+		tx, err = r.db.BeginTx(ctx, nil)
 		if err != nil {
 			return
 		}
@@ -59,7 +93,7 @@ func (r postgresRegistry) DoInTransaction(txFunc port.InTransaction) (out interf
 		}()
 		registry = postgresRegistry{
 			db:         r.db,
-			dbexecutor: tx,
+			dbexecutor: tracing.DB(tx, tracing.DBPostgres),
 		}
 	}
 	out, err = txFunc(registry)
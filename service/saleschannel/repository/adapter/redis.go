@@ -3,7 +3,9 @@ package adapter
 import (
 	"github.com/go-redis/redis"
 
+	"go-poc/service/saleschannel/repository/adapter/allocationrule"
 	"go-poc/service/saleschannel/repository/adapter/channel"
+	"go-poc/service/saleschannel/repository/adapter/channelproduct"
 	"go-poc/service/saleschannel/repository/port"
 )
 
@@ -20,3 +22,11 @@ func NewRedis(db *redis.Client) port.CacheRepository {
 func (r redisRegistry) Channel() port.ChannelCacheRepository {
 	return channel.NewRedisRepository(r.db)
 }
+
+func (r redisRegistry) ChannelProduct() port.ChannelProductCacheRepository {
+	return channelproduct.NewRedisRepository(r.db)
+}
+
+func (r redisRegistry) AllocationRule() port.AllocationRuleCacheRepository {
+	return allocationrule.NewRedisRepository(r.db)
+}
@@ -0,0 +1,24 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/saleschannel/model"
+)
+
+type AllocationRuleMainRepository interface {
+	Create(ctx context.Context, data *model.AllocationRule) error
+	Update(ctx context.Context, data *model.AllocationRule) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.AllocationRule, error)
+	FindByFilter(ctx context.Context, filter model.AllocationRuleFilter, lock bool) ([]*model.AllocationRule, error)
+	FindPage(ctx context.Context, filter model.AllocationRuleFilter, offset, limit int64) ([]*model.AllocationRule, error)
+	FindTotalByFilter(ctx context.Context, filter model.AllocationRuleFilter) (int64, error)
+	Delete(ctx context.Context, filter model.AllocationRuleFilter) error
+}
+
+type AllocationRuleCacheRepository interface {
+	Set(ctx context.Context, data *model.AllocationRule) error
+	Get(ctx context.Context, id uuid.UUID) (*model.AllocationRule, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -1,23 +1,29 @@
 package port
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 
 	"go-poc/service/saleschannel/model"
 )
 
 type ChannelMainRepository interface {
-	Create(data *model.Channel) error
-	Update(data *model.Channel) error
-	FindByID(id uuid.UUID) (*model.Channel, error)
-	FindByFilter(filter model.ChannelFilter, lock bool) ([]*model.Channel, error)
-	FindPage(filter model.ChannelFilter, offset, limit int64) ([]*model.Channel, error)
-	FindTotalByFilter(filter model.ChannelFilter) (int64, error)
-	Delete(filter model.ChannelFilter) error
+	Create(ctx context.Context, data *model.Channel) error
+	Update(ctx context.Context, data *model.Channel) error
+	UpdateIfVersion(ctx context.Context, data *model.Channel, version int) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Channel, error)
+	FindByFilter(ctx context.Context, filter model.ChannelFilter, lock bool) ([]*model.Channel, error)
+	FindPage(ctx context.Context, filter model.ChannelFilter, offset, limit int64) ([]*model.Channel, error)
+	FindTotalByFilter(ctx context.Context, filter model.ChannelFilter) (int64, error)
+	Delete(ctx context.Context, filter model.ChannelFilter) error
+	Restore(ctx context.Context, filter model.ChannelFilter) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
 
 type ChannelCacheRepository interface {
-	Set(data *model.Channel) error
-	Get(id uuid.UUID) (*model.Channel, error)
-	Delete(id uuid.UUID) error
+	Set(ctx context.Context, data *model.Channel) error
+	Get(ctx context.Context, id uuid.UUID) (*model.Channel, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -0,0 +1,24 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/saleschannel/model"
+)
+
+type ChannelProductMainRepository interface {
+	Create(ctx context.Context, data *model.ChannelProduct) error
+	Update(ctx context.Context, data *model.ChannelProduct) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.ChannelProduct, error)
+	FindByFilter(ctx context.Context, filter model.ChannelProductFilter, lock bool) ([]*model.ChannelProduct, error)
+	FindPage(ctx context.Context, filter model.ChannelProductFilter, offset, limit int64) ([]*model.ChannelProduct, error)
+	FindTotalByFilter(ctx context.Context, filter model.ChannelProductFilter) (int64, error)
+	Delete(ctx context.Context, filter model.ChannelProductFilter) error
+}
+
+type ChannelProductCacheRepository interface {
+	Set(ctx context.Context, data *model.ChannelProduct) error
+	Get(ctx context.Context, id uuid.UUID) (*model.ChannelProduct, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
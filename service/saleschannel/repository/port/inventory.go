@@ -0,0 +1,13 @@
+package port
+
+import (
+	"context"
+
+	"go-poc/service/saleschannel/model"
+)
+
+// InventoryRepository reads stock owned by the inventory service, the
+// adapter decides whether it is reached in process or over HTTP.
+type InventoryRepository interface {
+	FindStock(ctx context.Context, skus []string) ([]*model.Stock, error)
+}
@@ -1,12 +1,20 @@
 package port
 
+import "context"
+
 type InTransaction func(repoRegistry MainRepository) (interface{}, error)
 
 type MainRepository interface {
 	Channel() ChannelMainRepository
-	DoInTransaction(txFunc InTransaction) (out interface{}, err error)
+	ChannelProduct() ChannelProductMainRepository
+	AllocationRule() AllocationRuleMainRepository
+	Outbox() OutboxMainRepository
+	Audit() AuditMainRepository
+	DoInTransaction(ctx context.Context, txFunc InTransaction) (out interface{}, err error)
 }
 
 type CacheRepository interface {
 	Channel() ChannelCacheRepository
+	ChannelProduct() ChannelProductCacheRepository
+	AllocationRule() AllocationRuleCacheRepository
 }
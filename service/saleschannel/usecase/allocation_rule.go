@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sync/semaphore"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+	"go-poc/utils/tracing"
+)
+
+const (
+	CacheAllocationRule = "cache_allocation_rule"
+)
+
+type AllocationRule interface {
+	Upsert(ctx context.Context, inputs []model.AllocationRuleInput) (outputs []model.AllocationRuleOutput, err error)
+	Delete(ctx context.Context, filter model.AllocationRuleFilter) error
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.AllocationRule, error)
+	FindByFilter(ctx context.Context, filter model.AllocationRuleFilter) ([]*model.AllocationRule, error)
+	FindPage(ctx context.Context, filter model.AllocationRuleFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type allocationRuleKey struct {
+	channelID uuid.UUID
+	sku       string
+}
+
+type allocationRuleService struct {
+	main    port.MainRepository
+	cache   port.CacheRepository
+	workers int
+}
+
+func NewAllocationRule(
+	main port.MainRepository,
+	cache port.CacheRepository,
+	cfg config.SalesChannel,
+) AllocationRule {
+	return withAllocationRuleMetrics(&allocationRuleService{
+		main:    main,
+		cache:   cache,
+		workers: cfg.AllocationRuleWorkers,
+	})
+}
+
+func (s *allocationRuleService) Upsert(ctx context.Context, inputs []model.AllocationRuleInput) (outputs []model.AllocationRuleOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.AllocationRule.Upsert")
+	defer span.End()
+
+	if forbidden := forbiddenAllocationRuleInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		allocationRuleRepository := repoRegistry.AllocationRule()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+		ids := []uuid.UUID{}
+		channelIDs := []uuid.UUID{}
+		skus := []string{}
+
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+			channelIDs = append(channelIDs, input.ChannelID)
+			skus = append(skus, input.SKU)
+		}
+
+		allocationRules := []*model.AllocationRule{}
+		if len(ids) > 0 {
+			filter := model.AllocationRuleFilter{
+				IDs: ids,
+			}
+
+			allocationRules, err = allocationRuleRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find allocation rule by filter error")
+			}
+
+			// a allocation rule is also identified by its channel and sku
+			filter = model.AllocationRuleFilter{
+				ChannelIDs: channelIDs,
+				SKUs:       skus,
+			}
+
+			allocationRulesByKey, err := allocationRuleRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find allocation rule by filter error")
+			}
+
+			allocationRules = append(allocationRules, allocationRulesByKey...)
+		}
+
+		allocationRuleMap := make(map[uuid.UUID]model.AllocationRule)
+		allocationRuleKeyMap := make(map[allocationRuleKey]uuid.UUID)
+		for _, allocationRuleData := range allocationRules {
+			allocationRuleMap[allocationRuleData.ID] = *allocationRuleData
+			allocationRuleKeyMap[allocationRuleKey{allocationRuleData.ChannelID, allocationRuleData.SKU}] = allocationRuleData.ID
+		}
+
+		// an id may point at a row on another channel than the input's
+		forbidden := []model.AllocationRuleOutput{}
+		for _, input := range inputs {
+			if allocationRuleData, exist := allocationRuleMap[input.ID]; exist && !channelPermitted(ctx, allocationRuleData.ChannelID) {
+				forbidden = append(forbidden, model.AllocationRuleOutput{
+					ID:        allocationRuleData.ID,
+					ChannelID: allocationRuleData.ChannelID,
+					SKU:       allocationRuleData.SKU,
+					Message:   model.ErrChannelForbidden.Error(),
+				})
+			}
+		}
+
+		if len(forbidden) > 0 {
+			return forbidden, model.ErrChannelForbidden
+		}
+
+		for i, input := range inputs {
+			if _, exist := allocationRuleMap[input.ID]; exist {
+				continue
+			}
+
+			if id, exist := allocationRuleKeyMap[allocationRuleKey{input.ChannelID, input.SKU}]; exist {
+				inputs[i].ID = id
+			}
+		}
+
+		upsertAllocationRuleWorker := s.workers
+
+		outputChan := make(chan model.AllocationRuleOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertAllocationRuleWorker))
+		for _, inputData := range inputs {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.AllocationRuleInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				if err := inputDataInWorker.Validate(); err != nil {
+					output := model.AllocationRuleOutput{
+						ID:        inputDataInWorker.ID,
+						ChannelID: inputDataInWorker.ChannelID,
+						SKU:       inputDataInWorker.SKU,
+						Message:   err.Error(),
+					}
+
+					outputChan <- output
+					return
+				}
+
+				if allocationRuleData, exist := allocationRuleMap[inputDataInWorker.ID]; exist {
+					allocationRuleBefore := allocationRuleData
+					allocationRuleData.Update(inputDataInWorker)
+					err := allocationRuleRepository.Update(ctx, &allocationRuleData)
+					if err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventAllocationRuleUpserted, allocationRuleData.ID, &allocationRuleData); err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityAllocationRule, allocationRuleData.ID, model.AuditActionUpdate, &allocationRuleBefore, &allocationRuleData); err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.AllocationRule().Set)(ctx, &allocationRuleData)
+				} else {
+					allocationRuleData := model.NewAllocationRule(inputDataInWorker)
+					err := allocationRuleRepository.Create(ctx, allocationRuleData)
+					if err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventAllocationRuleUpserted, allocationRuleData.ID, allocationRuleData); err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityAllocationRule, allocationRuleData.ID, model.AuditActionCreate, nil, allocationRuleData); err != nil {
+						output := model.AllocationRuleOutput{
+							ID:        allocationRuleData.ID,
+							ChannelID: allocationRuleData.ChannelID,
+							SKU:       allocationRuleData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.AllocationRule().Set)(ctx, allocationRuleData)
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertAllocationRuleWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, errors.New("internal server error")
+		}
+
+		return nil, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.AllocationRuleOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *allocationRuleService) Delete(ctx context.Context, filter model.AllocationRuleFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.AllocationRule.Delete")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		allocationRuleRepository := repoRegistry.AllocationRule()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		allocationRules, err := allocationRuleRepository.FindByFilter(ctx, model.AllocationRuleFilter{IDs: filter.IDs}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find allocation rule by filter error")
+		}
+
+		for _, allocationRuleData := range allocationRules {
+			if !channelPermitted(ctx, allocationRuleData.ChannelID) {
+				return nil, model.ErrChannelForbidden
+			}
+		}
+
+		if err := allocationRuleRepository.Delete(ctx, filter); err != nil {
+			return nil, stacktrace.Propagate(err, "delete allocation rule error")
+		}
+
+		for _, id := range filter.IDs {
+			if err := writeOutbox(ctx, outboxRepository, model.EventAllocationRuleDeleted, id, map[string]uuid.UUID{"id": id}); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+		}
+
+		for _, allocationRuleData := range allocationRules {
+			if err := writeAudit(ctx, auditRepository, model.EntityAllocationRule, allocationRuleData.ID, model.AuditActionDelete, allocationRuleData, nil); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	for _, id := range filter.IDs {
+		go lifecycle.Background(s.cache.AllocationRule().Delete)(ctx, id)
+	}
+
+	return nil
+}
+
+func (s *allocationRuleService) FindByID(ctx context.Context, id uuid.UUID) (*model.AllocationRule, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.AllocationRule.FindByID")
+	defer span.End()
+
+	allocationRuleDataCache, err := s.cache.AllocationRule().Get(ctx, id)
+	if err == nil {
+		if !channelPermitted(ctx, allocationRuleDataCache.ChannelID) {
+			return nil, model.ErrChannelForbidden
+		}
+
+		return allocationRuleDataCache, nil
+	}
+
+	allocationRuleRepository := s.main.AllocationRule()
+	allocationRuleData, err := allocationRuleRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find allocation rule by id error")
+	}
+
+	go lifecycle.Background(s.cache.AllocationRule().Set)(ctx, allocationRuleData)
+
+	if !channelPermitted(ctx, allocationRuleData.ChannelID) {
+		return nil, model.ErrChannelForbidden
+	}
+
+	return allocationRuleData, nil
+}
+
+// FindByFilter only finds the allocation rules on channels the caller is
+// permitted.
+func (s *allocationRuleService) FindByFilter(ctx context.Context, filter model.AllocationRuleFilter) ([]*model.AllocationRule, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.AllocationRule.FindByFilter")
+	defer span.End()
+
+	channelIDs, ok := permitChannelIDs(ctx, filter.ChannelIDs)
+	if !ok {
+		return []*model.AllocationRule{}, nil
+	}
+
+	filter.ChannelIDs = channelIDs
+
+	allocationRuleRepository := s.main.AllocationRule()
+	results, err := allocationRuleRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.AllocationRule{}, stacktrace.Propagate(err, "find allocation rule by filter error")
+	}
+
+	return results, nil
+}
+
+// FindPage only pages through the allocation rules on channels the caller
+// is permitted.
+func (s *allocationRuleService) FindPage(ctx context.Context, filter model.AllocationRuleFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.AllocationRule.FindPage")
+	defer span.End()
+
+	channelIDs, ok := permitChannelIDs(ctx, filter.ChannelIDs)
+	if !ok {
+		return utils.PaginatePageLimit([]*model.AllocationRule{}, 0, page, limit), nil
+	}
+
+	filter.ChannelIDs = channelIDs
+
+	allocationRuleRepository := s.main.AllocationRule()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := allocationRuleRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find allocation rule page error")
+	}
+
+	total, err := allocationRuleRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total allocation rule by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
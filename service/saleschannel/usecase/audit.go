@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+)
+
+// writeAudit records one change through the given repository, inside
+// DoInTransaction it commits or rolls back together with the change.
+func writeAudit(ctx context.Context, auditRepository port.AuditMainRepository, entityType string, entityID uuid.UUID, action string, before, after interface{}) error {
+	auditData, err := model.NewAudit(ctx, entityType, entityID, action, before, after)
+	if err != nil {
+		return stacktrace.Propagate(err, "new audit error")
+	}
+
+	if err := auditRepository.Create(ctx, auditData); err != nil {
+		return stacktrace.Propagate(err, "create audit error")
+	}
+
+	return nil
+}
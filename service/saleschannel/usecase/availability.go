@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils/tracing"
+)
+
+type Availability interface {
+	FindByChannelID(ctx context.Context, channelID uuid.UUID) ([]*model.Availability, error)
+}
+
+type availabilityService struct {
+	main      port.MainRepository
+	inventory port.InventoryRepository
+}
+
+func NewAvailability(
+	main port.MainRepository,
+	inventory port.InventoryRepository,
+) Availability {
+	return withAvailabilityMetrics(&availabilityService{
+		main:      main,
+		inventory: inventory,
+	})
+}
+
+func (s *availabilityService) FindByChannelID(ctx context.Context, channelID uuid.UUID) ([]*model.Availability, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Availability.FindByChannelID")
+	defer span.End()
+
+	if !channelPermitted(ctx, channelID) {
+		return nil, model.ErrChannelForbidden
+	}
+
+	channelData, err := s.main.Channel().FindByID(ctx, channelID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel by id error")
+	}
+
+	filter := model.ChannelProductFilter{
+		ChannelIDs: []uuid.UUID{channelData.ID},
+	}
+
+	channelProducts, err := s.main.ChannelProduct().FindByFilter(ctx, filter, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel product by filter error")
+	}
+
+	results := []*model.Availability{}
+	if len(channelProducts) == 0 {
+		return results, nil
+	}
+
+	skus := []string{}
+	for _, channelProductData := range channelProducts {
+		skus = append(skus, channelProductData.SKU)
+	}
+
+	stocks, err := s.inventory.FindStock(ctx, skus)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find stock error")
+	}
+
+	stockMap := make(map[string]int)
+	for _, stock := range stocks {
+		stockMap[stock.SKU] = stock.QtySaleable
+	}
+
+	ruleFilter := model.AllocationRuleFilter{
+		ChannelIDs: []uuid.UUID{channelData.ID},
+	}
+
+	allocationRules, err := s.main.AllocationRule().FindByFilter(ctx, ruleFilter, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find allocation rule by filter error")
+	}
+
+	ruleMap := make(map[string]*model.AllocationRule)
+	for _, allocationRuleData := range allocationRules {
+		ruleMap[allocationRuleData.SKU] = allocationRuleData
+	}
+
+	for _, channelProductData := range channelProducts {
+		availability := &model.Availability{
+			ChannelID:        channelData.ID,
+			ChannelProductID: channelProductData.ID,
+			SKU:              channelProductData.SKU,
+			Name:             channelProductData.Name,
+			QtySaleable:      stockMap[channelProductData.SKU],
+			QtyAvailable:     stockMap[channelProductData.SKU],
+		}
+
+		// a rule for the sku wins over the channel wildcard
+		allocationRuleData, exist := ruleMap[channelProductData.SKU]
+		if !exist {
+			allocationRuleData, exist = ruleMap[model.AllocationRuleWildcard]
+		}
+
+		if exist {
+			availability.QtyAvailable = allocationRuleData.Apply(availability.QtySaleable)
+			availability.AllocationRuleID = allocationRuleData.ID
+		}
+
+		results = append(results, availability)
+	}
+
+	return results, nil
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,11 +13,11 @@ import (
 	"go-poc/service/saleschannel/model"
 	"go-poc/service/saleschannel/repository/port"
 	"go-poc/utils"
+	"go-poc/utils/activity"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
 	"go-poc/utils/log"
-)
-
-const (
-	CacheChannel = "cache_channel"
+	"go-poc/utils/tracing"
 )
 
 type Channel interface {
@@ -27,228 +25,70 @@ type Channel interface {
 	UpsertBatchFetching(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error)
 	UpsertWithTransaction(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error)
 	UpsertWithLock(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error)
-	Delete(filter model.ChannelFilter) error
-	FindByID(ID uuid.UUID) (*model.Channel, error)
-	FindByFilter(filter model.ChannelFilter) ([]*model.Channel, error)
-	FindPage(filter model.ChannelFilter, page, limit int64) (utils.Pagination, error)
+	Delete(ctx context.Context, filter model.ChannelFilter) error
+	DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error
+	Restore(ctx context.Context, filter model.ChannelFilter) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.Channel, error)
+	FindByFilter(ctx context.Context, filter model.ChannelFilter) ([]*model.Channel, error)
+	FindPage(ctx context.Context, filter model.ChannelFilter, page, limit int64) (utils.Pagination, error)
+	History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error)
+	WarmCache(ctx context.Context, batchSize int64) (int, error)
 }
 
-type service struct {
-	main  port.MainRepository
-	cache port.CacheRepository
+type channelService struct {
+	main    port.MainRepository
+	cache   port.CacheRepository
+	workers int
 }
 
 func NewChannel(
 	main port.MainRepository,
 	cache port.CacheRepository,
+	cfg config.SalesChannel,
 ) Channel {
-	return &service{
-		main:  main,
-		cache: cache,
-	}
-}
-
-func (s *service) Upsert(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
-	channelRepository := s.main.Channel()
-	upsertChannelWorker := 5
-	if os.Getenv("UPDATE_CHANNEL_WORKER") != "" {
-		upsertChannelWorkerEnv, err := strconv.Atoi(os.Getenv("UPDATE_CHANNEL_WORKER"))
-		if err == nil {
-			upsertChannelWorker = upsertChannelWorkerEnv
-		}
-	}
-
-	outputChan := make(chan model.ChannelOutput, len(inputs))
-	workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
-
-	for _, inputData := range inputs {
-		err := workerSemaphore.Acquire(ctx, 1)
-		if err != nil {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
-			continue
-		}
-
-		go func(inputDataInWorker model.ChannelInput) {
-			defer workerSemaphore.Release(1)
-			channelData, err := channelRepository.FindByID(inputDataInWorker.ID)
-			if err == nil {
-				channelData.Update(inputDataInWorker)
-				err := channelRepository.Update(channelData)
-				if err != nil {
-					output := model.ChannelOutput{
-						ID:      channelData.ID,
-						Code:    channelData.Code,
-						Message: stacktrace.RootCause(err).Error(),
-					}
-
-					outputChan <- output
-					return
-				}
-				err = s.cache.Channel().Set(channelData)
-				if err != nil {
-					log.WithContext(ctx).Error(stacktrace.Propagate(err, "cache error"))
-				}
-			} else if stacktrace.RootCause(err) == sql.ErrNoRows {
-				channelData := model.NewChannel(inputDataInWorker)
-				err := channelRepository.Create(channelData)
-				if err != nil {
-					output := model.ChannelOutput{
-						ID:      channelData.ID,
-						Code:    channelData.Code,
-						Message: stacktrace.RootCause(err).Error(),
-					}
-
-					outputChan <- output
-					return
-				}
-				err = s.cache.Channel().Set(channelData)
-				if err != nil {
-					log.WithContext(ctx).Error(stacktrace.Propagate(err, "cache error"))
-				}
-			} else {
-				output := model.ChannelOutput{
-					ID:      channelData.ID,
-					Code:    channelData.Code,
-					Message: stacktrace.RootCause(err).Error(),
-				}
-
-				outputChan <- output
-				return
-			}
-		}(inputData)
-	}
-
-	if err := workerSemaphore.Acquire(ctx, int64(upsertChannelWorker)); err != nil {
-		return nil, stacktrace.Propagate(err, "acquire worker error")
-	}
-
-	close(outputChan)
-	for outputData := range outputChan {
-		outputs = append(outputs, outputData)
-	}
-
-	if len(outputs) > 0 {
-		return outputs, errors.New("internal server error")
-	}
-
-	return nil, nil
+	return withChannelMetrics(&channelService{
+		main:    main,
+		cache:   cache,
+		workers: cfg.ChannelWorkers,
+	})
 }
 
-func (s *service) UpsertBatchFetching(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
-	channelRepository := s.main.Channel()
-	ids := []uuid.UUID{}
-
-	for _, input := range inputs {
-		ids = append(ids, input.ID)
-	}
+func (s *channelService) Upsert(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.Upsert")
+	defer span.End()
 
-	upsertChannelWorker := 5
-	if os.Getenv("UPDATE_CHANNEL_WORKER") != "" {
-		upsertChannelWorkerEnv, err := strconv.Atoi(os.Getenv("UPDATE_CHANNEL_WORKER"))
-		if err == nil {
-			upsertChannelWorker = upsertChannelWorkerEnv
-		}
+	if forbidden := forbiddenChannelInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
 	}
 
-	outputChan := make(chan model.ChannelOutput, len(inputs))
-	workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
-
-	channels := []*model.Channel{}
-	if len(ids) > 0 {
-		filter := model.ChannelFilter{
-			IDs: ids,
-		}
+	// the written channels are cached once committed, so no reader sees
+	// a row that may still be rolled back
+	writtenChan := make(chan *model.Channel, len(inputs))
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
 
-		channels, err = channelRepository.FindByFilter(filter, true)
+		deleted, err := deletedChannelInputs(ctx, channelRepository, inputs)
 		if err != nil {
-			return nil, stacktrace.Propagate(err, "find channel by filter error")
+			return nil, stacktrace.Propagate(err, "find deleted channel error")
 		}
-	}
 
-	channelMap := make(map[uuid.UUID]model.Channel)
-	for _, channelData := range channels {
-		channelMap[channelData.ID] = *channelData
-	}
-
-	for _, inputData := range inputs {
-		err := workerSemaphore.Acquire(ctx, 1)
-		if err != nil {
-			log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
-			continue
+		if len(deleted) > 0 {
+			return deleted, channelUpsertError(deleted)
 		}
 
-		go func(inputDataInWorker model.ChannelInput) {
-			defer workerSemaphore.Release(1)
-			if channelData, exist := channelMap[inputDataInWorker.ID]; exist {
-				channelData.Update(inputDataInWorker)
-				err := channelRepository.Update(&channelData)
-				if err != nil {
-					output := model.ChannelOutput{
-						ID:      channelData.ID,
-						Code:    channelData.Code,
-						Message: stacktrace.RootCause(err).Error(),
-					}
-
-					outputChan <- output
-					return
-				}
-				err = s.cache.Channel().Set(&channelData)
-				if err != nil {
-					log.WithContext(ctx).Error(stacktrace.Propagate(err, "cache error"))
-				}
-			} else {
-				channelData := model.NewChannel(inputDataInWorker)
-				err := channelRepository.Create(channelData)
-				if err != nil {
-					output := model.ChannelOutput{
-						ID:      channelData.ID,
-						Code:    channelData.Code,
-						Message: stacktrace.RootCause(err).Error(),
-					}
-
-					outputChan <- output
-					return
-				}
-				err = s.cache.Channel().Set(channelData)
-				if err != nil {
-					log.WithContext(ctx).Error(stacktrace.Propagate(err, "cache error"))
-				}
-			}
-		}(inputData)
-	}
-
-	if err := workerSemaphore.Acquire(ctx, int64(upsertChannelWorker)); err != nil {
-		return nil, stacktrace.Propagate(err, "acquire worker error")
-	}
-
-	close(outputChan)
-	for outputData := range outputChan {
-		outputs = append(outputs, outputData)
-	}
-
-	if len(outputs) > 0 {
-		return outputs, errors.New("internal server error")
-	}
-
-	return nil, nil
-}
-
-func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
-	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
-		channelRepository := repoRegistry.Channel()
+		// the rows are read up front, the workers share one connection and
+		// a query still streaming its rows would interleave with their writes
 		ids := []uuid.UUID{}
-
 		for _, input := range inputs {
 			ids = append(ids, input.ID)
 		}
 
 		channels := []*model.Channel{}
 		if len(ids) > 0 {
-			filter := model.ChannelFilter{
-				IDs: ids,
-			}
-
-			channels, err = channelRepository.FindByFilter(filter, true)
+			channels, err = channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: ids}, true)
 			if err != nil {
 				return nil, stacktrace.Propagate(err, "find channel by filter error")
 			}
@@ -259,16 +99,11 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 			channelMap[channelData.ID] = *channelData
 		}
 
-		upsertChannelWorker := 5
-		if os.Getenv("UPDATE_CHANNEL_WORKER") != "" {
-			upsertChannelWorkerEnv, err := strconv.Atoi(os.Getenv("UPDATE_CHANNEL_WORKER"))
-			if err == nil {
-				upsertChannelWorker = upsertChannelWorkerEnv
-			}
-		}
+		upsertChannelWorker := s.workers
 
 		outputChan := make(chan model.ChannelOutput, len(inputs))
 		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
+
 		for _, inputData := range inputs {
 			err := workerSemaphore.Acquire(ctx, 1)
 			if err != nil {
@@ -276,12 +111,51 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 				continue
 			}
 
+			done := lifecycle.Track()
 			go func(inputDataInWorker model.ChannelInput) {
+				defer done()
 				defer workerSemaphore.Release(1)
 				if channelData, exist := channelMap[inputDataInWorker.ID]; exist {
+					if err := channelData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  err.Error(),
+							Conflict: true,
+							Version:  channelData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelBefore := channelData
 					channelData.Update(inputDataInWorker)
-					err := channelRepository.Update(&channelData)
+					err := updateChannel(ctx, channelRepository, &channelData, inputDataInWorker.Version)
 					if err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  stacktrace.RootCause(err).Error(),
+							Conflict: stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionUpdate, &channelBefore, &channelData); err != nil {
 						output := model.ChannelOutput{
 							ID:      channelData.ID,
 							Code:    channelData.Code,
@@ -291,10 +165,24 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 						outputChan <- output
 						return
 					}
-					go s.cache.Channel().Set(&channelData)
+
+					writtenChan <- &channelData
 				} else {
-					channelData := model.NewChannel(inputDataInWorker)
-					err := channelRepository.Create(channelData)
+					// a version only matches a channel that exists
+					if inputDataInWorker.Version != nil {
+						output := model.ChannelOutput{
+							ID:       inputDataInWorker.ID,
+							Code:     inputDataInWorker.Code,
+							Message:  model.ErrVersionConflict.Error(),
+							Conflict: true,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelData := model.NewChannel(inputDataInWorker, activity.IsSeed(ctx))
+					err := channelRepository.Create(ctx, channelData)
 					if err != nil {
 						output := model.ChannelOutput{
 							ID:      channelData.ID,
@@ -305,7 +193,30 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 						outputChan <- output
 						return
 					}
-					go s.cache.Channel().Set(channelData)
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionCreate, nil, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- channelData
 				}
 			}(inputData)
 		}
@@ -320,14 +231,14 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 		}
 
 		if len(outputs) > 0 {
-			return outputs, errors.New("internal server error")
+			return outputs, channelUpsertError(outputs)
 		}
 
 		return nil, nil
 	}
 
 	var out interface{}
-	out, err = s.main.DoInTransaction(t)
+	out, err = s.main.DoInTransaction(ctx, t)
 	if err != nil {
 		if out != nil {
 			res := out.([]model.ChannelOutput)
@@ -337,25 +248,56 @@ func (s *service) UpsertWithTransaction(ctx context.Context, inputs []model.Chan
 		return nil, err
 	}
 
+	close(writtenChan)
+	for channelData := range writtenChan {
+		go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
+	}
+
 	return nil, nil
 }
 
-func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
+func (s *channelService) UpsertBatchFetching(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.UpsertBatchFetching")
+	defer span.End()
+
+	if forbidden := forbiddenChannelInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
+	}
+
+	// the written channels are cached once committed, so no reader sees
+	// a row that may still be rolled back
+	writtenChan := make(chan *model.Channel, len(inputs))
 	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
 		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		deleted, err := deletedChannelInputs(ctx, channelRepository, inputs)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find deleted channel error")
+		}
+
+		if len(deleted) > 0 {
+			return deleted, channelUpsertError(deleted)
+		}
 		ids := []uuid.UUID{}
 
 		for _, input := range inputs {
 			ids = append(ids, input.ID)
 		}
 
+		upsertChannelWorker := s.workers
+
+		outputChan := make(chan model.ChannelOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
+
 		channels := []*model.Channel{}
 		if len(ids) > 0 {
 			filter := model.ChannelFilter{
 				IDs: ids,
 			}
 
-			channels, err = channelRepository.FindByFilter(filter, true)
+			channels, err = channelRepository.FindByFilter(ctx, filter, true)
 			if err != nil {
 				return nil, stacktrace.Propagate(err, "find channel by filter error")
 			}
@@ -366,16 +308,6 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 			channelMap[channelData.ID] = *channelData
 		}
 
-		upsertChannelWorker := 5
-		if os.Getenv("UPDATE_CHANNEL_WORKER") != "" {
-			upsertChannelWorkerEnv, err := strconv.Atoi(os.Getenv("UPDATE_CHANNEL_WORKER"))
-			if err == nil {
-				upsertChannelWorker = upsertChannelWorkerEnv
-			}
-		}
-
-		outputChan := make(chan model.ChannelOutput, len(inputs))
-		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
 		for _, inputData := range inputs {
 			err := workerSemaphore.Acquire(ctx, 1)
 			if err != nil {
@@ -383,14 +315,51 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 				continue
 			}
 
+			done := lifecycle.Track()
 			go func(inputDataInWorker model.ChannelInput) {
+				defer done()
 				defer workerSemaphore.Release(1)
-				// wait for concurrent testing
-				time.Sleep(5 * time.Second)
 				if channelData, exist := channelMap[inputDataInWorker.ID]; exist {
+					if err := channelData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  err.Error(),
+							Conflict: true,
+							Version:  channelData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelBefore := channelData
 					channelData.Update(inputDataInWorker)
-					err := channelRepository.Update(&channelData)
+					err := updateChannel(ctx, channelRepository, &channelData, inputDataInWorker.Version)
 					if err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  stacktrace.RootCause(err).Error(),
+							Conflict: stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionUpdate, &channelBefore, &channelData); err != nil {
 						output := model.ChannelOutput{
 							ID:      channelData.ID,
 							Code:    channelData.Code,
@@ -400,10 +369,24 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 						outputChan <- output
 						return
 					}
-					go s.cache.Channel().Set(&channelData)
+
+					writtenChan <- &channelData
 				} else {
-					channelData := model.NewChannel(inputDataInWorker)
-					err := channelRepository.Create(channelData)
+					// a version only matches a channel that exists
+					if inputDataInWorker.Version != nil {
+						output := model.ChannelOutput{
+							ID:       inputDataInWorker.ID,
+							Code:     inputDataInWorker.Code,
+							Message:  model.ErrVersionConflict.Error(),
+							Conflict: true,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelData := model.NewChannel(inputDataInWorker, activity.IsSeed(ctx))
+					err := channelRepository.Create(ctx, channelData)
 					if err != nil {
 						output := model.ChannelOutput{
 							ID:      channelData.ID,
@@ -414,7 +397,30 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 						outputChan <- output
 						return
 					}
-					go s.cache.Channel().Set(channelData)
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionCreate, nil, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- channelData
 				}
 			}(inputData)
 		}
@@ -429,14 +435,14 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 		}
 
 		if len(outputs) > 0 {
-			return outputs, errors.New("internal server error")
+			return outputs, channelUpsertError(outputs)
 		}
 
 		return nil, nil
 	}
 
 	var out interface{}
-	out, err = s.main.DoInTransaction(t)
+	out, err = s.main.DoInTransaction(ctx, t)
 	if err != nil {
 		if out != nil {
 			res := out.([]model.ChannelOutput)
@@ -446,59 +452,802 @@ func (s *service) UpsertWithLock(ctx context.Context, inputs []model.ChannelInpu
 		return nil, err
 	}
 
-	return nil, nil
-}
-
-func (s *service) Delete(filter model.ChannelFilter) error {
-	channelRepository := s.main.Channel()
-
-	if err := channelRepository.Delete(filter); err != nil {
-		return stacktrace.Propagate(err, "delete channel error")
+	close(writtenChan)
+	for channelData := range writtenChan {
+		go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (s *service) FindByID(id uuid.UUID) (*model.Channel, error) {
-	channelDataCache, err := s.cache.Channel().Get(id)
-	if err == nil {
-		return channelDataCache, nil
-	}
+func (s *channelService) UpsertWithTransaction(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.UpsertWithTransaction")
+	defer span.End()
 
-	channelRepository := s.main.Channel()
-	channelData, err := channelRepository.FindByID(id)
-	if err != nil {
-		return nil, stacktrace.Propagate(err, "find channel by id error")
+	if forbidden := forbiddenChannelInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
 	}
 
-	go s.cache.Channel().Set(channelData)
+	// the written channels are cached once committed, so no reader sees
+	// a row that may still be rolled back
+	writtenChan := make(chan *model.Channel, len(inputs))
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
 
-	return channelData, nil
-}
+		deleted, err := deletedChannelInputs(ctx, channelRepository, inputs)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find deleted channel error")
+		}
 
-func (s *service) FindByFilter(filter model.ChannelFilter) ([]*model.Channel, error) {
-	channelRepository := s.main.Channel()
-	results, err := channelRepository.FindByFilter(filter, false)
-	if err != nil {
-		return []*model.Channel{}, stacktrace.Propagate(err, "find channel by filter error")
-	}
+		if len(deleted) > 0 {
+			return deleted, channelUpsertError(deleted)
+		}
+		ids := []uuid.UUID{}
 
-	return results, nil
-}
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+		}
 
-func (s *service) FindPage(filter model.ChannelFilter, page, limit int64) (utils.Pagination, error) {
-	channelRepository := s.main.Channel()
-	paginateEmpty := utils.PaginateEmpty()
+		channels := []*model.Channel{}
+		if len(ids) > 0 {
+			filter := model.ChannelFilter{
+				IDs: ids,
+			}
 
-	data, err := channelRepository.FindPage(filter, utils.GetOffset(page, limit), limit)
-	if err != nil {
-		return paginateEmpty, stacktrace.Propagate(err, "find channel page error")
-	}
+			channels, err = channelRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find channel by filter error")
+			}
+		}
 
-	total, err := channelRepository.FindTotalByFilter(filter)
-	if err != nil {
-		return paginateEmpty, stacktrace.Propagate(err, "find total channel by filter error")
-	}
+		channelMap := make(map[uuid.UUID]model.Channel)
+		for _, channelData := range channels {
+			channelMap[channelData.ID] = *channelData
+		}
 
-	return utils.PaginatePageLimit(data, total, page, limit), nil
+		upsertChannelWorker := s.workers
+
+		outputChan := make(chan model.ChannelOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
+		for _, inputData := range inputs {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.ChannelInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				if channelData, exist := channelMap[inputDataInWorker.ID]; exist {
+					if err := channelData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  err.Error(),
+							Conflict: true,
+							Version:  channelData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelBefore := channelData
+					channelData.Update(inputDataInWorker)
+					err := updateChannel(ctx, channelRepository, &channelData, inputDataInWorker.Version)
+					if err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  stacktrace.RootCause(err).Error(),
+							Conflict: stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionUpdate, &channelBefore, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- &channelData
+				} else {
+					// a version only matches a channel that exists
+					if inputDataInWorker.Version != nil {
+						output := model.ChannelOutput{
+							ID:       inputDataInWorker.ID,
+							Code:     inputDataInWorker.Code,
+							Message:  model.ErrVersionConflict.Error(),
+							Conflict: true,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelData := model.NewChannel(inputDataInWorker, activity.IsSeed(ctx))
+					err := channelRepository.Create(ctx, channelData)
+					if err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionCreate, nil, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- channelData
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertChannelWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, channelUpsertError(outputs)
+		}
+
+		return nil, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.ChannelOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	close(writtenChan)
+	for channelData := range writtenChan {
+		go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
+	}
+
+	return nil, nil
+}
+
+func (s *channelService) UpsertWithLock(ctx context.Context, inputs []model.ChannelInput) (outputs []model.ChannelOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.UpsertWithLock")
+	defer span.End()
+
+	if forbidden := forbiddenChannelInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
+	}
+
+	// the written channels are cached once committed, so no reader sees
+	// a row that may still be rolled back
+	writtenChan := make(chan *model.Channel, len(inputs))
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		deleted, err := deletedChannelInputs(ctx, channelRepository, inputs)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find deleted channel error")
+		}
+
+		if len(deleted) > 0 {
+			return deleted, channelUpsertError(deleted)
+		}
+		ids := []uuid.UUID{}
+
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+		}
+
+		channels := []*model.Channel{}
+		if len(ids) > 0 {
+			filter := model.ChannelFilter{
+				IDs: ids,
+			}
+
+			channels, err = channelRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find channel by filter error")
+			}
+		}
+
+		channelMap := make(map[uuid.UUID]model.Channel)
+		for _, channelData := range channels {
+			channelMap[channelData.ID] = *channelData
+		}
+
+		upsertChannelWorker := s.workers
+
+		outputChan := make(chan model.ChannelOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelWorker))
+		for _, inputData := range inputs {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.ChannelInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				// wait for concurrent testing
+				time.Sleep(5 * time.Second)
+				if channelData, exist := channelMap[inputDataInWorker.ID]; exist {
+					if err := channelData.CheckVersion(inputDataInWorker); err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  err.Error(),
+							Conflict: true,
+							Version:  channelData.Version,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelBefore := channelData
+					channelData.Update(inputDataInWorker)
+					err := updateChannel(ctx, channelRepository, &channelData, inputDataInWorker.Version)
+					if err != nil {
+						output := model.ChannelOutput{
+							ID:       channelData.ID,
+							Code:     channelData.Code,
+							Message:  stacktrace.RootCause(err).Error(),
+							Conflict: stacktrace.RootCause(err) == model.ErrVersionConflict,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionUpdate, &channelBefore, &channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- &channelData
+				} else {
+					// a version only matches a channel that exists
+					if inputDataInWorker.Version != nil {
+						output := model.ChannelOutput{
+							ID:       inputDataInWorker.ID,
+							Code:     inputDataInWorker.Code,
+							Message:  model.ErrVersionConflict.Error(),
+							Conflict: true,
+						}
+
+						outputChan <- output
+						return
+					}
+
+					channelData := model.NewChannel(inputDataInWorker, activity.IsSeed(ctx))
+					err := channelRepository.Create(ctx, channelData)
+					if err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelUpserted, channelData.ID, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionCreate, nil, channelData); err != nil {
+						output := model.ChannelOutput{
+							ID:      channelData.ID,
+							Code:    channelData.Code,
+							Message: stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					writtenChan <- channelData
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertChannelWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, channelUpsertError(outputs)
+		}
+
+		return nil, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.ChannelOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	close(writtenChan)
+	for channelData := range writtenChan {
+		go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
+	}
+
+	return nil, nil
+}
+
+func (s *channelService) Delete(ctx context.Context, filter model.ChannelFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.Delete")
+	defer span.End()
+
+	return s.delete(ctx, filter, nil)
+}
+
+// DeleteIfVersion deletes the channel only while it is still at version, the
+// row is locked so no update slips in between the check and the delete.
+func (s *channelService) DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.DeleteIfVersion")
+	defer span.End()
+
+	filter := model.ChannelFilter{
+		IDs: []uuid.UUID{id},
+	}
+
+	return s.delete(ctx, filter, &version)
+}
+
+func (s *channelService) delete(ctx context.Context, filter model.ChannelFilter, version *int) error {
+	if !channelsPermitted(ctx, filter.IDs) {
+		return model.ErrChannelForbidden
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		// only the live rows matching the filter are deleted, the events
+		// and audit rows are written for those alone
+		channels, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: filter.IDs, Codes: filter.Codes}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find channel by filter error")
+		}
+
+		if version != nil {
+			if len(channels) == 0 {
+				return nil, stacktrace.Propagate(sql.ErrNoRows, "channel not found")
+			}
+
+			for _, channelData := range channels {
+				if channelData.Version != *version {
+					return nil, model.ErrVersionConflict
+				}
+			}
+		}
+
+		if len(channels) == 0 {
+			return []uuid.UUID{}, nil
+		}
+
+		ids := []uuid.UUID{}
+		for _, channelData := range channels {
+			ids = append(ids, channelData.ID)
+		}
+
+		if err := channelRepository.Delete(ctx, model.ChannelFilter{IDs: ids}); err != nil {
+			return nil, stacktrace.Propagate(err, "delete channel error")
+		}
+
+		for _, id := range ids {
+			if err := writeOutbox(ctx, outboxRepository, model.EventChannelDeleted, id, map[string]uuid.UUID{"id": id}); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+		}
+
+		deleted, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: ids, IncludeDeleted: true}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find channel by filter error")
+		}
+
+		deletedMap := make(map[uuid.UUID]*model.Channel)
+		for _, channelData := range deleted {
+			deletedMap[channelData.ID] = channelData
+		}
+
+		for _, channelData := range channels {
+			if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionDelete, channelData, deletedMap[channelData.ID]); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return ids, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	// the rows deleted by code are evicted too, a cached row would keep
+	// serving the version and ETag from before the delete
+	for _, id := range out.([]uuid.UUID) {
+		go lifecycle.Background(s.cache.Channel().Delete)(ctx, id)
+	}
+
+	return nil
+}
+
+// Restore brings soft deleted channels back, ids that are not deleted are
+// left as they are.
+func (s *channelService) Restore(ctx context.Context, filter model.ChannelFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.Restore")
+	defer span.End()
+
+	if !channelsPermitted(ctx, filter.IDs) {
+		return model.ErrChannelForbidden
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelRepository := repoRegistry.Channel()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		found, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: filter.IDs, IncludeDeleted: true}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find channel by filter error")
+		}
+
+		deletedMap := make(map[uuid.UUID]*model.Channel)
+		ids := []uuid.UUID{}
+		for _, channelData := range found {
+			if channelData.DeletedAt != nil {
+				deletedMap[channelData.ID] = channelData
+				ids = append(ids, channelData.ID)
+			}
+		}
+
+		if len(ids) == 0 {
+			return nil, stacktrace.Propagate(sql.ErrNoRows, "channel not found")
+		}
+
+		if err := channelRepository.Restore(ctx, model.ChannelFilter{IDs: ids}); err != nil {
+			return nil, stacktrace.Propagate(err, "restore channel error")
+		}
+
+		channels, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: ids}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find channel by filter error")
+		}
+
+		for _, channelData := range channels {
+			if err := writeOutbox(ctx, outboxRepository, model.EventChannelRestored, channelData.ID, channelData); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+
+			if err := writeAudit(ctx, auditRepository, model.EntityChannel, channelData.ID, model.AuditActionRestore, deletedMap[channelData.ID], channelData); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return channels, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	for _, channelData := range out.([]*model.Channel) {
+		go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
+	}
+
+	return nil
+}
+
+// Purge hard deletes the channels soft deleted before the given time.
+func (s *channelService) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.Purge")
+	defer span.End()
+
+	total, err := s.main.Channel().Purge(ctx, before)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "purge channel error")
+	}
+
+	return total, nil
+}
+
+func (s *channelService) FindByID(ctx context.Context, id uuid.UUID) (*model.Channel, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.FindByID")
+	defer span.End()
+
+	if !channelPermitted(ctx, id) {
+		return nil, model.ErrChannelForbidden
+	}
+
+	channelDataCache, err := s.cache.Channel().Get(ctx, id)
+	if err == nil {
+		return channelDataCache, nil
+	}
+
+	channelRepository := s.main.Channel()
+	channelData, err := channelRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel by id error")
+	}
+
+	go lifecycle.Background(s.cache.Channel().Set)(ctx, channelData)
+
+	return channelData, nil
+}
+
+// FindByFilter only finds the channels the caller is permitted.
+func (s *channelService) FindByFilter(ctx context.Context, filter model.ChannelFilter) ([]*model.Channel, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.FindByFilter")
+	defer span.End()
+
+	filter, ok := permitChannelFilter(ctx, filter)
+	if !ok {
+		return []*model.Channel{}, nil
+	}
+
+	channelRepository := s.main.Channel()
+	results, err := channelRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.Channel{}, stacktrace.Propagate(err, "find channel by filter error")
+	}
+
+	return results, nil
+}
+
+// FindPage only pages through the channels the caller is permitted.
+func (s *channelService) FindPage(ctx context.Context, filter model.ChannelFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.FindPage")
+	defer span.End()
+
+	filter, ok := permitChannelFilter(ctx, filter)
+	if !ok {
+		return utils.PaginatePageLimit([]*model.Channel{}, 0, page, limit), nil
+	}
+
+	channelRepository := s.main.Channel()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := channelRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find channel page error")
+	}
+
+	total, err := channelRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total channel by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
+
+// History pages through the audit log of one channel, newest change first.
+func (s *channelService) History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.History")
+	defer span.End()
+
+	if !channelPermitted(ctx, id) {
+		return utils.PaginateEmpty(), model.ErrChannelForbidden
+	}
+
+	auditRepository := s.main.Audit()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := auditRepository.FindPageByEntity(ctx, model.EntityChannel, id, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find channel history error")
+	}
+
+	total, err := auditRepository.FindTotalByEntity(ctx, model.EntityChannel, id)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total channel history error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
+
+// WarmCache loads every channel into the cache, batchSize rows at a time.
+func (s *channelService) WarmCache(ctx context.Context, batchSize int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.Channel.WarmCache")
+	defer span.End()
+
+	channelRepository := s.main.Channel()
+	total := 0
+	for offset := int64(0); ; offset += batchSize {
+		channels, err := channelRepository.FindPage(ctx, model.ChannelFilter{}, offset, batchSize)
+		if err != nil {
+			return total, stacktrace.Propagate(err, "find channel page error")
+		}
+
+		for _, channelData := range channels {
+			if err := s.cache.Channel().Set(ctx, channelData); err != nil {
+				return total, stacktrace.Propagate(err, "set channel cache error")
+			}
+		}
+
+		total += len(channels)
+		if int64(len(channels)) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// updateChannel applies the update only while the row is still at the
+// version the client read, when it sent one.
+func updateChannel(ctx context.Context, channelRepository port.ChannelMainRepository, channelData *model.Channel, version *int) error {
+	if version != nil {
+		return channelRepository.UpdateIfVersion(ctx, channelData, *version)
+	}
+
+	return channelRepository.Update(ctx, channelData)
+}
+
+// deletedChannelInputs answers the inputs reusing the id or the code of a
+// soft deleted channel, both stay taken until the channel is restored or purged.
+func deletedChannelInputs(ctx context.Context, channelRepository port.ChannelMainRepository, inputs []model.ChannelInput) ([]model.ChannelOutput, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	ids := []uuid.UUID{}
+	codes := []string{}
+	for _, input := range inputs {
+		ids = append(ids, input.ID)
+		codes = append(codes, input.Code)
+	}
+
+	// the filter matches ids and codes together, so each is looked up alone
+	byID, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{IDs: ids, IncludeDeleted: true}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel by filter error")
+	}
+
+	byCode, err := channelRepository.FindByFilter(ctx, model.ChannelFilter{Codes: codes, IncludeDeleted: true}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel by filter error")
+	}
+
+	deletedIDs := make(map[uuid.UUID]bool)
+	for _, channelData := range byID {
+		if channelData.DeletedAt != nil {
+			deletedIDs[channelData.ID] = true
+		}
+	}
+
+	deletedCodes := make(map[string]uuid.UUID)
+	for _, channelData := range byCode {
+		if channelData.DeletedAt != nil {
+			deletedCodes[channelData.Code] = channelData.ID
+		}
+	}
+
+	outputs := []model.ChannelOutput{}
+	for _, input := range inputs {
+		message := ""
+		if deletedIDs[input.ID] {
+			message = model.ErrChannelDeleted.Error()
+		} else if id, exist := deletedCodes[input.Code]; exist && id != input.ID {
+			message = model.ErrChannelCodeDeleted.Error()
+		}
+
+		if message != "" {
+			outputs = append(outputs, model.ChannelOutput{
+				ID:       input.ID,
+				Code:     input.Code,
+				Message:  message,
+				Conflict: true,
+			})
+		}
+	}
+
+	return outputs, nil
+}
+
+// channelUpsertError reports a version conflict when every failed item is one,
+// any other failure stays an internal error.
+func channelUpsertError(outputs []model.ChannelOutput) error {
+	for _, output := range outputs {
+		if !output.Conflict {
+			return errors.New("internal server error")
+		}
+	}
+
+	return model.ErrVersionConflict
 }
@@ -0,0 +1,387 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sync/semaphore"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/service/saleschannel/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+	"go-poc/utils/tracing"
+)
+
+type ChannelProduct interface {
+	Upsert(ctx context.Context, inputs []model.ChannelProductInput) (outputs []model.ChannelProductOutput, err error)
+	Delete(ctx context.Context, filter model.ChannelProductFilter) error
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.ChannelProduct, error)
+	FindByFilter(ctx context.Context, filter model.ChannelProductFilter) ([]*model.ChannelProduct, error)
+	FindPage(ctx context.Context, filter model.ChannelProductFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type channelProductKey struct {
+	channelID uuid.UUID
+	sku       string
+}
+
+type channelProductService struct {
+	main    port.MainRepository
+	cache   port.CacheRepository
+	workers int
+}
+
+func NewChannelProduct(
+	main port.MainRepository,
+	cache port.CacheRepository,
+	cfg config.SalesChannel,
+) ChannelProduct {
+	return withChannelProductMetrics(&channelProductService{
+		main:    main,
+		cache:   cache,
+		workers: cfg.ChannelProductWorkers,
+	})
+}
+
+func (s *channelProductService) Upsert(ctx context.Context, inputs []model.ChannelProductInput) (outputs []model.ChannelProductOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.ChannelProduct.Upsert")
+	defer span.End()
+
+	if forbidden := forbiddenChannelProductInputs(ctx, inputs); len(forbidden) > 0 {
+		return forbidden, model.ErrChannelForbidden
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelProductRepository := repoRegistry.ChannelProduct()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+		ids := []uuid.UUID{}
+		channelIDs := []uuid.UUID{}
+		skus := []string{}
+
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+			channelIDs = append(channelIDs, input.ChannelID)
+			skus = append(skus, input.SKU)
+		}
+
+		channelProducts := []*model.ChannelProduct{}
+		if len(ids) > 0 {
+			filter := model.ChannelProductFilter{
+				IDs: ids,
+			}
+
+			channelProducts, err = channelProductRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find channel product by filter error")
+			}
+
+			// a channel product is also identified by its channel and sku
+			filter = model.ChannelProductFilter{
+				ChannelIDs: channelIDs,
+				SKUs:       skus,
+			}
+
+			channelProductsByKey, err := channelProductRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find channel product by filter error")
+			}
+
+			channelProducts = append(channelProducts, channelProductsByKey...)
+		}
+
+		channelProductMap := make(map[uuid.UUID]model.ChannelProduct)
+		channelProductKeyMap := make(map[channelProductKey]uuid.UUID)
+		for _, channelProductData := range channelProducts {
+			channelProductMap[channelProductData.ID] = *channelProductData
+			channelProductKeyMap[channelProductKey{channelProductData.ChannelID, channelProductData.SKU}] = channelProductData.ID
+		}
+
+		// an id may point at a row on another channel than the input's
+		forbidden := []model.ChannelProductOutput{}
+		for _, input := range inputs {
+			if channelProductData, exist := channelProductMap[input.ID]; exist && !channelPermitted(ctx, channelProductData.ChannelID) {
+				forbidden = append(forbidden, model.ChannelProductOutput{
+					ID:        channelProductData.ID,
+					ChannelID: channelProductData.ChannelID,
+					SKU:       channelProductData.SKU,
+					Message:   model.ErrChannelForbidden.Error(),
+				})
+			}
+		}
+
+		if len(forbidden) > 0 {
+			return forbidden, model.ErrChannelForbidden
+		}
+
+		for i, input := range inputs {
+			if _, exist := channelProductMap[input.ID]; exist {
+				continue
+			}
+
+			if id, exist := channelProductKeyMap[channelProductKey{input.ChannelID, input.SKU}]; exist {
+				inputs[i].ID = id
+			}
+		}
+
+		upsertChannelProductWorker := s.workers
+
+		outputChan := make(chan model.ChannelProductOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertChannelProductWorker))
+		for _, inputData := range inputs {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.ChannelProductInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				if channelProductData, exist := channelProductMap[inputDataInWorker.ID]; exist {
+					channelProductBefore := channelProductData
+					channelProductData.Update(inputDataInWorker)
+					err := channelProductRepository.Update(ctx, &channelProductData)
+					if err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelProductUpserted, channelProductData.ID, &channelProductData); err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannelProduct, channelProductData.ID, model.AuditActionUpdate, &channelProductBefore, &channelProductData); err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.ChannelProduct().Set)(ctx, &channelProductData)
+				} else {
+					channelProductData := model.NewChannelProduct(inputDataInWorker)
+					err := channelProductRepository.Create(ctx, channelProductData)
+					if err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeOutbox(ctx, outboxRepository, model.EventChannelProductUpserted, channelProductData.ID, channelProductData); err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					if err := writeAudit(ctx, auditRepository, model.EntityChannelProduct, channelProductData.ID, model.AuditActionCreate, nil, channelProductData); err != nil {
+						output := model.ChannelProductOutput{
+							ID:        channelProductData.ID,
+							ChannelID: channelProductData.ChannelID,
+							SKU:       channelProductData.SKU,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+					go lifecycle.Background(s.cache.ChannelProduct().Set)(ctx, channelProductData)
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertChannelProductWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, errors.New("internal server error")
+		}
+
+		return nil, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.ChannelProductOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *channelProductService) Delete(ctx context.Context, filter model.ChannelProductFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.ChannelProduct.Delete")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		channelProductRepository := repoRegistry.ChannelProduct()
+		outboxRepository := repoRegistry.Outbox()
+		auditRepository := repoRegistry.Audit()
+
+		channelProducts, err := channelProductRepository.FindByFilter(ctx, model.ChannelProductFilter{IDs: filter.IDs}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find channel product by filter error")
+		}
+
+		for _, channelProductData := range channelProducts {
+			if !channelPermitted(ctx, channelProductData.ChannelID) {
+				return nil, model.ErrChannelForbidden
+			}
+		}
+
+		if err := channelProductRepository.Delete(ctx, filter); err != nil {
+			return nil, stacktrace.Propagate(err, "delete channel product error")
+		}
+
+		for _, id := range filter.IDs {
+			if err := writeOutbox(ctx, outboxRepository, model.EventChannelProductDeleted, id, map[string]uuid.UUID{"id": id}); err != nil {
+				return nil, stacktrace.Propagate(err, "write outbox error")
+			}
+		}
+
+		for _, channelProductData := range channelProducts {
+			if err := writeAudit(ctx, auditRepository, model.EntityChannelProduct, channelProductData.ID, model.AuditActionDelete, channelProductData, nil); err != nil {
+				return nil, stacktrace.Propagate(err, "write audit error")
+			}
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	for _, id := range filter.IDs {
+		go lifecycle.Background(s.cache.ChannelProduct().Delete)(ctx, id)
+	}
+
+	return nil
+}
+
+func (s *channelProductService) FindByID(ctx context.Context, id uuid.UUID) (*model.ChannelProduct, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.ChannelProduct.FindByID")
+	defer span.End()
+
+	channelProductDataCache, err := s.cache.ChannelProduct().Get(ctx, id)
+	if err == nil {
+		if !channelPermitted(ctx, channelProductDataCache.ChannelID) {
+			return nil, model.ErrChannelForbidden
+		}
+
+		return channelProductDataCache, nil
+	}
+
+	channelProductRepository := s.main.ChannelProduct()
+	channelProductData, err := channelProductRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find channel product by id error")
+	}
+
+	go lifecycle.Background(s.cache.ChannelProduct().Set)(ctx, channelProductData)
+
+	if !channelPermitted(ctx, channelProductData.ChannelID) {
+		return nil, model.ErrChannelForbidden
+	}
+
+	return channelProductData, nil
+}
+
+// FindByFilter only finds the channel products on channels the caller is
+// permitted.
+func (s *channelProductService) FindByFilter(ctx context.Context, filter model.ChannelProductFilter) ([]*model.ChannelProduct, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.ChannelProduct.FindByFilter")
+	defer span.End()
+
+	channelIDs, ok := permitChannelIDs(ctx, filter.ChannelIDs)
+	if !ok {
+		return []*model.ChannelProduct{}, nil
+	}
+
+	filter.ChannelIDs = channelIDs
+
+	channelProductRepository := s.main.ChannelProduct()
+	results, err := channelProductRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.ChannelProduct{}, stacktrace.Propagate(err, "find channel product by filter error")
+	}
+
+	return results, nil
+}
+
+// FindPage only pages through the channel products on channels the caller
+// is permitted.
+func (s *channelProductService) FindPage(ctx context.Context, filter model.ChannelProductFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "saleschannel.ChannelProduct.FindPage")
+	defer span.End()
+
+	channelIDs, ok := permitChannelIDs(ctx, filter.ChannelIDs)
+	if !ok {
+		return utils.PaginatePageLimit([]*model.ChannelProduct{}, 0, page, limit), nil
+	}
+
+	filter.ChannelIDs = channelIDs
+
+	channelProductRepository := s.main.ChannelProduct()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := channelProductRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find channel product page error")
+	}
+
+	total, err := channelProductRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total channel product by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/utils"
+	"go-poc/utils/metrics"
+)
+
+type allocationRuleMetrics struct {
+	next AllocationRule
+}
+
+// withAllocationRuleMetrics counts the calls of each AllocationRule method by outcome.
+func withAllocationRuleMetrics(next AllocationRule) AllocationRule {
+	return &allocationRuleMetrics{
+		next: next,
+	}
+}
+
+func (m *allocationRuleMetrics) Upsert(ctx context.Context, inputs []model.AllocationRuleInput) ([]model.AllocationRuleOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "allocation_rule", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *allocationRuleMetrics) Delete(ctx context.Context, filter model.AllocationRuleFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "allocation_rule", "delete", 0, err)
+
+	return err
+}
+
+func (m *allocationRuleMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.AllocationRule, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("saleschannel", "allocation_rule", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *allocationRuleMetrics) FindByFilter(ctx context.Context, filter model.AllocationRuleFilter) ([]*model.AllocationRule, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "allocation_rule", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *allocationRuleMetrics) FindPage(ctx context.Context, filter model.AllocationRuleFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("saleschannel", "allocation_rule", "find_page", 0, err)
+
+	return out, err
+}
+
+type availabilityMetrics struct {
+	next Availability
+}
+
+// withAvailabilityMetrics counts the calls of each Availability method by outcome.
+func withAvailabilityMetrics(next Availability) Availability {
+	return &availabilityMetrics{
+		next: next,
+	}
+}
+
+func (m *availabilityMetrics) FindByChannelID(ctx context.Context, channelID uuid.UUID) ([]*model.Availability, error) {
+	out, err := m.next.FindByChannelID(ctx, channelID)
+	metrics.ObserveUsecase("saleschannel", "availability", "find_by_channel_id", 0, err)
+
+	return out, err
+}
+
+type channelMetrics struct {
+	next Channel
+}
+
+// withChannelMetrics counts the calls of each Channel method by outcome.
+func withChannelMetrics(next Channel) Channel {
+	return &channelMetrics{
+		next: next,
+	}
+}
+
+func (m *channelMetrics) Upsert(ctx context.Context, inputs []model.ChannelInput) ([]model.ChannelOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "channel", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *channelMetrics) UpsertBatchFetching(ctx context.Context, inputs []model.ChannelInput) ([]model.ChannelOutput, error) {
+	outputs, err := m.next.UpsertBatchFetching(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "channel", "upsert_batch_fetching", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *channelMetrics) UpsertWithTransaction(ctx context.Context, inputs []model.ChannelInput) ([]model.ChannelOutput, error) {
+	outputs, err := m.next.UpsertWithTransaction(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "channel", "upsert_with_transaction", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *channelMetrics) UpsertWithLock(ctx context.Context, inputs []model.ChannelInput) ([]model.ChannelOutput, error) {
+	outputs, err := m.next.UpsertWithLock(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "channel", "upsert_with_lock", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *channelMetrics) Delete(ctx context.Context, filter model.ChannelFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "channel", "delete", 0, err)
+
+	return err
+}
+
+func (m *channelMetrics) DeleteIfVersion(ctx context.Context, id uuid.UUID, version int) error {
+	err := m.next.DeleteIfVersion(ctx, id, version)
+	metrics.ObserveUsecase("saleschannel", "channel", "delete_if_version", 0, err)
+
+	return err
+}
+
+func (m *channelMetrics) Restore(ctx context.Context, filter model.ChannelFilter) error {
+	err := m.next.Restore(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "channel", "restore", 0, err)
+
+	return err
+}
+
+func (m *channelMetrics) Purge(ctx context.Context, before time.Time) (int64, error) {
+	out, err := m.next.Purge(ctx, before)
+	metrics.ObserveUsecase("saleschannel", "channel", "purge", 0, err)
+
+	return out, err
+}
+
+func (m *channelMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.Channel, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("saleschannel", "channel", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *channelMetrics) FindByFilter(ctx context.Context, filter model.ChannelFilter) ([]*model.Channel, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "channel", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *channelMetrics) FindPage(ctx context.Context, filter model.ChannelFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("saleschannel", "channel", "find_page", 0, err)
+
+	return out, err
+}
+
+func (m *channelMetrics) History(ctx context.Context, id uuid.UUID, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.History(ctx, id, page, limit)
+	metrics.ObserveUsecase("saleschannel", "channel", "history", 0, err)
+
+	return out, err
+}
+
+func (m *channelMetrics) WarmCache(ctx context.Context, batchSize int64) (int, error) {
+	out, err := m.next.WarmCache(ctx, batchSize)
+	metrics.ObserveUsecase("saleschannel", "channel", "warm_cache", 0, err)
+
+	return out, err
+}
+
+type channelProductMetrics struct {
+	next ChannelProduct
+}
+
+// withChannelProductMetrics counts the calls of each ChannelProduct method by outcome.
+func withChannelProductMetrics(next ChannelProduct) ChannelProduct {
+	return &channelProductMetrics{
+		next: next,
+	}
+}
+
+func (m *channelProductMetrics) Upsert(ctx context.Context, inputs []model.ChannelProductInput) ([]model.ChannelProductOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("saleschannel", "channel_product", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *channelProductMetrics) Delete(ctx context.Context, filter model.ChannelProductFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "channel_product", "delete", 0, err)
+
+	return err
+}
+
+func (m *channelProductMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.ChannelProduct, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("saleschannel", "channel_product", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *channelProductMetrics) FindByFilter(ctx context.Context, filter model.ChannelProductFilter) ([]*model.ChannelProduct, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("saleschannel", "channel_product", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *channelProductMetrics) FindPage(ctx context.Context, filter model.ChannelProductFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("saleschannel", "channel_product", "find_page", 0, err)
+
+	return out, err
+}
+
+type relayMetrics struct {
+	next Relay
+}
+
+// withRelayMetrics counts the calls of each Relay method by outcome.
+func withRelayMetrics(next Relay) Relay {
+	return &relayMetrics{
+		next: next,
+	}
+}
+
+func (m *relayMetrics) Relay(ctx context.Context, limit int64) (int, error) {
+	out, err := m.next.Relay(ctx, limit)
+	metrics.ObserveUsecase("saleschannel", "relay", "relay", 0, err)
+
+	return out, err
+}
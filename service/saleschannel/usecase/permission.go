@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/utils/activity"
+)
+
+// channelPermitted is true when the caller of ctx may touch the channel.
+func channelPermitted(ctx context.Context, id uuid.UUID) bool {
+	permitted, restricted := activity.GetChannelIDs(ctx)
+	if !restricted {
+		return true
+	}
+
+	for _, permittedID := range permitted {
+		if permittedID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func channelsPermitted(ctx context.Context, ids []uuid.UUID) bool {
+	for _, id := range ids {
+		if !channelPermitted(ctx, id) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// permitChannelFilter narrows the filter to the channels of the caller, ok
+// is false when no permitted channel is left to find.
+func permitChannelFilter(ctx context.Context, filter model.ChannelFilter) (model.ChannelFilter, bool) {
+	ids, ok := permitChannelIDs(ctx, filter.IDs)
+	filter.IDs = ids
+	return filter, ok
+}
+
+// permitChannelIDs narrows the channel ids of a filter to the channels of
+// the caller, no ids at all means every permitted channel.
+func permitChannelIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, bool) {
+	permitted, restricted := activity.GetChannelIDs(ctx)
+	if !restricted {
+		return ids, true
+	}
+
+	if len(ids) == 0 {
+		return permitted, len(permitted) > 0
+	}
+
+	narrowed := []uuid.UUID{}
+	for _, id := range ids {
+		if channelPermitted(ctx, id) {
+			narrowed = append(narrowed, id)
+		}
+	}
+
+	return narrowed, len(narrowed) > 0
+}
+
+// forbiddenChannelInputs reports the inputs on channels the caller may not
+// touch, a restricted caller cannot create channels either.
+func forbiddenChannelInputs(ctx context.Context, inputs []model.ChannelInput) []model.ChannelOutput {
+	outputs := []model.ChannelOutput{}
+	for _, input := range inputs {
+		if !channelPermitted(ctx, input.ID) {
+			outputs = append(outputs, model.ChannelOutput{
+				ID:      input.ID,
+				Code:    input.Code,
+				Message: model.ErrChannelForbidden.Error(),
+			})
+		}
+	}
+
+	return outputs
+}
+
+// forbiddenChannelProductInputs reports the inputs on channels the caller may
+// not touch.
+func forbiddenChannelProductInputs(ctx context.Context, inputs []model.ChannelProductInput) []model.ChannelProductOutput {
+	outputs := []model.ChannelProductOutput{}
+	for _, input := range inputs {
+		if !channelPermitted(ctx, input.ChannelID) {
+			outputs = append(outputs, model.ChannelProductOutput{
+				ID:        input.ID,
+				ChannelID: input.ChannelID,
+				SKU:       input.SKU,
+				Message:   model.ErrChannelForbidden.Error(),
+			})
+		}
+	}
+
+	return outputs
+}
+
+// forbiddenAllocationRuleInputs reports the inputs on channels the caller may
+// not touch.
+func forbiddenAllocationRuleInputs(ctx context.Context, inputs []model.AllocationRuleInput) []model.AllocationRuleOutput {
+	outputs := []model.AllocationRuleOutput{}
+	for _, input := range inputs {
+		if !channelPermitted(ctx, input.ChannelID) {
+			outputs = append(outputs, model.AllocationRuleOutput{
+				ID:        input.ID,
+				ChannelID: input.ChannelID,
+				SKU:       input.SKU,
+				Message:   model.ErrChannelForbidden.Error(),
+			})
+		}
+	}
+
+	return outputs
+}
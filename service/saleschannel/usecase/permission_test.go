@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/saleschannel/model"
+	"go-poc/utils/activity"
+)
+
+func TestPermitChannelFilter(t *testing.T) {
+	permitted := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name       string
+		restricted []uuid.UUID
+		unlimited  bool
+		ids        []uuid.UUID
+		wantIDs    []uuid.UUID
+		wantOK     bool
+	}{
+		{name: "unrestricted keeps the ids", unlimited: true, ids: []uuid.UUID{other}, wantIDs: []uuid.UUID{other}, wantOK: true},
+		{name: "unrestricted without ids", unlimited: true, wantOK: true},
+		{name: "no ids finds every permitted channel", restricted: []uuid.UUID{permitted}, wantIDs: []uuid.UUID{permitted}, wantOK: true},
+		{name: "drops the other channels", restricted: []uuid.UUID{permitted}, ids: []uuid.UUID{permitted, other}, wantIDs: []uuid.UUID{permitted}, wantOK: true},
+		{name: "only other channels", restricted: []uuid.UUID{permitted}, ids: []uuid.UUID{other}, wantIDs: []uuid.UUID{}, wantOK: false},
+		{name: "no permitted channel", restricted: []uuid.UUID{}, wantIDs: []uuid.UUID{}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !tt.unlimited {
+				ctx = activity.WithChannelIDs(ctx, tt.restricted)
+			}
+
+			filter, ok := permitChannelFilter(ctx, model.ChannelFilter{IDs: tt.ids})
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if len(filter.IDs) != len(tt.wantIDs) || (len(tt.wantIDs) > 0 && !reflect.DeepEqual(filter.IDs, tt.wantIDs)) {
+				t.Fatalf("ids = %v, want %v", filter.IDs, tt.wantIDs)
+			}
+
+			channelIDs, ok := permitChannelIDs(ctx, tt.ids)
+			if ok != tt.wantOK || len(channelIDs) != len(tt.wantIDs) {
+				t.Fatalf("permitChannelIDs = %v %v, want %v %v", channelIDs, ok, tt.wantIDs, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestChannelsPermitted(t *testing.T) {
+	permitted := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name      string
+		unlimited bool
+		ids       []uuid.UUID
+		want      bool
+	}{
+		{name: "unrestricted", unlimited: true, ids: []uuid.UUID{other}, want: true},
+		{name: "permitted", ids: []uuid.UUID{permitted}, want: true},
+		{name: "no ids", want: true},
+		{name: "one other channel", ids: []uuid.UUID{permitted, other}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !tt.unlimited {
+				ctx = activity.WithChannelIDs(ctx, []uuid.UUID{permitted})
+			}
+
+			if got := channelsPermitted(ctx, tt.ids); got != tt.want {
+				t.Fatalf("channelsPermitted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForbiddenInputs(t *testing.T) {
+	permitted := uuid.New()
+	other := uuid.New()
+	ctx := activity.WithChannelIDs(context.Background(), []uuid.UUID{permitted})
+
+	tests := []struct {
+		name          string
+		channelIDs    []uuid.UUID
+		wantForbidden int
+	}{
+		{name: "permitted channel", channelIDs: []uuid.UUID{permitted}},
+		{name: "other channel", channelIDs: []uuid.UUID{other}, wantForbidden: 1},
+		{name: "mixed channels", channelIDs: []uuid.UUID{permitted, other, other}, wantForbidden: 2},
+		{name: "new channel", channelIDs: []uuid.UUID{uuid.Nil}, wantForbidden: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channels := []model.ChannelInput{}
+			channelProducts := []model.ChannelProductInput{}
+			allocationRules := []model.AllocationRuleInput{}
+			for _, id := range tt.channelIDs {
+				channels = append(channels, model.ChannelInput{ID: id})
+				channelProducts = append(channelProducts, model.ChannelProductInput{ChannelID: id})
+				allocationRules = append(allocationRules, model.AllocationRuleInput{ChannelID: id})
+			}
+
+			if got := forbiddenChannelInputs(ctx, channels); len(got) != tt.wantForbidden {
+				t.Fatalf("forbiddenChannelInputs = %d, want %d", len(got), tt.wantForbidden)
+			}
+
+			if got := forbiddenChannelProductInputs(ctx, channelProducts); len(got) != tt.wantForbidden {
+				t.Fatalf("forbiddenChannelProductInputs = %d, want %d", len(got), tt.wantForbidden)
+			}
+
+			forbidden := forbiddenAllocationRuleInputs(ctx, allocationRules)
+			if len(forbidden) != tt.wantForbidden {
+				t.Fatalf("forbiddenAllocationRuleInputs = %d, want %d", len(forbidden), tt.wantForbidden)
+			}
+
+			for _, output := range forbidden {
+				if output.Message != model.ErrChannelForbidden.Error() {
+					t.Fatalf("message = %s, want %s", output.Message, model.ErrChannelForbidden)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/external"
+	"go-poc/middleware"
+	"go-poc/utils/log"
+)
+
+const (
+	StatusUp          = "up"
+	StatusDown        = "down"
+	StatusReady       = "ready"
+	StatusUnavailable = "unavailable"
+)
+
+type DependencyStatus struct {
+	Service   string  `json:"service"`
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// Error is only logged, the body of an unauthenticated probe must not
+	// carry driver messages.
+	Error string `json:"-"`
+}
+
+type MigrationReadiness struct {
+	external.MigrationStatus
+	Status string `json:"status"`
+	Error  string `json:"-"`
+}
+
+type Readiness struct {
+	Status       string               `json:"status"`
+	Dependencies []DependencyStatus   `json:"dependencies"`
+	Migrations   []MigrationReadiness `json:"migrations"`
+}
+
+type HealthHandler struct {
+	probes    []external.Probe
+	migrators []*external.Migrator
+	timeout   time.Duration
+}
+
+// NewHealth gives each probe and migration status timeout to answer.
+func NewHealth(
+	probes []external.Probe,
+	migrators []*external.Migrator,
+	timeout time.Duration,
+) HealthHandler {
+	return HealthHandler{
+		probes:    probes,
+		migrators: migrators,
+		timeout:   timeout,
+	}
+}
+
+// HandleLive answers as long as the process serves requests.
+func (h *HealthHandler) HandleLive(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": StatusUp,
+	})
+}
+
+// HandleReady answers 503 unless every dependency is up and every schema
+// is clean and not behind the latest version, a newer schema is expected
+// while an older build is still rolling out.
+func (h *HealthHandler) HandleReady(c *gin.Context) {
+	ctx := middleware.Context(c, "readiness")
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	readiness := Readiness{
+		Status:       StatusReady,
+		Dependencies: make([]DependencyStatus, len(h.probes)),
+		Migrations:   make([]MigrationReadiness, len(h.migrators)),
+	}
+
+	var wg sync.WaitGroup
+	for i, probe := range h.probes {
+		wg.Add(1)
+		go func(i int, probe external.Probe) {
+			defer wg.Done()
+			readiness.Dependencies[i] = check(ctx, probe)
+		}(i, probe)
+	}
+
+	for i, migrator := range h.migrators {
+		wg.Add(1)
+		go func(i int, migrator *external.Migrator) {
+			defer wg.Done()
+			readiness.Migrations[i] = checkMigration(ctx, migrator)
+		}(i, migrator)
+	}
+
+	wg.Wait()
+
+	for _, dependency := range readiness.Dependencies {
+		if dependency.Status != StatusUp {
+			readiness.Status = StatusUnavailable
+			log.WithContext(ctx).Warnf("%s %s %s is down: %s", dependency.Service, dependency.Name, dependency.Kind, dependency.Error)
+		}
+	}
+
+	for _, migration := range readiness.Migrations {
+		if migration.Status != StatusUp {
+			readiness.Status = StatusUnavailable
+			log.WithContext(ctx).Warnf("%s migration is not ready: %s", migration.Service, migration.Error)
+		}
+	}
+
+	status := http.StatusOK
+	if readiness.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, readiness)
+}
+
+func check(ctx context.Context, probe external.Probe) DependencyStatus {
+	status := DependencyStatus{
+		Service: probe.Service,
+		Name:    probe.Name,
+		Kind:    probe.Kind,
+		Status:  StatusUp,
+	}
+
+	start := time.Now()
+	err := wait(ctx, func() error {
+		return probe.Check(ctx)
+	})
+	status.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = stacktrace.RootCause(err).Error()
+	}
+
+	return status
+}
+
+func checkMigration(ctx context.Context, migrator *external.Migrator) MigrationReadiness {
+	type result struct {
+		status external.MigrationStatus
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		status, err := migrator.Status()
+		done <- result{status: status, err: err}
+	}()
+
+	var migrationStatus external.MigrationStatus
+	var err error
+	select {
+	case res := <-done:
+		migrationStatus, err = res.status, res.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	readiness := MigrationReadiness{
+		MigrationStatus: migrationStatus,
+		Status:          StatusUp,
+	}
+
+	switch {
+	case err != nil:
+		err = stacktrace.RootCause(err)
+	case migrationStatus.Dirty:
+		err = external.ErrDirtySchema
+	case migrationStatus.Version < migrationStatus.Latest:
+		err = errors.New("schema is behind the latest version")
+	}
+
+	if err != nil {
+		readiness.Status = StatusDown
+		readiness.Error = err.Error()
+	}
+
+	return readiness
+}
+
+// wait runs fn until ctx is done, the clients without a context keep
+// running in the background but no longer hold the probe.
+func wait(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
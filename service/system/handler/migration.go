@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/external"
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type MigrationHandler struct {
+	migrators []*external.Migrator
+}
+
+func NewMigration(
+	migrators ...*external.Migrator,
+) MigrationHandler {
+	return MigrationHandler{
+		migrators: migrators,
+	}
+}
+
+func (h *MigrationHandler) HandleStatus(c *gin.Context) {
+	ctx := middleware.Context(c, "migration_status")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	statuses := []external.MigrationStatus{}
+	for _, migrator := range h.migrators {
+		status, err := migrator.Status()
+		if err != nil {
+			log.WithContext(ctx).Error("error migration status", err)
+			respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+			return
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	respond.Success(c, trxID, http.StatusOK, statuses)
+}
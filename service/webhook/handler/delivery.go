@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type DeliveryHandler struct {
+	usecase usecase.Delivery
+}
+
+func NewDelivery(
+	usecase usecase.Delivery,
+) DeliveryHandler {
+	return DeliveryHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *DeliveryHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "delivery_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.DeliveryFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error delivery pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *DeliveryHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "delivery_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.DeliveryURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "delivery not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error delivery find by id", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *DeliveryHandler) HandleReplay(c *gin.Context) {
+	ctx := middleware.Context(c, "delivery_replay")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.DeliveryFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+	total, err := h.usecase.Replay(ctx, filter)
+	if err != nil {
+		if err == model.ErrEmptyReplayFilter {
+			respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+			return
+		}
+
+		log.WithContext(ctx).Error("error delivery replay", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, gin.H{"replayed": total})
+}
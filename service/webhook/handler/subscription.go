@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/middleware"
+	"go-poc/respond"
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/usecase"
+	"go-poc/utils/activity"
+	"go-poc/utils/log"
+)
+
+type SubscriptionHandler struct {
+	usecase usecase.Subscription
+}
+
+func NewSubscription(
+	usecase usecase.Subscription,
+) SubscriptionHandler {
+	return SubscriptionHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *SubscriptionHandler) HandleUpsert(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_upsert")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	var inputs []model.SubscriptionInput
+	if err := c.BindJSON(&inputs); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	outputs, err := h.usecase.Upsert(ctx, inputs)
+	if err != nil {
+		if len(outputs) > 0 {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "error subscription upsert %v", outputs))
+			respond.Invalid(c, trxID, http.StatusInternalServerError, outputs)
+			return
+		}
+
+		log.WithContext(ctx).Error("error subscription upsert", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusCreated, outputs)
+}
+
+func (h *SubscriptionHandler) HandleRotate(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_rotate")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	input := model.SubscriptionRotateInput{}
+	if err := c.BindJSON(&input); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, input)
+	data, err := h.usecase.Rotate(ctx, input.ID)
+	if err != nil {
+		if stacktrace.RootCause(err) == sql.ErrNoRows {
+			respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "subscription not found")
+			return
+		}
+
+		log.WithContext(ctx).Error("error subscription rotate", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SubscriptionHandler) HandleAllByFilter(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_all_by_filter")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.SubscriptionFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+	items, err := h.usecase.FindByFilter(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error subscription all by filter", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	if len(items) == 0 {
+		respond.Error(c, trxID, http.StatusNotFound, respond.ErrNotFound, "subscription not found")
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, items)
+}
+
+func (h *SubscriptionHandler) HandlePagination(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_pagination")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	page := 1
+	if number, err := strconv.Atoi(c.Query("page")); err == nil {
+		page = number
+	}
+
+	limit := 25
+	if number, err := strconv.Atoi(c.Query("limit")); err == nil {
+		limit = number
+	}
+
+	filter := model.SubscriptionFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindPage(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		log.WithContext(ctx).Error("error subscription pagination", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SubscriptionHandler) HandleFindByID(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_find_by_id")
+	trxID, _ := activity.GetTransactionID(ctx)
+
+	uri := model.SubscriptionURI{}
+	if err := c.BindUri(&uri); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	id, err := uuid.Parse(uri.ID)
+	if err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	data, err := h.usecase.FindByID(ctx, id)
+	if err != nil {
+		log.WithContext(ctx).Error("error subscription find by id", err)
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, data)
+}
+
+func (h *SubscriptionHandler) HandleDelete(c *gin.Context) {
+	ctx := middleware.Context(c, "subscription_delete")
+	trxID, _ := activity.GetTransactionID(ctx)
+	filter := model.SubscriptionFilter{}
+	if err := c.BindJSON(&filter); err != nil {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, err.Error())
+		return
+	}
+
+	ctx = activity.WithPayload(ctx, filter)
+
+	if len(filter.IDs) == 0 {
+		respond.Error(c, trxID, http.StatusBadRequest, respond.ErrBadRequest, "ids empty")
+		return
+	}
+
+	err := h.usecase.Delete(ctx, filter)
+	if err != nil {
+		log.WithContext(ctx).Error("error subscription delete", err)
+		respond.Error(c, trxID, http.StatusInternalServerError, respond.ErrInternal, stacktrace.RootCause(err).Error())
+		return
+	}
+
+	respond.Success(c, trxID, http.StatusOK, nil)
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS webhook_subscriptions;
\ No newline at end of file
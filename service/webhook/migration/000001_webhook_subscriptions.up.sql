@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id CHAR(36) primary key NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    active BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_webhook_subscriptions_event_type ON webhook_subscriptions (event_type);
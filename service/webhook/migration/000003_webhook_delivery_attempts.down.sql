@@ -0,0 +1 @@
+DROP TABLE IF EXISTS webhook_delivery_attempts;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts
+(
+    id CHAR(36) primary key NOT NULL,
+    delivery_id CHAR(36) NOT NULL,
+    status_code INT DEFAULT 0 NOT NULL,
+    error TEXT NOT NULL,
+    duration_ms BIGINT DEFAULT 0 NOT NULL,
+    attempted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE ON UPDATE RESTRICT
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempted_at);
@@ -0,0 +1 @@
+ALTER TABLE webhook_deliveries ADD COLUMN locked_until TIMESTAMP NULL;
\ No newline at end of file
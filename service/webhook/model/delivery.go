@@ -0,0 +1,148 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils/event"
+)
+
+var (
+	ErrEmptyReplayFilter = errors.New("replay filter is required")
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff = 24 * time.Hour
+)
+
+type Delivery struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id" db:"subscription_id"`
+	EventID        uuid.UUID  `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Payload        string     `json:"payload" db:"payload"`
+	Status         string     `json:"status" db:"status"`
+	AttemptCount   int        `json:"attempt_count" db:"attempt_count"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code" db:"last_status_code"`
+	LastError      string     `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	LockedUntil    *time.Time `json:"locked_until" db:"locked_until"`
+}
+
+func NewDelivery(subscriptionID uuid.UUID, e event.Event) (*Delivery, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscriptionID,
+		EventID:        e.ID,
+		EventType:      e.Type,
+		Payload:        string(payload),
+		Status:         DeliveryStatusPending,
+		NextAttemptAt:  time.Now(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}, nil
+}
+
+// Claim leases the delivery to a worker until the given time, no other
+// worker picks it before the lease expires.
+func (m *Delivery) Claim(until time.Time) {
+	m.LockedUntil = &until
+	m.UpdatedAt = time.Now()
+}
+
+func (m *Delivery) Succeed(statusCode int) {
+	m.AttemptCount++
+	m.LockedUntil = nil
+	m.Status = DeliveryStatusDelivered
+	m.LastStatusCode = statusCode
+	m.LastError = ""
+	m.UpdatedAt = time.Now()
+}
+
+// Fail schedules the next attempt with an exponential backoff, a non
+// retriable failure or an exhausted budget marks the delivery failed.
+func (m *Delivery) Fail(statusCode int, message string, retriable bool, maxAttempts int, backoff time.Duration) {
+	m.AttemptCount++
+	m.LockedUntil = nil
+	m.LastStatusCode = statusCode
+	m.LastError = message
+	m.UpdatedAt = time.Now()
+
+	if !retriable || m.AttemptCount >= maxAttempts {
+		m.Status = DeliveryStatusFailed
+		return
+	}
+
+	m.NextAttemptAt = time.Now().Add(Backoff(backoff, m.AttemptCount))
+}
+
+// Backoff is the delay after the given attempt, doubling from backoff and
+// capped at MaxBackoff.
+func Backoff(backoff time.Duration, attempt int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempt && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > MaxBackoff {
+		return MaxBackoff
+	}
+
+	return delay
+}
+
+// Replay queues the delivery again with a fresh retry budget.
+func (m *Delivery) Replay() {
+	m.Status = DeliveryStatusPending
+	m.AttemptCount = 0
+	m.NextAttemptAt = time.Now()
+	m.UpdatedAt = time.Now()
+}
+
+// Sign returns the HMAC-SHA256 of the timestamp and payload keyed with the
+// subscription secret, receivers recompute it to trust the payload.
+func (m *Delivery) Sign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "." + m.Payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type DeliveryFilter struct {
+	IDs             []uuid.UUID `json:"ids"`
+	SubscriptionIDs []uuid.UUID `json:"subscription_ids"`
+	EventIDs        []uuid.UUID `json:"event_ids"`
+	Statuses        []string    `json:"statuses"`
+}
+
+type DeliveryURI struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type DeliveryDetail struct {
+	*Delivery
+	Attempts []*DeliveryAttempt `json:"attempts"`
+}
+
+// IsEmpty reports whether the filter would match every delivery.
+func (f DeliveryFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && len(f.SubscriptionIDs) == 0 && len(f.EventIDs) == 0 && len(f.Statuses) == 0
+}
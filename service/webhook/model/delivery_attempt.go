@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeliveryAttempt struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	DeliveryID  uuid.UUID `json:"delivery_id" db:"delivery_id"`
+	StatusCode  int       `json:"status_code" db:"status_code"`
+	Error       string    `json:"error" db:"error"`
+	DurationMs  int64     `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+func NewDeliveryAttempt(deliveryID uuid.UUID, statusCode int, message string, duration time.Duration) *DeliveryAttempt {
+	return &DeliveryAttempt{
+		ID:          uuid.New(),
+		DeliveryID:  deliveryID,
+		StatusCode:  statusCode,
+		Error:       message,
+		DurationMs:  duration.Milliseconds(),
+		AttemptedAt: time.Now(),
+	}
+}
+
+type DeliveryAttemptFilter struct {
+	IDs         []uuid.UUID `json:"ids"`
+	DeliveryIDs []uuid.UUID `json:"delivery_ids"`
+}
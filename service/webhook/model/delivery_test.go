@@ -0,0 +1,121 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliverySign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		payload   string
+		want      string
+	}{
+		{
+			name:      "payload",
+			secret:    "secret",
+			timestamp: 1700000000,
+			payload:   `{"id":1}`,
+			want:      "sha256=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11",
+		},
+		{
+			name:      "other secret",
+			secret:    "other",
+			timestamp: 1700000000,
+			payload:   `{"id":1}`,
+			want:      "sha256=e0cb77fc6d5b2877ec062213c262d236b5dd5a833d29fdc5a058c5fbfa287b47",
+		},
+		{
+			name:      "other timestamp",
+			secret:    "secret",
+			timestamp: 1700000001,
+			payload:   `{"id":1}`,
+			want:      "sha256=d0c79a345e51a61362e0123dd2fc00ec01a78397760f2babc7a052bbbf46c313",
+		},
+		{
+			name:      "empty payload",
+			secret:    "secret",
+			timestamp: 0,
+			want:      "sha256=3445798a051818ef95def46c2eb62b43d377ce6e3c29b4d0aec3da0e59577f79",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := Delivery{Payload: tt.payload}
+			if got := delivery.Sign(tt.secret, tt.timestamp); got != tt.want {
+				t.Fatalf("Sign = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", backoff: time.Second, attempt: 1, want: time.Second},
+		{name: "no attempt yet", backoff: time.Second, attempt: 0, want: time.Second},
+		{name: "second attempt", backoff: time.Second, attempt: 2, want: 2 * time.Second},
+		{name: "fifth attempt", backoff: time.Second, attempt: 5, want: 16 * time.Second},
+		{name: "capped", backoff: time.Hour, attempt: 6, want: MaxBackoff},
+		{name: "base above the cap", backoff: 48 * time.Hour, attempt: 1, want: MaxBackoff},
+		{name: "no overflow", backoff: time.Second, attempt: 1000, want: MaxBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Backoff(tt.backoff, tt.attempt); got != tt.want {
+				t.Fatalf("Backoff(%s, %d) = %s, want %s", tt.backoff, tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryFail(t *testing.T) {
+	tests := []struct {
+		name         string
+		attemptCount int
+		retriable    bool
+		maxAttempts  int
+		wantStatus   string
+	}{
+		{name: "retriable", retriable: true, maxAttempts: 3, wantStatus: DeliveryStatusPending},
+		{name: "not retriable", retriable: false, maxAttempts: 3, wantStatus: DeliveryStatusFailed},
+		{name: "last attempt", attemptCount: 2, retriable: true, maxAttempts: 3, wantStatus: DeliveryStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockedUntil := time.Now().Add(time.Minute)
+			delivery := Delivery{
+				Status:       DeliveryStatusPending,
+				AttemptCount: tt.attemptCount,
+				LockedUntil:  &lockedUntil,
+			}
+
+			before := time.Now()
+			delivery.Fail(500, "server error", tt.retriable, tt.maxAttempts, time.Second)
+			if delivery.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+
+			if delivery.AttemptCount != tt.attemptCount+1 {
+				t.Fatalf("attempt count = %d, want %d", delivery.AttemptCount, tt.attemptCount+1)
+			}
+
+			if delivery.LockedUntil != nil {
+				t.Fatalf("lease kept until %s", delivery.LockedUntil)
+			}
+
+			if tt.wantStatus == DeliveryStatusPending && delivery.NextAttemptAt.Before(before.Add(time.Second)) {
+				t.Fatalf("next attempt at %s, want a backoff of at least 1s", delivery.NextAttemptAt)
+			}
+		})
+	}
+}
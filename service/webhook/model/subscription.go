@@ -0,0 +1,104 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/utils"
+)
+
+const (
+	// SubscriptionWildcard subscribes to every event type.
+	SubscriptionWildcard = "*"
+)
+
+// Subscription posts the events of EventType to URL. The secret signing
+// them is only shown when the subscription is created or rotated.
+type Subscription struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	URL       string    `json:"url" db:"url"`
+	EventType string    `json:"event_type" db:"event_type"`
+	Secret    string    `json:"-" db:"secret"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func NewSubscription(v SubscriptionInput) *Subscription {
+	secret := v.Secret
+	if secret == "" {
+		secret = utils.GenerateSecureToken(32)
+	}
+
+	active := true
+	if v.Active != nil {
+		active = *v.Active
+	}
+
+	return &Subscription{
+		ID:        uuid.New(),
+		URL:       v.URL,
+		EventType: v.EventType,
+		Secret:    secret,
+		Active:    active,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (m *Subscription) Update(v SubscriptionInput) {
+	m.URL = v.URL
+	m.EventType = v.EventType
+	if v.Secret != "" {
+		m.Secret = v.Secret
+	}
+	if v.Active != nil {
+		m.Active = *v.Active
+	}
+	m.UpdatedAt = time.Now()
+}
+
+// Rotate replaces the secret, deliveries are signed with the new one from
+// the next attempt.
+func (m *Subscription) Rotate() (string, error) {
+	secret := utils.GenerateSecureToken(32)
+	if secret == "" {
+		return "", errors.New("generate subscription secret error")
+	}
+
+	m.Secret = secret
+	m.UpdatedAt = time.Now()
+
+	return secret, nil
+}
+
+type SubscriptionInput struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url" binding:"required,url"`
+	EventType string    `json:"event_type" binding:"required"`
+	Secret    string    `json:"secret"`
+	Active    *bool     `json:"active"`
+}
+
+type SubscriptionOutput struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	EventType string    `json:"event_type"`
+	Secret    string    `json:"secret,omitempty"`
+	Message   string    `json:"message"`
+}
+
+type SubscriptionRotateInput struct {
+	ID uuid.UUID `json:"id" binding:"required"`
+}
+
+type SubscriptionFilter struct {
+	IDs        []uuid.UUID `json:"ids"`
+	EventTypes []string    `json:"event_types"`
+}
+
+type SubscriptionURI struct {
+	ID string `uri:"id" binding:"required"`
+}
@@ -0,0 +1,342 @@
+package delivery
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+)
+
+type postgresRepository struct {
+	db utils.DBExecutor
+}
+
+func NewPostgresRepository(db utils.DBExecutor) port.DeliveryMainRepository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+func (repo *postgresRepository) Create(ctx context.Context, data *model.Delivery) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Insert("webhook_deliveries").Rows(
+		goqu.Record{
+			"id":               data.ID,
+			"subscription_id":  data.SubscriptionID,
+			"event_id":         data.EventID,
+			"event_type":       data.EventType,
+			"payload":          data.Payload,
+			"status":           data.Status,
+			"attempt_count":    data.AttemptCount,
+			"next_attempt_at":  data.NextAttemptAt,
+			"last_status_code": data.LastStatusCode,
+			"last_error":       data.LastError,
+			"created_at":       data.CreatedAt,
+			"updated_at":       data.UpdatedAt,
+			"locked_until":     data.LockedUntil,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) Update(ctx context.Context, data *model.Delivery) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.Update("webhook_deliveries").Set(
+		goqu.Record{
+			"subscription_id":  data.SubscriptionID,
+			"event_id":         data.EventID,
+			"event_type":       data.EventType,
+			"payload":          data.Payload,
+			"status":           data.Status,
+			"attempt_count":    data.AttemptCount,
+			"next_attempt_at":  data.NextAttemptAt,
+			"last_status_code": data.LastStatusCode,
+			"last_error":       data.LastError,
+			"updated_at":       data.UpdatedAt,
+			"locked_until":     data.LockedUntil,
+		},
+	)
+	dataset = dataset.Where(goqu.Ex{"id": data.ID})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *postgresRepository) FindByID(ctx context.Context, id uuid.UUID) (result *model.Delivery, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("webhook_deliveries")
+	dataset = dataset.Where(goqu.Ex{"id": id})
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	row := repo.db.QueryRowContext(ctx, query)
+	result = &model.Delivery{}
+	err = row.Scan(
+		&result.ID,
+		&result.SubscriptionID,
+		&result.EventID,
+		&result.EventType,
+		&result.Payload,
+		&result.Status,
+		&result.AttemptCount,
+		&result.NextAttemptAt,
+		&result.LastStatusCode,
+		&result.LastError,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.LockedUntil,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "scan error")
+	}
+
+	return result, nil
+}
+
+func (repo *postgresRepository) FindByFilter(ctx context.Context, filter model.DeliveryFilter, lock bool) (result []*model.Delivery, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("webhook_deliveries")
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	deliveries := []*model.Delivery{}
+	for res.Next() {
+		item := &model.Delivery{}
+		err := res.Scan(
+			&item.ID,
+			&item.SubscriptionID,
+			&item.EventID,
+			&item.EventType,
+			&item.Payload,
+			&item.Status,
+			&item.AttemptCount,
+			&item.NextAttemptAt,
+			&item.LastStatusCode,
+			&item.LastError,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.LockedUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return deliveries, nil
+}
+
+func (repo *postgresRepository) FindPage(ctx context.Context, filter model.DeliveryFilter, offset, limit int64) (result []*model.Delivery, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("webhook_deliveries")
+	dataset = repo.addFilter(dataset, filter).Offset(uint(offset)).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	deliveries := []*model.Delivery{}
+	for res.Next() {
+		item := &model.Delivery{}
+		err := res.Scan(
+			&item.ID,
+			&item.SubscriptionID,
+			&item.EventID,
+			&item.EventType,
+			&item.Payload,
+			&item.Status,
+			&item.AttemptCount,
+			&item.NextAttemptAt,
+			&item.LastStatusCode,
+			&item.LastError,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.LockedUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return deliveries, nil
+}
+
+func (repo *postgresRepository) FindTotalByFilter(ctx context.Context, filter model.DeliveryFilter) (total int64, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("webhook_deliveries")
+	dataset = dataset.Select(goqu.COUNT("*"))
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "query error")
+	}
+
+	err = repo.db.QueryRowContext(ctx, query).Scan(&total)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "scan error")
+	}
+
+	return total, nil
+}
+
+func (repo *postgresRepository) FindDue(ctx context.Context, now time.Time, limit int64) (result []*model.Delivery, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("postgres")
+	dataset := dialect.From("webhook_deliveries")
+	dataset = dataset.Where(
+		goqu.Ex{"status": model.DeliveryStatusPending},
+		goqu.C("next_attempt_at").Lte(now),
+		goqu.Or(
+			goqu.C("locked_until").IsNull(),
+			goqu.C("locked_until").Lte(now),
+		),
+	).Order(goqu.C("next_attempt_at").Asc()).Limit(uint(limit))
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+
+	// concurrent workers skip the rows another worker is claiming
+	query += " FOR UPDATE SKIP LOCKED"
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	deliveries := []*model.Delivery{}
+	for res.Next() {
+		item := &model.Delivery{}
+		err := res.Scan(
+			&item.ID,
+			&item.SubscriptionID,
+			&item.EventID,
+			&item.EventType,
+			&item.Payload,
+			&item.Status,
+			&item.AttemptCount,
+			&item.NextAttemptAt,
+			&item.LastStatusCode,
+			&item.LastError,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.LockedUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return deliveries, nil
+}
+
+func (repo *postgresRepository) addFilter(dataset *goqu.SelectDataset, filter model.DeliveryFilter) *goqu.SelectDataset {
+	if len(filter.IDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	}
+
+	if len(filter.SubscriptionIDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"subscription_id": filter.SubscriptionIDs})
+	}
+
+	if len(filter.EventIDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"event_id": filter.EventIDs})
+	}
+
+	if len(filter.Statuses) != 0 {
+		dataset = dataset.Where(goqu.Ex{"status": filter.Statuses})
+	}
+
+	return dataset
+}
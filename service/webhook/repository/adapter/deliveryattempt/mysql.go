@@ -0,0 +1,111 @@
+package deliveryattempt
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	"github.com/palantir/stacktrace"
+
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+)
+
+type mysqlRepository struct {
+	db utils.DBExecutor
+}
+
+func NewMySQLRepository(db utils.DBExecutor) port.DeliveryAttemptMainRepository {
+	return &mysqlRepository{
+		db: db,
+	}
+}
+
+func (repo *mysqlRepository) Create(ctx context.Context, data *model.DeliveryAttempt) error {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.Insert("webhook_delivery_attempts").Rows(
+		goqu.Record{
+			"id":           data.ID,
+			"delivery_id":  data.DeliveryID,
+			"status_code":  data.StatusCode,
+			"error":        data.Error,
+			"duration_ms":  data.DurationMs,
+			"attempted_at": data.AttemptedAt,
+		},
+	)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return stacktrace.Propagate(err, "dataset error")
+	}
+
+	_, err = repo.db.ExecContext(ctx, query)
+	if err != nil {
+		return stacktrace.Propagate(err, "exec error")
+	}
+
+	return nil
+}
+
+func (repo *mysqlRepository) FindByFilter(ctx context.Context, filter model.DeliveryAttemptFilter, lock bool) (result []*model.DeliveryAttempt, err error) {
+	ctx, cancel := utils.WithQueryTimeout(ctx)
+	defer cancel()
+
+	dialect := goqu.Dialect("mysql")
+	dataset := dialect.From("webhook_delivery_attempts")
+	dataset = repo.addFilter(dataset, filter)
+
+	query, _, err := dataset.ToSQL()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "dataset error")
+	}
+
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	res, err := repo.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "query error")
+	}
+	defer res.Close()
+
+	attempts := []*model.DeliveryAttempt{}
+	for res.Next() {
+		item := &model.DeliveryAttempt{}
+		err := res.Scan(
+			&item.ID,
+			&item.DeliveryID,
+			&item.StatusCode,
+			&item.Error,
+			&item.DurationMs,
+			&item.AttemptedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		attempts = append(attempts, item)
+	}
+
+	if err := res.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "rows error")
+	}
+
+	return attempts, nil
+}
+
+func (repo *mysqlRepository) addFilter(dataset *goqu.SelectDataset, filter model.DeliveryAttemptFilter) *goqu.SelectDataset {
+	if len(filter.IDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"id": filter.IDs})
+	}
+
+	if len(filter.DeliveryIDs) != 0 {
+		dataset = dataset.Where(goqu.Ex{"delivery_id": filter.DeliveryIDs})
+	}
+
+	return dataset
+}
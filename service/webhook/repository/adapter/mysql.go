@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"go-poc/service/webhook/repository/adapter/delivery"
+	"go-poc/service/webhook/repository/adapter/deliveryattempt"
+	"go-poc/service/webhook/repository/adapter/subscription"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/tracing"
+)
+
+type mysqlRegistry struct {
+	db         *sql.DB
+	dbexecutor utils.DBExecutor
+}
+
+func NewMySQL(db *sql.DB) port.MainRepository {
+	return mysqlRegistry{
+		db: db,
+	}
+}
+
+func (r mysqlRegistry) Subscription() port.SubscriptionMainRepository {
+	if r.dbexecutor != nil {
+		return subscription.NewMySQLRepository(r.dbexecutor)
+	}
+	return subscription.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) Delivery() port.DeliveryMainRepository {
+	if r.dbexecutor != nil {
+		return delivery.NewMySQLRepository(r.dbexecutor)
+	}
+	return delivery.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) DeliveryAttempt() port.DeliveryAttemptMainRepository {
+	if r.dbexecutor != nil {
+		return deliveryattempt.NewMySQLRepository(r.dbexecutor)
+	}
+	return deliveryattempt.NewMySQLRepository(tracing.DB(r.db, tracing.DBMySQL))
+}
+
+func (r mysqlRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
+	var tx *sql.Tx
+	registry := r
+	if r.dbexecutor == nil {
+		tx, err = r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				_ = tx.Rollback()
+				switch x := p.(type) {
+				case string:
+					err = errors.New(x)
+				case error:
+					err = x
+				default:
+					// Fallback err (per specs, error strings should be lowercase w/o punctuation
+					err = errors.New("unknown panic")
+				}
+			} else if err != nil {
+				xerr := tx.Rollback() // err is non-nil; don't change it
+				if xerr != nil {
+					err = errors.Wrap(err, xerr.Error())
+				}
+			} else {
+				err = tx.Commit() // err is nil; if Commit returns error update err
+			}
+		}()
+		registry = mysqlRegistry{
+			db:         r.db,
+			dbexecutor: tracing.DB(tx, tracing.DBMySQL),
+		}
+	}
+	out, err = txFunc(registry)
+	if err != nil {
+		if out != nil {
+			return out, err
+		}
+
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"go-poc/service/webhook/repository/adapter/delivery"
+	"go-poc/service/webhook/repository/adapter/deliveryattempt"
+	"go-poc/service/webhook/repository/adapter/subscription"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/tracing"
+)
+
+type postgresRegistry struct {
+	db         *sql.DB
+	dbexecutor utils.DBExecutor
+}
+
+func NewPostgres(db *sql.DB) port.MainRepository {
+	return postgresRegistry{
+		db: db,
+	}
+}
+
+func (r postgresRegistry) Subscription() port.SubscriptionMainRepository {
+	if r.dbexecutor != nil {
+		return subscription.NewPostgresRepository(r.dbexecutor)
+	}
+	return subscription.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) Delivery() port.DeliveryMainRepository {
+	if r.dbexecutor != nil {
+		return delivery.NewPostgresRepository(r.dbexecutor)
+	}
+	return delivery.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) DeliveryAttempt() port.DeliveryAttemptMainRepository {
+	if r.dbexecutor != nil {
+		return deliveryattempt.NewPostgresRepository(r.dbexecutor)
+	}
+	return deliveryattempt.NewPostgresRepository(tracing.DB(r.db, tracing.DBPostgres))
+}
+
+func (r postgresRegistry) DoInTransaction(ctx context.Context, txFunc port.InTransaction) (out interface{}, err error) {
+	var tx *sql.Tx
+	registry := r
+	if r.dbexecutor == nil {
+		tx, err = r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				_ = tx.Rollback()
+				switch x := p.(type) {
+				case string:
+					err = errors.New(x)
+				case error:
+					err = x
+				default:
+					// Fallback err (per specs, error strings should be lowercase w/o punctuation
+					err = errors.New("unknown panic")
+				}
+			} else if err != nil {
+				xerr := tx.Rollback() // err is non-nil; don't change it
+				if xerr != nil {
+					err = errors.Wrap(err, xerr.Error())
+				}
+			} else {
+				err = tx.Commit() // err is nil; if Commit returns error update err
+			}
+		}()
+		registry = postgresRegistry{
+			db:         r.db,
+			dbexecutor: tracing.DB(tx, tracing.DBPostgres),
+		}
+	}
+	out, err = txFunc(registry)
+	if err != nil {
+		if out != nil {
+			return out, err
+		}
+
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,25 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/webhook/model"
+)
+
+type DeliveryMainRepository interface {
+	Create(ctx context.Context, data *model.Delivery) error
+	Update(ctx context.Context, data *model.Delivery) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Delivery, error)
+	FindByFilter(ctx context.Context, filter model.DeliveryFilter, lock bool) ([]*model.Delivery, error)
+	FindPage(ctx context.Context, filter model.DeliveryFilter, offset, limit int64) ([]*model.Delivery, error)
+	FindTotalByFilter(ctx context.Context, filter model.DeliveryFilter) (int64, error)
+	FindDue(ctx context.Context, now time.Time, limit int64) ([]*model.Delivery, error)
+}
+
+type DeliveryAttemptMainRepository interface {
+	Create(ctx context.Context, data *model.DeliveryAttempt) error
+	FindByFilter(ctx context.Context, filter model.DeliveryAttemptFilter, lock bool) ([]*model.DeliveryAttempt, error)
+}
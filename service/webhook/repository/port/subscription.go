@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"github.com/google/uuid"
+
+	"go-poc/service/webhook/model"
+)
+
+type SubscriptionMainRepository interface {
+	Create(ctx context.Context, data *model.Subscription) error
+	Update(ctx context.Context, data *model.Subscription) error
+	FindByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error)
+	FindByFilter(ctx context.Context, filter model.SubscriptionFilter, lock bool) ([]*model.Subscription, error)
+	FindPage(ctx context.Context, filter model.SubscriptionFilter, offset, limit int64) ([]*model.Subscription, error)
+	FindTotalByFilter(ctx context.Context, filter model.SubscriptionFilter) (int64, error)
+	Delete(ctx context.Context, filter model.SubscriptionFilter) error
+}
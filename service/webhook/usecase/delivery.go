@@ -0,0 +1,388 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sync/semaphore"
+
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/event"
+	"go-poc/utils/httpclient"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+	"go-poc/utils/tracing"
+)
+
+const (
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+type Delivery interface {
+	Publish(ctx context.Context, events []event.Event) error
+	Deliver(ctx context.Context, limit int64) (int, error)
+	Replay(ctx context.Context, filter model.DeliveryFilter) (int, error)
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.DeliveryDetail, error)
+	FindPage(ctx context.Context, filter model.DeliveryFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type deliveryService struct {
+	main        port.MainRepository
+	doer        httpclient.HttpDoer
+	maxAttempts int
+	backoff     time.Duration
+	lease       time.Duration
+	workers     int
+}
+
+type deliveryKey struct {
+	subscriptionID uuid.UUID
+	eventID        uuid.UUID
+}
+
+func NewDelivery(
+	main port.MainRepository,
+	doer httpclient.HttpDoer,
+	cfg config.Webhook,
+) Delivery {
+	return withDeliveryMetrics(&deliveryService{
+		main:        main,
+		doer:        doer,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		lease:       cfg.Lease,
+		workers:     cfg.DeliveryWorkers,
+	})
+}
+
+// Publish queues a delivery for every active subscription matching each
+// event. The relay publishes at least once, so events already queued for a
+// subscription are skipped.
+func (s *deliveryService) Publish(ctx context.Context, events []event.Event) error {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Delivery.Publish")
+	defer span.End()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	eventTypes := []string{model.SubscriptionWildcard}
+	eventIDs := []uuid.UUID{}
+	for _, eventData := range events {
+		eventTypes = append(eventTypes, eventData.Type)
+		eventIDs = append(eventIDs, eventData.ID)
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		deliveryRepository := repoRegistry.Delivery()
+
+		subscriptions, err := repoRegistry.Subscription().FindByFilter(ctx, model.SubscriptionFilter{EventTypes: eventTypes}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find subscription by filter error")
+		}
+
+		if len(subscriptions) == 0 {
+			return nil, nil
+		}
+
+		deliveries, err := deliveryRepository.FindByFilter(ctx, model.DeliveryFilter{EventIDs: eventIDs}, false)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find delivery by filter error")
+		}
+
+		deliveryMap := make(map[deliveryKey]bool)
+		for _, deliveryData := range deliveries {
+			deliveryMap[deliveryKey{deliveryData.SubscriptionID, deliveryData.EventID}] = true
+		}
+
+		for _, eventData := range events {
+			for _, subscriptionData := range subscriptions {
+				if !subscriptionData.Active {
+					continue
+				}
+
+				if subscriptionData.EventType != model.SubscriptionWildcard && subscriptionData.EventType != eventData.Type {
+					continue
+				}
+
+				if deliveryMap[deliveryKey{subscriptionData.ID, eventData.ID}] {
+					continue
+				}
+
+				deliveryData, err := model.NewDelivery(subscriptionData.ID, eventData)
+				if err != nil {
+					return nil, stacktrace.Propagate(err, "new delivery error")
+				}
+
+				if err := deliveryRepository.Create(ctx, deliveryData); err != nil {
+					return nil, stacktrace.Propagate(err, "create delivery error")
+				}
+			}
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Deliver sends up to limit due deliveries and records an attempt for each.
+// The deliveries are claimed with a lease in a short transaction and sent
+// outside of it, so no row stays locked during the calls. Each attempt is
+// recorded in its own transaction, a worker dying mid batch leaves the rest
+// to be picked again once their lease expires.
+func (s *deliveryService) Deliver(ctx context.Context, limit int64) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Delivery.Deliver")
+	defer span.End()
+
+	deliveries, err := s.claim(ctx, limit)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "claim delivery error")
+	}
+
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	subscriptionIDs := []uuid.UUID{}
+	for _, deliveryData := range deliveries {
+		subscriptionIDs = append(subscriptionIDs, deliveryData.SubscriptionID)
+	}
+
+	subscriptions, err := s.main.Subscription().FindByFilter(ctx, model.SubscriptionFilter{IDs: subscriptionIDs}, false)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "find subscription by filter error")
+	}
+
+	subscriptionMap := make(map[uuid.UUID]*model.Subscription)
+	for _, subscriptionData := range subscriptions {
+		subscriptionMap[subscriptionData.ID] = subscriptionData
+	}
+
+	deliveryWorker := s.workers
+
+	errChan := make(chan error, len(deliveries))
+	workerSemaphore := semaphore.NewWeighted(int64(deliveryWorker))
+	for _, deliveryData := range deliveries {
+		err := workerSemaphore.Acquire(ctx, 1)
+		if err != nil {
+			log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+			continue
+		}
+
+		done := lifecycle.Track()
+		go func(deliveryDataInWorker *model.Delivery) {
+			defer done()
+			defer workerSemaphore.Release(1)
+
+			var attemptData *model.DeliveryAttempt
+			subscriptionData, exist := subscriptionMap[deliveryDataInWorker.SubscriptionID]
+			if !exist || !subscriptionData.Active {
+				message := "subscription is not active"
+				deliveryDataInWorker.Fail(0, message, false, s.maxAttempts, s.backoff)
+				attemptData = model.NewDeliveryAttempt(deliveryDataInWorker.ID, 0, message, 0)
+			} else {
+				attemptData = s.send(ctx, subscriptionData, deliveryDataInWorker)
+			}
+
+			if err := s.record(ctx, deliveryDataInWorker, attemptData); err != nil {
+				errChan <- stacktrace.Propagate(err, "record delivery %s error", deliveryDataInWorker.ID)
+			}
+		}(deliveryData)
+	}
+
+	if err := workerSemaphore.Acquire(ctx, int64(deliveryWorker)); err != nil {
+		return 0, stacktrace.Propagate(err, "acquire worker error")
+	}
+
+	close(errChan)
+	var recordErr error
+	failed := 0
+	for err := range errChan {
+		log.WithContext(ctx).Error(err)
+		recordErr = err
+		failed++
+	}
+
+	if recordErr != nil {
+		return len(deliveries) - failed, stacktrace.Propagate(recordErr, "record %d of %d deliveries error", failed, len(deliveries))
+	}
+
+	return len(deliveries), nil
+}
+
+// claim leases up to limit due deliveries to this worker.
+func (s *deliveryService) claim(ctx context.Context, limit int64) ([]*model.Delivery, error) {
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		deliveryRepository := repoRegistry.Delivery()
+
+		now := time.Now()
+		deliveries, err := deliveryRepository.FindDue(ctx, now, limit)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find due delivery error")
+		}
+
+		for _, deliveryData := range deliveries {
+			deliveryData.Claim(now.Add(s.lease))
+			if err := deliveryRepository.Update(ctx, deliveryData); err != nil {
+				return nil, stacktrace.Propagate(err, "update delivery error")
+			}
+		}
+
+		return deliveries, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.([]*model.Delivery), nil
+}
+
+// record saves the attempt and the outcome it gave the delivery together.
+func (s *deliveryService) record(ctx context.Context, deliveryData *model.Delivery, attemptData *model.DeliveryAttempt) error {
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		if err := repoRegistry.DeliveryAttempt().Create(ctx, attemptData); err != nil {
+			return nil, stacktrace.Propagate(err, "create delivery attempt error")
+		}
+
+		if err := repoRegistry.Delivery().Update(ctx, deliveryData); err != nil {
+			return nil, stacktrace.Propagate(err, "update delivery error")
+		}
+
+		return nil, nil
+	}
+
+	if _, err := s.main.DoInTransaction(ctx, t); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// send posts the payload signed with the subscription secret. Transport
+// failures the doer flags as retriable, 408, 429 and 5xx responses are
+// retried with backoff, any other failure is final.
+func (s *deliveryService) send(ctx context.Context, subscriptionData *model.Subscription, deliveryData *model.Delivery) *model.DeliveryAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriptionData.URL, bytes.NewBufferString(deliveryData.Payload))
+	if err != nil {
+		deliveryData.Fail(0, err.Error(), false, s.maxAttempts, s.backoff)
+		return model.NewDeliveryAttempt(deliveryData.ID, 0, err.Error(), 0)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, deliveryData.ID.String())
+	req.Header.Set(HeaderEvent, deliveryData.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, deliveryData.Sign(subscriptionData.Secret, timestamp))
+
+	startedAt := time.Now()
+	_, statusCode, err := s.doer.Do(ctx, req)
+	duration := time.Since(startedAt)
+
+	switch {
+	case err != nil:
+		deliveryData.Fail(statusCode, err.Error(), httpclient.IsRetriable(err), s.maxAttempts, s.backoff)
+	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices:
+		deliveryData.Succeed(statusCode)
+	default:
+		retriable := statusCode >= http.StatusInternalServerError ||
+			statusCode == http.StatusTooManyRequests ||
+			statusCode == http.StatusRequestTimeout
+		deliveryData.Fail(statusCode, "unexpected status "+strconv.Itoa(statusCode), retriable, s.maxAttempts, s.backoff)
+	}
+
+	return model.NewDeliveryAttempt(deliveryData.ID, statusCode, deliveryData.LastError, duration)
+}
+
+// Replay queues the matching deliveries again, whatever their status.
+func (s *deliveryService) Replay(ctx context.Context, filter model.DeliveryFilter) (int, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Delivery.Replay")
+	defer span.End()
+
+	if filter.IsEmpty() {
+		return 0, model.ErrEmptyReplayFilter
+	}
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		deliveryRepository := repoRegistry.Delivery()
+
+		deliveries, err := deliveryRepository.FindByFilter(ctx, filter, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find delivery by filter error")
+		}
+
+		for _, deliveryData := range deliveries {
+			deliveryData.Replay()
+			if err := deliveryRepository.Update(ctx, deliveryData); err != nil {
+				return nil, stacktrace.Propagate(err, "update delivery error")
+			}
+		}
+
+		return len(deliveries), nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return out.(int), nil
+}
+
+func (s *deliveryService) FindByID(ctx context.Context, id uuid.UUID) (*model.DeliveryDetail, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Delivery.FindByID")
+	defer span.End()
+
+	deliveryData, err := s.main.Delivery().FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find delivery by id error")
+	}
+
+	attempts, err := s.main.DeliveryAttempt().FindByFilter(ctx, model.DeliveryAttemptFilter{DeliveryIDs: []uuid.UUID{id}}, false)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find delivery attempt by filter error")
+	}
+
+	return &model.DeliveryDetail{
+		Delivery: deliveryData,
+		Attempts: attempts,
+	}, nil
+}
+
+func (s *deliveryService) FindPage(ctx context.Context, filter model.DeliveryFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Delivery.FindPage")
+	defer span.End()
+
+	deliveryRepository := s.main.Delivery()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := deliveryRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find delivery page error")
+	}
+
+	total, err := deliveryRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total delivery by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"go-poc/service/webhook/model"
+	"go-poc/utils"
+	"go-poc/utils/event"
+	"go-poc/utils/metrics"
+)
+
+type deliveryMetrics struct {
+	next Delivery
+}
+
+// withDeliveryMetrics counts the calls of each Delivery method by outcome.
+func withDeliveryMetrics(next Delivery) Delivery {
+	return &deliveryMetrics{
+		next: next,
+	}
+}
+
+func (m *deliveryMetrics) Publish(ctx context.Context, events []event.Event) error {
+	err := m.next.Publish(ctx, events)
+	metrics.ObserveUsecase("webhook", "delivery", "publish", 0, err)
+
+	return err
+}
+
+func (m *deliveryMetrics) Deliver(ctx context.Context, limit int64) (int, error) {
+	out, err := m.next.Deliver(ctx, limit)
+	metrics.ObserveUsecase("webhook", "delivery", "deliver", 0, err)
+
+	return out, err
+}
+
+func (m *deliveryMetrics) Replay(ctx context.Context, filter model.DeliveryFilter) (int, error) {
+	out, err := m.next.Replay(ctx, filter)
+	metrics.ObserveUsecase("webhook", "delivery", "replay", 0, err)
+
+	return out, err
+}
+
+func (m *deliveryMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.DeliveryDetail, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("webhook", "delivery", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *deliveryMetrics) FindPage(ctx context.Context, filter model.DeliveryFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("webhook", "delivery", "find_page", 0, err)
+
+	return out, err
+}
+
+type subscriptionMetrics struct {
+	next Subscription
+}
+
+// withSubscriptionMetrics counts the calls of each Subscription method by outcome.
+func withSubscriptionMetrics(next Subscription) Subscription {
+	return &subscriptionMetrics{
+		next: next,
+	}
+}
+
+func (m *subscriptionMetrics) Upsert(ctx context.Context, inputs []model.SubscriptionInput) ([]model.SubscriptionOutput, error) {
+	outputs, err := m.next.Upsert(ctx, inputs)
+	metrics.ObserveUsecase("webhook", "subscription", "upsert", len(outputs), err)
+
+	return outputs, err
+}
+
+func (m *subscriptionMetrics) Rotate(ctx context.Context, id uuid.UUID) (*model.SubscriptionOutput, error) {
+	out, err := m.next.Rotate(ctx, id)
+	metrics.ObserveUsecase("webhook", "subscription", "rotate", 0, err)
+
+	return out, err
+}
+
+func (m *subscriptionMetrics) Delete(ctx context.Context, filter model.SubscriptionFilter) error {
+	err := m.next.Delete(ctx, filter)
+	metrics.ObserveUsecase("webhook", "subscription", "delete", 0, err)
+
+	return err
+}
+
+func (m *subscriptionMetrics) FindByID(ctx context.Context, ID uuid.UUID) (*model.Subscription, error) {
+	out, err := m.next.FindByID(ctx, ID)
+	metrics.ObserveUsecase("webhook", "subscription", "find_by_id", 0, err)
+
+	return out, err
+}
+
+func (m *subscriptionMetrics) FindByFilter(ctx context.Context, filter model.SubscriptionFilter) ([]*model.Subscription, error) {
+	out, err := m.next.FindByFilter(ctx, filter)
+	metrics.ObserveUsecase("webhook", "subscription", "find_by_filter", 0, err)
+
+	return out, err
+}
+
+func (m *subscriptionMetrics) FindPage(ctx context.Context, filter model.SubscriptionFilter, page, limit int64) (utils.Pagination, error) {
+	out, err := m.next.FindPage(ctx, filter, page, limit)
+	metrics.ObserveUsecase("webhook", "subscription", "find_page", 0, err)
+
+	return out, err
+}
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"golang.org/x/sync/semaphore"
+
+	"go-poc/service/webhook/model"
+	"go-poc/service/webhook/repository/port"
+	"go-poc/utils"
+	"go-poc/utils/config"
+	"go-poc/utils/lifecycle"
+	"go-poc/utils/log"
+	"go-poc/utils/tracing"
+)
+
+type Subscription interface {
+	Upsert(ctx context.Context, inputs []model.SubscriptionInput) (outputs []model.SubscriptionOutput, err error)
+	Rotate(ctx context.Context, id uuid.UUID) (*model.SubscriptionOutput, error)
+	Delete(ctx context.Context, filter model.SubscriptionFilter) error
+	FindByID(ctx context.Context, ID uuid.UUID) (*model.Subscription, error)
+	FindByFilter(ctx context.Context, filter model.SubscriptionFilter) ([]*model.Subscription, error)
+	FindPage(ctx context.Context, filter model.SubscriptionFilter, page, limit int64) (utils.Pagination, error)
+}
+
+type subscriptionService struct {
+	main    port.MainRepository
+	workers int
+}
+
+func NewSubscription(
+	main port.MainRepository,
+	cfg config.Webhook,
+) Subscription {
+	return withSubscriptionMetrics(&subscriptionService{
+		main:    main,
+		workers: cfg.SubscriptionWorkers,
+	})
+}
+
+func (s *subscriptionService) Upsert(ctx context.Context, inputs []model.SubscriptionInput) (outputs []model.SubscriptionOutput, err error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.Upsert")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		subscriptionRepository := repoRegistry.Subscription()
+		ids := []uuid.UUID{}
+
+		for _, input := range inputs {
+			ids = append(ids, input.ID)
+		}
+
+		subscriptions := []*model.Subscription{}
+		if len(ids) > 0 {
+			filter := model.SubscriptionFilter{
+				IDs: ids,
+			}
+
+			subscriptions, err = subscriptionRepository.FindByFilter(ctx, filter, true)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "find subscription by filter error")
+			}
+		}
+
+		subscriptionMap := make(map[uuid.UUID]model.Subscription)
+		for _, subscriptionData := range subscriptions {
+			subscriptionMap[subscriptionData.ID] = *subscriptionData
+		}
+
+		upsertSubscriptionWorker := s.workers
+
+		outputChan := make(chan model.SubscriptionOutput, len(inputs))
+		createdChan := make(chan model.SubscriptionOutput, len(inputs))
+		workerSemaphore := semaphore.NewWeighted(int64(upsertSubscriptionWorker))
+		for _, inputData := range inputs {
+			err := workerSemaphore.Acquire(ctx, 1)
+			if err != nil {
+				log.WithContext(ctx).Error(stacktrace.Propagate(err, "acquire worker error"))
+				continue
+			}
+
+			done := lifecycle.Track()
+			go func(inputDataInWorker model.SubscriptionInput) {
+				defer done()
+				defer workerSemaphore.Release(1)
+				if subscriptionData, exist := subscriptionMap[inputDataInWorker.ID]; exist {
+					subscriptionData.Update(inputDataInWorker)
+					err := subscriptionRepository.Update(ctx, &subscriptionData)
+					if err != nil {
+						output := model.SubscriptionOutput{
+							ID:        subscriptionData.ID,
+							URL:       subscriptionData.URL,
+							EventType: subscriptionData.EventType,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+				} else {
+					subscriptionData := model.NewSubscription(inputDataInWorker)
+					err := subscriptionRepository.Create(ctx, subscriptionData)
+					if err != nil {
+						output := model.SubscriptionOutput{
+							ID:        subscriptionData.ID,
+							URL:       subscriptionData.URL,
+							EventType: subscriptionData.EventType,
+							Message:   stacktrace.RootCause(err).Error(),
+						}
+
+						outputChan <- output
+						return
+					}
+
+					createdChan <- model.SubscriptionOutput{
+						ID:        subscriptionData.ID,
+						URL:       subscriptionData.URL,
+						EventType: subscriptionData.EventType,
+						Secret:    subscriptionData.Secret,
+					}
+				}
+			}(inputData)
+		}
+
+		if err := workerSemaphore.Acquire(ctx, int64(upsertSubscriptionWorker)); err != nil {
+			return nil, stacktrace.Propagate(err, "acquire worker error")
+		}
+
+		close(outputChan)
+		for outputData := range outputChan {
+			outputs = append(outputs, outputData)
+		}
+
+		if len(outputs) > 0 {
+			return outputs, errors.New("internal server error")
+		}
+
+		close(createdChan)
+		created := []model.SubscriptionOutput{}
+		for createdData := range createdChan {
+			created = append(created, createdData)
+		}
+
+		return created, nil
+	}
+
+	var out interface{}
+	out, err = s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		if out != nil {
+			res := out.([]model.SubscriptionOutput)
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	return out.([]model.SubscriptionOutput), nil
+}
+
+// Rotate replaces the secret of a subscription, the new one is only shown
+// in the answer.
+func (s *subscriptionService) Rotate(ctx context.Context, id uuid.UUID) (*model.SubscriptionOutput, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.Rotate")
+	defer span.End()
+
+	var t = func(repoRegistry port.MainRepository) (interface{}, error) {
+		subscriptionRepository := repoRegistry.Subscription()
+
+		subscriptions, err := subscriptionRepository.FindByFilter(ctx, model.SubscriptionFilter{IDs: []uuid.UUID{id}}, true)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "find subscription by filter error")
+		}
+
+		if len(subscriptions) == 0 {
+			return nil, stacktrace.Propagate(sql.ErrNoRows, "subscription %s not found", id)
+		}
+
+		subscriptionData := subscriptions[0]
+		secret, err := subscriptionData.Rotate()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "rotate subscription secret error")
+		}
+
+		if err := subscriptionRepository.Update(ctx, subscriptionData); err != nil {
+			return nil, stacktrace.Propagate(err, "update subscription error")
+		}
+
+		return &model.SubscriptionOutput{
+			ID:        subscriptionData.ID,
+			URL:       subscriptionData.URL,
+			EventType: subscriptionData.EventType,
+			Secret:    secret,
+		}, nil
+	}
+
+	out, err := s.main.DoInTransaction(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.(*model.SubscriptionOutput), nil
+}
+
+func (s *subscriptionService) Delete(ctx context.Context, filter model.SubscriptionFilter) error {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.Delete")
+	defer span.End()
+
+	subscriptionRepository := s.main.Subscription()
+	if err := subscriptionRepository.Delete(ctx, filter); err != nil {
+		return stacktrace.Propagate(err, "delete subscription error")
+	}
+
+	return nil
+}
+
+func (s *subscriptionService) FindByID(ctx context.Context, id uuid.UUID) (*model.Subscription, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.FindByID")
+	defer span.End()
+
+	subscriptionRepository := s.main.Subscription()
+	subscriptionData, err := subscriptionRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "find subscription by id error")
+	}
+
+	return subscriptionData, nil
+}
+
+func (s *subscriptionService) FindByFilter(ctx context.Context, filter model.SubscriptionFilter) ([]*model.Subscription, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.FindByFilter")
+	defer span.End()
+
+	subscriptionRepository := s.main.Subscription()
+	results, err := subscriptionRepository.FindByFilter(ctx, filter, false)
+	if err != nil {
+		return []*model.Subscription{}, stacktrace.Propagate(err, "find subscription by filter error")
+	}
+
+	return results, nil
+}
+
+func (s *subscriptionService) FindPage(ctx context.Context, filter model.SubscriptionFilter, page, limit int64) (utils.Pagination, error) {
+	ctx, span := tracing.StartSpan(ctx, "webhook.Subscription.FindPage")
+	defer span.End()
+
+	subscriptionRepository := s.main.Subscription()
+	paginateEmpty := utils.PaginateEmpty()
+
+	data, err := subscriptionRepository.FindPage(ctx, filter, utils.GetOffset(page, limit), limit)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find subscription page error")
+	}
+
+	total, err := subscriptionRepository.FindTotalByFilter(ctx, filter)
+	if err != nil {
+		return paginateEmpty, stacktrace.Propagate(err, "find total subscription by filter error")
+	}
+
+	return utils.PaginatePageLimit(data, total, page, limit), nil
+}
@@ -14,6 +14,8 @@ const (
 	Action
 	ClientID
 	Payload
+	ChannelIDs
+	Seed
 )
 
 func NewContext(action string) context.Context {
@@ -22,6 +24,12 @@ func NewContext(action string) context.Context {
 	return context.WithValue(ctx, Action, action)
 }
 
+// WithTransactionID starts an activity under ctx, the context of a request
+// so it ends when the request does.
+func WithTransactionID(ctx context.Context, trxID string) context.Context {
+	return context.WithValue(ctx, TransactionID, trxID)
+}
+
 func GetTransactionID(ctx context.Context) (string, bool) {
 	trxID, ok := ctx.Value(TransactionID).(string)
 	return trxID, ok
@@ -45,6 +53,29 @@ func GetClientID(ctx context.Context) (string, bool) {
 	return clientID, ok
 }
 
+// WithChannelIDs restricts the activity to the given channels.
+func WithChannelIDs(ctx context.Context, ids []uuid.UUID) context.Context {
+	return context.WithValue(ctx, ChannelIDs, ids)
+}
+
+// GetChannelIDs returns the channels the activity is restricted to, ok is
+// false when it may touch every channel.
+func GetChannelIDs(ctx context.Context) ([]uuid.UUID, bool) {
+	ids, ok := ctx.Value(ChannelIDs).([]uuid.UUID)
+	return ids, ok
+}
+
+// WithSeed marks the activity as loading fixtures, the rows it creates
+// keep the ids given in the fixtures.
+func WithSeed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, Seed, true)
+}
+
+func IsSeed(ctx context.Context) bool {
+	seed, _ := ctx.Value(Seed).(bool)
+	return seed
+}
+
 func WithPayload(ctx context.Context, payload interface{}) context.Context {
 	return context.WithValue(ctx, Payload, payload)
 }
@@ -0,0 +1,37 @@
+package cachekey
+
+import (
+	"fmt"
+)
+
+// Keyspace names the cache entries of one entity of a service. Redis and
+// Memcache are shared by every service, the service and entity keep their
+// entries apart and the version keeps the entries of an older model out of
+// reach: bump it when the cached struct changes, the old entries are never
+// read again and expire or get evicted.
+type Keyspace struct {
+	service string
+	entity  string
+	version int
+}
+
+func New(service, entity string, version int) Keyspace {
+	return Keyspace{
+		service: service,
+		entity:  entity,
+		version: version,
+	}
+}
+
+// Key is {service}:{entity}:v{version}:{id}.
+func (k Keyspace) Key(id fmt.Stringer) string {
+	return fmt.Sprintf("%s:%s:v%d:%s", k.service, k.entity, k.version, id)
+}
+
+func (k Keyspace) Service() string {
+	return k.service
+}
+
+func (k Keyspace) Entity() string {
+	return k.entity
+}
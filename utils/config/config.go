@@ -0,0 +1,371 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	AdapterMySQL    = "mysql"
+	AdapterPostgres = "postgres"
+	AdapterRedis    = "redis"
+	AdapterMemcache = "memcache"
+
+	InventoryInProcess = "inprocess"
+	InventoryHTTP      = "http"
+
+	PublisherLog          = "log"
+	PublisherWebhook      = "webhook"
+	PublisherSubscription = "subscription"
+
+	// MaxWebhookAttempts bounds WEBHOOK_MAX_ATTEMPTS, past a few dozen
+	// attempts the capped backoff only keeps retrying a dead endpoint.
+	MaxWebhookAttempts = 50
+)
+
+// Config is every setting of the process, see Load for where each comes
+// from. The field comments name the env variable.
+type Config struct {
+	App        App
+	HTTPClient HTTPClient
+	// MySQL and Postgres are the connection shared by the services using the
+	// driver, a service overrides any of it under <SERVICE>_DB_*.
+	MySQL    Database // MYSQL_*
+	Postgres Database // POSTGRES_*
+	Redis    Server   // REDIS_*
+	Memcache Server   // MEMCACHE_*
+	Tracing  Tracing
+	Auth     Auth
+	Event    Event
+	Relay    Worker // RELAY_*
+
+	SalesChannel SalesChannel
+	Inventory    Inventory
+	Webhook      Webhook
+	Identity     Identity
+}
+
+type App struct {
+	Mode            string        // APP_MODE
+	Environment     string        // ENVIRONMENT
+	Port            string        // SERVER_PORT
+	QueryTimeout    time.Duration // DB_QUERY_TIMEOUT
+	HealthTimeout   time.Duration // HEALTH_TIMEOUT
+	ShutdownTimeout time.Duration // SHUTDOWN_TIMEOUT
+	// CacheWarmBatchSize is the rows `cache warm` loads at a time.
+	CacheWarmBatchSize int64         // CACHE_WARM_BATCH_SIZE
+	PurgeRetention     time.Duration // PURGE_RETENTION
+}
+
+type HTTPClient struct {
+	Timeout time.Duration // TIMEOUT, in seconds
+}
+
+type Database struct {
+	Host            string        // HOST
+	Port            string        // PORT
+	Username        string        // USERNAME
+	Password        string        // PASSWORD
+	Name            string        // DB
+	MaxOpenConns    int           // MAX_OPEN_CONNS
+	MaxIdleConns    int           // MAX_IDLE_CONNS
+	ConnMaxLifetime time.Duration // CONN_MAX_LIFETIME, 0 keeps them open
+}
+
+type Server struct {
+	Host string // HOST
+	Port string // PORT
+}
+
+func (s Server) Addr() string {
+	return s.Host + ":" + s.Port
+}
+
+type Tracing struct {
+	Exporter  string // TRACING_EXPORTER
+	JaegerURL string // JAEGER_URL
+}
+
+type Auth struct {
+	// Disabled leaves the routes open when no authenticator is configured,
+	// serve refuses to start without one otherwise.
+	Disabled         bool   // AUTH_DISABLED
+	APIKeys          string // AUTH_API_KEYS
+	APIKeysFile      string // AUTH_API_KEYS_FILE
+	JWTSecret        string // AUTH_JWT_SECRET
+	JWTPublicKeyFile string // AUTH_JWT_PUBLIC_KEY_FILE
+	JWTIssuer        string // AUTH_JWT_ISSUER
+	JWTAudience      string // AUTH_JWT_AUDIENCE
+}
+
+type Event struct {
+	Publisher  string // EVENT_PUBLISHER
+	LogFile    string // EVENT_LOG_FILE
+	WebhookURL string // EVENT_WEBHOOK_URL
+}
+
+// Worker is a polling command, it runs every Interval in batches of
+// BatchSize.
+type Worker struct {
+	Interval  time.Duration // INTERVAL
+	BatchSize int64         // BATCH_SIZE
+}
+
+type SalesChannel struct {
+	Main      string   // SALES_CHANNEL_MAIN
+	Cache     string   // SALES_CHANNEL_CACHE
+	Inventory string   // SALES_CHANNEL_INVENTORY
+	Database  Database // SALES_CHANNEL_DB_*
+
+	// the goroutines a batch upsert runs at once
+	ChannelWorkers        int // UPDATE_CHANNEL_WORKER
+	ChannelProductWorkers int // UPDATE_CHANNEL_PRODUCT_WORKER
+	AllocationRuleWorkers int // UPDATE_ALLOCATION_RULE_WORKER
+}
+
+type Inventory struct {
+	Main     string   // INVENTORY_MAIN
+	Cache    string   // INVENTORY_CACHE
+	Database Database // INVENTORY_DB_*
+	// URL and APIKey reach the inventory service over http, for
+	// SALES_CHANNEL_INVENTORY=http.
+	URL    string // INVENTORY_URL
+	APIKey string // INVENTORY_API_KEY
+
+	LocationWorkers int // UPDATE_LOCATION_WORKER
+	SourcingWorkers int // UPDATE_SOURCING_WORKER
+}
+
+type Webhook struct {
+	Main     string   // WEBHOOK_MAIN
+	Database Database // WEBHOOK_DB_*
+	Deliver  Worker   // WEBHOOK_INTERVAL and WEBHOOK_BATCH_SIZE
+
+	MaxAttempts         int           // WEBHOOK_MAX_ATTEMPTS
+	Backoff             time.Duration // WEBHOOK_BACKOFF
+	Lease               time.Duration // WEBHOOK_LEASE
+	DeliveryWorkers     int           // WEBHOOK_DELIVERY_WORKER
+	SubscriptionWorkers int           // UPDATE_SUBSCRIPTION_WORKER
+}
+
+type Identity struct {
+	// Main is empty when the identity service is off.
+	Main     string   // IDENTITY_MAIN
+	Database Database // IDENTITY_DB_*
+
+	AccessTokenTTL  time.Duration // IDENTITY_ACCESS_TOKEN_TTL
+	RefreshTokenTTL time.Duration // IDENTITY_REFRESH_TOKEN_TTL
+	KeyCacheTTL     time.Duration // IDENTITY_KEY_CACHE_TTL
+}
+
+// Default is the configuration before the file and the env are read.
+func Default() Config {
+	mysql := Database{
+		Port:         "3306",
+		MaxOpenConns: 100,
+		MaxIdleConns: 10,
+	}
+	postgres := Database{
+		Port:         "5432",
+		MaxOpenConns: 100,
+		MaxIdleConns: 10,
+	}
+
+	return Config{
+		App: App{
+			Mode:               "debug",
+			Port:               "8000",
+			QueryTimeout:       30 * time.Second,
+			HealthTimeout:      2 * time.Second,
+			ShutdownTimeout:    30 * time.Second,
+			CacheWarmBatchSize: 500,
+			PurgeRetention:     720 * time.Hour,
+		},
+		HTTPClient: HTTPClient{
+			Timeout: 30 * time.Second,
+		},
+		MySQL:    mysql,
+		Postgres: postgres,
+		Redis:    Server{Port: "6379"},
+		Memcache: Server{Port: "11211"},
+		Tracing: Tracing{
+			Exporter: "jaeger",
+		},
+		Event: Event{
+			Publisher: PublisherLog,
+		},
+		Relay: Worker{
+			Interval:  5 * time.Second,
+			BatchSize: 100,
+		},
+		SalesChannel: SalesChannel{
+			Inventory:             InventoryInProcess,
+			ChannelWorkers:        5,
+			ChannelProductWorkers: 5,
+			AllocationRuleWorkers: 5,
+		},
+		Inventory: Inventory{
+			LocationWorkers: 5,
+			SourcingWorkers: 5,
+		},
+		Webhook: Webhook{
+			Deliver: Worker{
+				Interval:  5 * time.Second,
+				BatchSize: 100,
+			},
+			MaxAttempts:         8,
+			Backoff:             30 * time.Second,
+			Lease:               5 * time.Minute,
+			DeliveryWorkers:     5,
+			SubscriptionWorkers: 5,
+		},
+		Identity: Identity{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 720 * time.Hour,
+			KeyCacheTTL:     time.Minute,
+		},
+	}
+}
+
+// Validate lists every invalid setting at once, so a bad deployment fails
+// on start instead of on the first request using it.
+func (c Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return stacktrace.NewError("invalid config: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+func (c Config) problems() []string {
+	v := validator{}
+
+	v.oneOf("APP_MODE", c.App.Mode, "debug", "release", "test")
+	v.nonNegative("DB_QUERY_TIMEOUT", int64(c.App.QueryTimeout))
+	v.positive("HEALTH_TIMEOUT", int64(c.App.HealthTimeout))
+	v.positive("SHUTDOWN_TIMEOUT", int64(c.App.ShutdownTimeout))
+	v.positive("CACHE_WARM_BATCH_SIZE", c.App.CacheWarmBatchSize)
+	v.nonNegative("PURGE_RETENTION", int64(c.App.PurgeRetention))
+	v.positive("TIMEOUT", int64(c.HTTPClient.Timeout))
+	v.oneOf("TRACING_EXPORTER", c.Tracing.Exporter, "jaeger", "stdout", "none")
+	v.worker("RELAY", c.Relay)
+
+	v.oneOf("SALES_CHANNEL_MAIN", c.SalesChannel.Main, AdapterMySQL, AdapterPostgres)
+	v.oneOf("SALES_CHANNEL_CACHE", c.SalesChannel.Cache, AdapterRedis, AdapterMemcache)
+	v.oneOf("SALES_CHANNEL_INVENTORY", c.SalesChannel.Inventory, InventoryInProcess, InventoryHTTP)
+	v.database("SALES_CHANNEL_DB", c.SalesChannel.Main, c.SalesChannel.Database)
+	v.positive("UPDATE_CHANNEL_WORKER", int64(c.SalesChannel.ChannelWorkers))
+	v.positive("UPDATE_CHANNEL_PRODUCT_WORKER", int64(c.SalesChannel.ChannelProductWorkers))
+	v.positive("UPDATE_ALLOCATION_RULE_WORKER", int64(c.SalesChannel.AllocationRuleWorkers))
+	if c.SalesChannel.Inventory == InventoryHTTP {
+		v.required("INVENTORY_URL", c.Inventory.URL)
+	}
+
+	v.oneOf("INVENTORY_MAIN", c.Inventory.Main, AdapterMySQL, AdapterPostgres)
+	v.oneOf("INVENTORY_CACHE", c.Inventory.Cache, AdapterRedis, AdapterMemcache)
+	v.database("INVENTORY_DB", c.Inventory.Main, c.Inventory.Database)
+	v.positive("UPDATE_LOCATION_WORKER", int64(c.Inventory.LocationWorkers))
+	v.positive("UPDATE_SOURCING_WORKER", int64(c.Inventory.SourcingWorkers))
+
+	v.oneOf("WEBHOOK_MAIN", c.Webhook.Main, AdapterMySQL, AdapterPostgres)
+	v.database("WEBHOOK_DB", c.Webhook.Main, c.Webhook.Database)
+	v.worker("WEBHOOK", c.Webhook.Deliver)
+	v.positive("WEBHOOK_MAX_ATTEMPTS", int64(c.Webhook.MaxAttempts))
+	v.atMost("WEBHOOK_MAX_ATTEMPTS", int64(c.Webhook.MaxAttempts), MaxWebhookAttempts)
+	v.nonNegative("WEBHOOK_BACKOFF", int64(c.Webhook.Backoff))
+	v.positive("WEBHOOK_LEASE", int64(c.Webhook.Lease))
+	v.positive("WEBHOOK_DELIVERY_WORKER", int64(c.Webhook.DeliveryWorkers))
+	v.positive("UPDATE_SUBSCRIPTION_WORKER", int64(c.Webhook.SubscriptionWorkers))
+
+	if c.Identity.Main != "" {
+		v.oneOf("IDENTITY_MAIN", c.Identity.Main, AdapterMySQL, AdapterPostgres)
+		v.database("IDENTITY_DB", c.Identity.Main, c.Identity.Database)
+	}
+	v.positive("IDENTITY_ACCESS_TOKEN_TTL", int64(c.Identity.AccessTokenTTL))
+	v.positive("IDENTITY_REFRESH_TOKEN_TTL", int64(c.Identity.RefreshTokenTTL))
+	v.nonNegative("IDENTITY_KEY_CACHE_TTL", int64(c.Identity.KeyCacheTTL))
+
+	v.oneOf("EVENT_PUBLISHER", c.Event.Publisher, PublisherLog, PublisherWebhook, PublisherSubscription)
+	if c.Event.Publisher == PublisherWebhook {
+		v.required("EVENT_WEBHOOK_URL", c.Event.WebhookURL)
+	}
+
+	return v.problems
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(name, value string) {
+	if value == "" {
+		v.add("%s is required", name)
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	for _, option := range allowed {
+		if value == option {
+			return
+		}
+	}
+
+	if value == "" {
+		v.add("%s is required, one of %s", name, strings.Join(allowed, ", "))
+		return
+	}
+
+	v.add("%s %q is unknown, one of %s", name, value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) positive(name string, value int64) {
+	if value <= 0 {
+		v.add("%s must be positive", name)
+	}
+}
+
+func (v *validator) nonNegative(name string, value int64) {
+	if value < 0 {
+		v.add("%s must not be negative", name)
+	}
+}
+
+func (v *validator) atMost(name string, value, max int64) {
+	if value > max {
+		v.add("%s must be at most %d", name, max)
+	}
+}
+
+func (v *validator) worker(prefix string, worker Worker) {
+	v.positive(prefix+"_INTERVAL", int64(worker.Interval))
+	v.positive(prefix+"_BATCH_SIZE", worker.BatchSize)
+}
+
+// database checks the connection of a service, its empty settings were
+// taken from the driver ones.
+func (v *validator) database(prefix, driver string, database Database) {
+	// an unknown driver is reported on its own
+	if driver != AdapterMySQL && driver != AdapterPostgres {
+		return
+	}
+
+	if database.Host == "" {
+		v.add("%s_HOST or %s_HOST is required", prefix, strings.ToUpper(driver))
+	}
+	if database.Name == "" {
+		v.add("%s_DB or %s_DB is required", prefix, strings.ToUpper(driver))
+	}
+	v.positive(prefix+"_MAX_OPEN_CONNS", int64(database.MaxOpenConns))
+	v.nonNegative(prefix+"_MAX_IDLE_CONNS", int64(database.MaxIdleConns))
+	v.nonNegative(prefix+"_CONN_MAX_LIFETIME", int64(database.ConnMaxLifetime))
+	if database.MaxIdleConns > database.MaxOpenConns {
+		v.add("%s_MAX_IDLE_CONNS must not exceed %s_MAX_OPEN_CONNS", prefix, prefix)
+	}
+}
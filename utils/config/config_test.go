@@ -0,0 +1,128 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// validConfig is the default configuration with every service on a
+// reachable database.
+func validConfig() Config {
+	cfg := Default()
+	database := cfg.MySQL
+	database.Host = "localhost"
+	database.Name = "go_poc"
+
+	cfg.SalesChannel.Main = AdapterMySQL
+	cfg.SalesChannel.Cache = AdapterRedis
+	cfg.SalesChannel.Database = database
+	cfg.Inventory.Main = AdapterMySQL
+	cfg.Inventory.Cache = AdapterMemcache
+	cfg.Inventory.Database = database
+	cfg.Webhook.Main = AdapterMySQL
+	cfg.Webhook.Database = database
+
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(cfg *Config)
+		wantProblems []string
+	}{
+		{
+			name:   "valid",
+			change: func(cfg *Config) {},
+		},
+		{
+			name: "identity on its own database",
+			change: func(cfg *Config) {
+				cfg.Identity.Main = AdapterPostgres
+				cfg.Identity.Database = Database{Host: "localhost", Name: "identity", MaxOpenConns: 10}
+			},
+		},
+		{
+			name:         "unknown mode",
+			change:       func(cfg *Config) { cfg.App.Mode = "prod" },
+			wantProblems: []string{`APP_MODE "prod" is unknown, one of debug, release, test`},
+		},
+		{
+			name:         "no main adapter",
+			change:       func(cfg *Config) { cfg.SalesChannel.Main = "" },
+			wantProblems: []string{"SALES_CHANNEL_MAIN is required, one of mysql, postgres"},
+		},
+		{
+			name:         "no database host",
+			change:       func(cfg *Config) { cfg.Inventory.Database.Host = "" },
+			wantProblems: []string{"INVENTORY_DB_HOST or MYSQL_HOST is required"},
+		},
+		{
+			name: "more idle than open connections",
+			change: func(cfg *Config) {
+				cfg.Webhook.Database.MaxOpenConns = 5
+				cfg.Webhook.Database.MaxIdleConns = 10
+			},
+			wantProblems: []string{"WEBHOOK_DB_MAX_IDLE_CONNS must not exceed WEBHOOK_DB_MAX_OPEN_CONNS"},
+		},
+		{
+			name:         "no workers",
+			change:       func(cfg *Config) { cfg.SalesChannel.ChannelWorkers = 0 },
+			wantProblems: []string{"UPDATE_CHANNEL_WORKER must be positive"},
+		},
+		{
+			name:         "negative backoff",
+			change:       func(cfg *Config) { cfg.Webhook.Backoff = -time.Second },
+			wantProblems: []string{"WEBHOOK_BACKOFF must not be negative"},
+		},
+		{
+			name:         "too many webhook attempts",
+			change:       func(cfg *Config) { cfg.Webhook.MaxAttempts = MaxWebhookAttempts + 1 },
+			wantProblems: []string{"WEBHOOK_MAX_ATTEMPTS must be at most 50"},
+		},
+		{
+			name:         "no webhook lease",
+			change:       func(cfg *Config) { cfg.Webhook.Lease = 0 },
+			wantProblems: []string{"WEBHOOK_LEASE must be positive"},
+		},
+		{
+			name:         "inventory over http without url",
+			change:       func(cfg *Config) { cfg.SalesChannel.Inventory = InventoryHTTP },
+			wantProblems: []string{"INVENTORY_URL is required"},
+		},
+		{
+			name:         "webhook publisher without url",
+			change:       func(cfg *Config) { cfg.Event.Publisher = PublisherWebhook },
+			wantProblems: []string{"EVENT_WEBHOOK_URL is required"},
+		},
+		{
+			name: "every problem at once",
+			change: func(cfg *Config) {
+				cfg.Tracing.Exporter = "zipkin"
+				cfg.Relay.Interval = 0
+				cfg.Identity.AccessTokenTTL = 0
+			},
+			wantProblems: []string{
+				`TRACING_EXPORTER "zipkin" is unknown, one of jaeger, stdout, none`,
+				"RELAY_INTERVAL must be positive",
+				"IDENTITY_ACCESS_TOKEN_TTL must be positive",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+
+			if problems := cfg.problems(); !reflect.DeepEqual(problems, tt.wantProblems) {
+				t.Fatalf("problems = %q, want %q", problems, tt.wantProblems)
+			}
+
+			if err := cfg.Validate(); (err != nil) != (len(tt.wantProblems) > 0) {
+				t.Fatalf("Validate error = %v, want problems %q", err, tt.wantProblems)
+			}
+		})
+	}
+}
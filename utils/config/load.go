@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the defaults, then the optional yaml or toml file at path,
+// then the env, a non empty variable winning over the file. The file uses
+// the env names, as flat keys or nested tables joined by an underscore:
+// `mysql: {host: db}` sets MYSQL_HOST. A key that names no setting, or a
+// value that does not parse, fails the load.
+func Load(path string) (Config, error) {
+	values := map[string]string{}
+	if path != "" {
+		var err error
+		values, err = readFile(path)
+		if err != nil {
+			return Config{}, stacktrace.Propagate(err, "read config file %s error", path)
+		}
+	}
+
+	l := &loader{
+		values: values,
+		used:   map[string]bool{},
+	}
+	cfg := Default()
+
+	l.string("APP_MODE", &cfg.App.Mode)
+	l.string("ENVIRONMENT", &cfg.App.Environment)
+	l.string("SERVER_PORT", &cfg.App.Port)
+	l.duration("DB_QUERY_TIMEOUT", &cfg.App.QueryTimeout)
+	l.duration("HEALTH_TIMEOUT", &cfg.App.HealthTimeout)
+	l.duration("SHUTDOWN_TIMEOUT", &cfg.App.ShutdownTimeout)
+	l.int64("CACHE_WARM_BATCH_SIZE", &cfg.App.CacheWarmBatchSize)
+	l.duration("PURGE_RETENTION", &cfg.App.PurgeRetention)
+	l.seconds("TIMEOUT", &cfg.HTTPClient.Timeout)
+
+	l.database("MYSQL", &cfg.MySQL)
+	l.database("POSTGRES", &cfg.Postgres)
+	l.server("REDIS", &cfg.Redis)
+	l.server("MEMCACHE", &cfg.Memcache)
+
+	l.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	l.string("JAEGER_URL", &cfg.Tracing.JaegerURL)
+
+	l.bool("AUTH_DISABLED", &cfg.Auth.Disabled)
+	l.string("AUTH_API_KEYS", &cfg.Auth.APIKeys)
+	l.string("AUTH_API_KEYS_FILE", &cfg.Auth.APIKeysFile)
+	l.string("AUTH_JWT_SECRET", &cfg.Auth.JWTSecret)
+	l.string("AUTH_JWT_PUBLIC_KEY_FILE", &cfg.Auth.JWTPublicKeyFile)
+	l.string("AUTH_JWT_ISSUER", &cfg.Auth.JWTIssuer)
+	l.string("AUTH_JWT_AUDIENCE", &cfg.Auth.JWTAudience)
+
+	l.string("EVENT_PUBLISHER", &cfg.Event.Publisher)
+	l.string("EVENT_LOG_FILE", &cfg.Event.LogFile)
+	l.string("EVENT_WEBHOOK_URL", &cfg.Event.WebhookURL)
+	l.worker("RELAY", &cfg.Relay)
+
+	l.string("SALES_CHANNEL_MAIN", &cfg.SalesChannel.Main)
+	l.string("SALES_CHANNEL_CACHE", &cfg.SalesChannel.Cache)
+	l.string("SALES_CHANNEL_INVENTORY", &cfg.SalesChannel.Inventory)
+	cfg.SalesChannel.Database = cfg.driver(cfg.SalesChannel.Main)
+	l.database("SALES_CHANNEL_DB", &cfg.SalesChannel.Database)
+	l.int("UPDATE_CHANNEL_WORKER", &cfg.SalesChannel.ChannelWorkers)
+	l.int("UPDATE_CHANNEL_PRODUCT_WORKER", &cfg.SalesChannel.ChannelProductWorkers)
+	l.int("UPDATE_ALLOCATION_RULE_WORKER", &cfg.SalesChannel.AllocationRuleWorkers)
+
+	l.string("INVENTORY_MAIN", &cfg.Inventory.Main)
+	l.string("INVENTORY_CACHE", &cfg.Inventory.Cache)
+	cfg.Inventory.Database = cfg.driver(cfg.Inventory.Main)
+	l.database("INVENTORY_DB", &cfg.Inventory.Database)
+	l.string("INVENTORY_URL", &cfg.Inventory.URL)
+	l.string("INVENTORY_API_KEY", &cfg.Inventory.APIKey)
+	l.int("UPDATE_LOCATION_WORKER", &cfg.Inventory.LocationWorkers)
+	l.int("UPDATE_SOURCING_WORKER", &cfg.Inventory.SourcingWorkers)
+
+	l.string("WEBHOOK_MAIN", &cfg.Webhook.Main)
+	cfg.Webhook.Database = cfg.driver(cfg.Webhook.Main)
+	l.database("WEBHOOK_DB", &cfg.Webhook.Database)
+	l.worker("WEBHOOK", &cfg.Webhook.Deliver)
+	l.int("WEBHOOK_MAX_ATTEMPTS", &cfg.Webhook.MaxAttempts)
+	l.duration("WEBHOOK_BACKOFF", &cfg.Webhook.Backoff)
+	l.duration("WEBHOOK_LEASE", &cfg.Webhook.Lease)
+	l.int("WEBHOOK_DELIVERY_WORKER", &cfg.Webhook.DeliveryWorkers)
+	l.int("UPDATE_SUBSCRIPTION_WORKER", &cfg.Webhook.SubscriptionWorkers)
+
+	l.string("IDENTITY_MAIN", &cfg.Identity.Main)
+	cfg.Identity.Database = cfg.driver(cfg.Identity.Main)
+	l.database("IDENTITY_DB", &cfg.Identity.Database)
+	l.duration("IDENTITY_ACCESS_TOKEN_TTL", &cfg.Identity.AccessTokenTTL)
+	l.duration("IDENTITY_REFRESH_TOKEN_TTL", &cfg.Identity.RefreshTokenTTL)
+	l.duration("IDENTITY_KEY_CACHE_TTL", &cfg.Identity.KeyCacheTTL)
+
+	l.unknownKeys()
+	problems := append(l.problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return Config{}, stacktrace.NewError("invalid config: %s", strings.Join(problems, ", "))
+	}
+
+	return cfg, nil
+}
+
+// driver is the shared connection a service using main starts from.
+func (c Config) driver(main string) Database {
+	switch main {
+	case AdapterMySQL:
+		return c.MySQL
+	case AdapterPostgres:
+		return c.Postgres
+	}
+
+	return Database{}
+}
+
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, stacktrace.NewError("unknown config file format %s, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	if err := flatten("", tree, values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// flatten turns the nested tables into env names, a list becomes a comma
+// separated value like AUTH_API_KEYS expects.
+func flatten(prefix string, value interface{}, values map[string]string) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			name := strings.ToUpper(key)
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+
+			if err := flatten(name, child, values); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return stacktrace.NewError("%s must be a list of values", prefix)
+			}
+
+			items = append(items, fmt.Sprint(item))
+		}
+
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(value)
+	}
+
+	return nil
+}
+
+type loader struct {
+	values   map[string]string
+	used     map[string]bool
+	problems []string
+}
+
+// lookup returns the env value of name, or else the file one.
+func (l *loader) lookup(name string) (string, bool) {
+	l.used[name] = true
+	if value := os.Getenv(name); value != "" {
+		return value, true
+	}
+
+	value, exist := l.values[name]
+	return value, exist && value != ""
+}
+
+func (l *loader) invalid(name, value, kind string) {
+	l.problems = append(l.problems, fmt.Sprintf("%s %q is not %s", name, value, kind))
+}
+
+func (l *loader) string(name string, target *string) {
+	if value, exist := l.lookup(name); exist {
+		*target = value
+	}
+}
+
+func (l *loader) bool(name string, target *bool) {
+	if value, exist := l.lookup(name); exist {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			l.invalid(name, value, "a boolean")
+			return
+		}
+
+		*target = parsed
+	}
+}
+
+func (l *loader) int(name string, target *int) {
+	if value, exist := l.lookup(name); exist {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			l.invalid(name, value, "an integer")
+			return
+		}
+
+		*target = parsed
+	}
+}
+
+func (l *loader) int64(name string, target *int64) {
+	if value, exist := l.lookup(name); exist {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			l.invalid(name, value, "an integer")
+			return
+		}
+
+		*target = parsed
+	}
+}
+
+func (l *loader) duration(name string, target *time.Duration) {
+	if value, exist := l.lookup(name); exist {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			l.invalid(name, value, "a duration")
+			return
+		}
+
+		*target = parsed
+	}
+}
+
+// seconds reads a whole number of seconds, or a duration like 1m30s.
+func (l *loader) seconds(name string, target *time.Duration) {
+	if value, exist := l.lookup(name); exist {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			*target = time.Duration(parsed) * time.Second
+			return
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			l.invalid(name, value, "a number of seconds")
+			return
+		}
+
+		*target = parsed
+	}
+}
+
+func (l *loader) database(prefix string, target *Database) {
+	l.string(prefix+"_HOST", &target.Host)
+	l.string(prefix+"_PORT", &target.Port)
+	l.string(prefix+"_USERNAME", &target.Username)
+	l.string(prefix+"_PASSWORD", &target.Password)
+	l.string(prefix+"_DB", &target.Name)
+	l.int(prefix+"_MAX_OPEN_CONNS", &target.MaxOpenConns)
+	l.int(prefix+"_MAX_IDLE_CONNS", &target.MaxIdleConns)
+	l.duration(prefix+"_CONN_MAX_LIFETIME", &target.ConnMaxLifetime)
+}
+
+func (l *loader) server(prefix string, target *Server) {
+	l.string(prefix+"_HOST", &target.Host)
+	l.string(prefix+"_PORT", &target.Port)
+}
+
+func (l *loader) worker(prefix string, target *Worker) {
+	l.duration(prefix+"_INTERVAL", &target.Interval)
+	l.int64(prefix+"_BATCH_SIZE", &target.BatchSize)
+}
+
+// unknownKeys reports the file keys no setting read, a typo would
+// otherwise be ignored silently.
+func (l *loader) unknownKeys() {
+	unknown := []string{}
+	for name := range l.values {
+		if !l.used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		l.problems = append(l.problems, fmt.Sprintf("%s is not a setting", name))
+	}
+}
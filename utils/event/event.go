@@ -0,0 +1,23 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a domain change recorded in a service outbox.
+type Event struct {
+	ID          uuid.UUID       `json:"id"`
+	Service     string          `json:"service"`
+	Type        string          `json:"type"`
+	AggregateID uuid.UUID       `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
@@ -0,0 +1,53 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/palantir/stacktrace"
+
+	"go-poc/utils/log"
+)
+
+type logPublisher struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogPublisher writes every event as a JSON line to path, or to the
+// application log when path is empty.
+func NewLogPublisher(path string) Publisher {
+	return &logPublisher{
+		path: path,
+	}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, events []Event) error {
+	if p.path == "" {
+		for _, e := range events {
+			log.WithContext(ctx).WithField("event", e).Info("event published")
+		}
+
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return stacktrace.Propagate(err, "open event log error")
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return stacktrace.Propagate(err, "write event log error")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/palantir/stacktrace"
+
+	"go-poc/utils/httpclient"
+)
+
+type webhookPublisher struct {
+	doer httpclient.HttpDoer
+	url  string
+}
+
+// NewWebhookPublisher POSTs each batch of events as a JSON array to url,
+// any non 2xx answer fails the batch so it is relayed again.
+func NewWebhookPublisher(doer httpclient.HttpDoer, url string) Publisher {
+	return &webhookPublisher{
+		doer: doer,
+		url:  url,
+	}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return stacktrace.Propagate(err, "marshal error")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewBuffer(body))
+	if err != nil {
+		return stacktrace.Propagate(err, "request error")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, statusCode, err := p.doer.Do(ctx, req)
+	if err != nil {
+		return stacktrace.Propagate(err, "do request error")
+	}
+
+	if statusCode < 200 || statusCode >= 300 {
+		return stacktrace.NewError("webhook responded %d", statusCode)
+	}
+
+	return nil
+}
@@ -3,19 +3,42 @@ package httpclient
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
 	"go-poc/utils/log"
+	"go-poc/utils/tracing"
 )
 
+// RetriableError marks transport failures worth retrying, the cause is kept
+// so callers can still inspect it.
 type RetriableError struct {
 	message string
+	cause   error
+}
+
+func (e *RetriableError) Error() string {
+	return e.message
+}
+
+func (e *RetriableError) Unwrap() error {
+	return e.cause
+}
+
+// IsRetriable reports whether err was returned for a transport failure.
+func IsRetriable(err error) bool {
+	var retriableErr *RetriableError
+	return errors.As(err, &retriableErr)
 }
 
 type HttpRequestPayload struct {
@@ -47,26 +70,16 @@ type proxiedHttpDoer struct {
 	httpDoer
 }
 
-func NewDoer() HttpDoer {
-	timeout, err := strconv.Atoi(os.Getenv("TIMEOUT"))
-	if err != nil {
-		timeout = 30
-	}
-
+func NewDoer(timeout time.Duration) HttpDoer {
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Timeout: timeout,
 	}
 	return newDoer(client)
 }
 
-func NewDoerWithProxy(proxyUrl *url.URL) HttpDoer {
-	timeout, err := strconv.Atoi(os.Getenv("TIMEOUT"))
-	if err != nil {
-		timeout = 30
-	}
-
+func NewDoerWithProxy(proxyUrl *url.URL, timeout time.Duration) HttpDoer {
 	client := &http.Client{
-		Timeout:   time.Duration(timeout) * time.Second,
+		Timeout:   timeout,
 		Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)},
 	}
 	return newProxiedDoer(client)
@@ -83,20 +96,15 @@ func newProxiedDoer(client *http.Client) ProxiedHttpDoer {
 func (d *proxiedHttpDoer) WithProxyAuthHeader(value string) (ProxiedHttpDoer, error) {
 	oldTransport, _ := d.httpDoer.client.Transport.(*http.Transport)
 	// copy the transport object so it doesn't use same header
-	newTransport := *oldTransport
+	newTransport := oldTransport.Clone()
 
 	header := http.Header{}
 	header.Add("Proxy-Authorization", "Basic "+value)
 	newTransport.ProxyConnectHeader = header
 
-	timeout, err := strconv.Atoi(os.Getenv("TIMEOUT"))
-	if err != nil {
-		timeout = 30
-	}
-
 	client := &http.Client{
-		Timeout:   time.Duration(timeout) * time.Second,
-		Transport: &newTransport,
+		Timeout:   d.httpDoer.client.Timeout,
+		Transport: newTransport,
 	}
 
 	return newProxiedDoer(client), nil
@@ -132,20 +140,23 @@ func (d *httpDoer) do(ctx context.Context, req *http.Request) ([]byte, int, erro
 	if err != nil {
 		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			resObj.StatusCode = 500
-			log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, newRetriableError(err.Error()))
-			return nil, 500, err, reqObj, resObj
+			retriableErr := newRetriableError(err)
+			log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, retriableErr)
+			return nil, 500, retriableErr, reqObj, resObj
 		}
 
-		log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, newRetriableError(err.Error()))
-		return nil, 0, err, reqObj, resObj
+		retriableErr := newRetriableError(err)
+		log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, retriableErr)
+		return nil, 0, retriableErr, reqObj, resObj
 	}
 	defer resp.Body.Close()
 
 	resObj.StatusCode = resp.StatusCode
 	respBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, newRetriableError(err.Error()))
-		return nil, resp.StatusCode, err, reqObj, resObj
+		retriableErr := newRetriableError(err)
+		log.WithContext(ctx).Errorf("%s %s %s", req.Method, req.URL, retriableErr)
+		return nil, resp.StatusCode, retriableErr, reqObj, resObj
 	}
 
 	truncatedRespBytes := truncateBytes(respBytes)
@@ -160,8 +171,21 @@ func (d *httpDoer) do(ctx context.Context, req *http.Request) ([]byte, int, erro
 	return respBytes, resp.StatusCode, err, reqObj, resObj
 }
 
+// Do sends the trace context of ctx along with req so the callee continues
+// the trace.
 func (d *httpDoer) Do(ctx context.Context, req *http.Request) ([]byte, int, error) {
+	ctx, span := tracing.StartSpanKind(ctx, trace.SpanKindClient, "HTTP "+req.Method,
+		semconv.HTTPMethod(req.Method),
+		semconv.NetPeerName(req.URL.Hostname()),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
 	respBytes, statusCode, err, _, _ := d.do(ctx, req)
+	span.SetAttributes(semconv.HTTPStatusCode(statusCode))
+	if err == nil && statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	tracing.End(span, err)
 
 	return respBytes, statusCode, err
 }
@@ -182,6 +206,6 @@ func truncateBytes(bytes []byte) []byte {
 	return bytes[0:log.MAX_LOG_ENTRY_SIZE]
 }
 
-func newRetriableError(message string) *RetriableError {
-	return &RetriableError{message: message}
+func newRetriableError(err error) *RetriableError {
+	return &RetriableError{message: err.Error(), cause: err}
 }
@@ -21,14 +21,9 @@ type httpGetter struct {
 	client *http.Client
 }
 
-func NewGetter() HttpGetter {
-	timeout, err := strconv.Atoi(os.Getenv("TIMEOUT"))
-	if err != nil {
-		timeout = 30
-	}
-
+func NewGetter(timeout time.Duration) HttpGetter {
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Timeout: timeout,
 	}
 	return newGetter(client)
 }
@@ -0,0 +1,43 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// background counts the goroutines a request or a job leaves running, the
+// shutdown waits for them before closing the clients they use.
+var background sync.WaitGroup
+
+// Track marks one goroutine started, it calls the returned func once done.
+func Track() func() {
+	background.Add(1)
+	return background.Done
+}
+
+// Background tracks the goroutine of go Background(fn)(ctx, arg). The
+// function value is evaluated before the goroutine starts, so it is
+// tracked from there, and fn gets its arguments like with a plain go.
+func Background[T any](fn func(ctx context.Context, arg T) error) func(ctx context.Context, arg T) {
+	done := Track()
+	return func(ctx context.Context, arg T) {
+		defer done()
+		fn(ctx, arg)
+	}
+}
+
+// Wait blocks until every tracked goroutine returned or ctx is done.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,147 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/palantir/stacktrace"
+
+	"go-poc/utils/log"
+)
+
+// Runner is a long running part of the process. Start blocks until Stop is
+// called, Stop returns once the work in flight is done or ctx is.
+type Runner struct {
+	Name  string
+	Start func() error
+	Stop  func(ctx context.Context) error
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Manager starts the runners and shuts the process down on SIGINT or
+// SIGTERM: the runners are stopped, the tracked goroutines waited for and
+// the clients closed in the order they were added, all within timeout.
+type Manager struct {
+	timeout time.Duration
+	runners []Runner
+	closers []closer
+	once    sync.Once
+	err     error
+}
+
+func New(timeout time.Duration) *Manager {
+	return &Manager{
+		timeout: timeout,
+	}
+}
+
+func (m *Manager) Add(runner Runner) {
+	m.runners = append(m.runners, runner)
+}
+
+// OnClose adds a client to close once nothing runs anymore.
+func (m *Manager) OnClose(name string, close func(ctx context.Context) error) {
+	m.closers = append(m.closers, closer{name: name, close: close})
+}
+
+// Run starts every runner and blocks until a signal arrives or a runner
+// fails, then shuts down.
+func (m *Manager) Run(ctx context.Context) error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	failed := make(chan error, len(m.runners))
+	for _, runner := range m.runners {
+		go func(runner Runner) {
+			log.WithContext(ctx).Info(runner.Name + " started")
+			if err := runner.Start(); err != nil {
+				failed <- stacktrace.Propagate(err, "%s error", runner.Name)
+			}
+		}(runner)
+	}
+
+	var err error
+	select {
+	case sig := <-quit:
+		log.WithContext(ctx).Infof("%s received, shutting down", sig)
+	case err = <-failed:
+		log.WithContext(ctx).Error(err)
+	}
+
+	if shutdownErr := m.Shutdown(ctx); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
+
+	return err
+}
+
+// Shutdown stops the runners, waits for the tracked goroutines and closes
+// the clients, only the first call does. A step past the deadline is logged
+// and the next one still runs so every client gets closed.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.err = m.shutdown(ctx)
+	})
+
+	return m.err
+}
+
+func (m *Manager) shutdown(ctx context.Context) error {
+	deadline, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var firstErr error
+	fail := func(err error) {
+		log.WithContext(ctx).Error(err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, runner := range m.runners {
+		if err := runner.Stop(deadline); err != nil {
+			fail(stacktrace.Propagate(err, "stop %s error", runner.Name))
+			continue
+		}
+
+		log.WithContext(ctx).Info(runner.Name + " stopped")
+	}
+
+	if err := Wait(deadline); err != nil {
+		fail(stacktrace.Propagate(err, "drain background work error"))
+	}
+
+	for _, closer := range m.closers {
+		if err := closer.close(deadline); err != nil {
+			fail(stacktrace.Propagate(err, "close %s error", closer.name))
+		}
+	}
+
+	return firstErr
+}
+
+// HTTPServer serves srv until it is shut down, the requests in flight are
+// answered before Stop returns.
+func HTTPServer(srv *http.Server) Runner {
+	return Runner{
+		Name: "http server",
+		Start: func() error {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
+}
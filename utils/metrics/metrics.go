@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "go_poc"
+
+const (
+	OutcomeSuccess = "success"
+	// OutcomeFailure is a batch call that answered with failed items.
+	OutcomeFailure = "failure"
+	OutcomeError   = "error"
+
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	usecaseCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "usecase_calls_total",
+		Help:      "Calls of the usecase methods by outcome.",
+	}, []string{"service", "usecase", "method", "outcome"})
+
+	usecaseFailedItems = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "usecase_failed_items_total",
+		Help:      "Items a batch usecase method answered as failed.",
+	}, []string{"service", "usecase", "method"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache reads by adapter and result.",
+	}, []string{"service", "entity", "adapter", "result"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveHTTP(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveUsecase counts a usecase call, failed is the number of items a
+// batch method answered with alongside err.
+func ObserveUsecase(service, usecase, method string, failed int, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+		if failed > 0 {
+			outcome = OutcomeFailure
+			usecaseFailedItems.WithLabelValues(service, usecase, method).Add(float64(failed))
+		}
+	}
+
+	usecaseCalls.WithLabelValues(service, usecase, method, outcome).Inc()
+}
+
+// ObserveCacheLookup counts a cache read, miss is the error the client
+// returns for an absent key.
+func ObserveCacheLookup(service, entity, adapter string, err, miss error) {
+	result := CacheHit
+	if err == miss {
+		result = CacheMiss
+	} else if err != nil {
+		result = CacheError
+	}
+
+	cacheLookups.WithLabelValues(service, entity, adapter, result).Inc()
+}
+
+// RegisterDB exposes the pool statistics of db under the name of the
+// service owning it.
+func RegisterDB(service string, db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, service))
+}
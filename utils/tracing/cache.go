@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	CacheRedis    = semconv.DBSystemRedis
+	CacheMemcache = semconv.DBSystemMemcached
+)
+
+// EndLookup ends the span of a cache read, miss is the error the client
+// returns for an absent key and is recorded as a miss, not a failure.
+func EndLookup(span trace.Span, err, miss error) {
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err == miss {
+		err = nil
+	}
+
+	End(span, err)
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"go-poc/utils"
+)
+
+var (
+	DBMySQL    = semconv.DBSystemMySQL
+	DBPostgres = semconv.DBSystemPostgreSQL
+)
+
+var matchTable = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE)\\s+[`\"]?(\\w+)")
+
+type db struct {
+	executor utils.DBExecutor
+	system   attribute.KeyValue
+}
+
+// DB opens a span around every statement run by executor. The statements
+// carry their values inline so only the operation and the table are kept.
+func DB(executor utils.DBExecutor, system attribute.KeyValue) utils.DBExecutor {
+	return &db{
+		executor: executor,
+		system:   system,
+	}
+}
+
+func (d *db) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	res, err := d.executor.ExecContext(ctx, query, args...)
+	End(span, err)
+
+	return res, err
+}
+
+func (d *db) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := d.start(ctx, query)
+	stmt, err := d.executor.PrepareContext(ctx, query)
+	End(span, err)
+
+	return stmt, err
+}
+
+// QueryContext ends the span once the query answered, the rows are read
+// afterwards by the caller.
+func (d *db) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := d.start(ctx, query)
+	rows, err := d.executor.QueryContext(ctx, query, args...)
+	End(span, err)
+
+	return rows, err
+}
+
+func (d *db) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := d.start(ctx, query)
+	row := d.executor.QueryRowContext(ctx, query, args...)
+	End(span, row.Err())
+
+	return row
+}
+
+func (d *db) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, table := describe(query)
+	name := operation
+	attributes := []attribute.KeyValue{d.system, semconv.DBOperationKey.String(operation)}
+	if table != "" {
+		name += " " + table
+		attributes = append(attributes, semconv.DBSQLTableKey.String(table))
+	}
+
+	return StartSpanKind(ctx, trace.SpanKindClient, name, attributes...)
+}
+
+// describe returns the leading keyword of query and the first table it
+// names.
+func describe(query string) (string, string) {
+	query = strings.TrimSpace(query)
+	operation := query
+	if i := strings.IndexAny(query, " \t\n"); i > 0 {
+		operation = query[:i]
+	}
+
+	table := ""
+	if match := matchTable.FindStringSubmatch(query); match != nil {
+		table = match[1]
+	}
+
+	return strings.ToUpper(operation), table
+}
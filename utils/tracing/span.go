@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/palantir/stacktrace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "go-poc"
+
+// StartSpan opens a span under the one carried by ctx, the caller ends it.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return StartSpanKind(ctx, trace.SpanKindInternal, name, attributes...)
+}
+
+// StartSpanKind opens a span of kind, server for the requests received and
+// client for the calls made to a database, a cache or another service.
+func StartSpanKind(ctx context.Context, kind trace.SpanKind, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// End marks the span failed when err is set and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(stacktrace.RootCause(err))
+		span.SetStatus(codes.Error, stacktrace.RootCause(err).Error())
+	}
+
+	span.End()
+}
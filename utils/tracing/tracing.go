@@ -1,28 +1,81 @@
 package tracing
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	jaegerpropagator "go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-func Start(url, service, environment string) (*tracesdk.TracerProvider, error) {
-	// Create the Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
-	if err != nil {
-		return nil, err
-	}
-	tracingProvider := tracesdk.NewTracerProvider(
-		// Always be sure to batch in production.
-		tracesdk.WithBatcher(exp),
+const (
+	ExporterJaeger = "jaeger"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Start installs the global provider and the W3C trace context, baggage and
+// Jaeger propagators. The exporter is jaeger by default, url being the
+// agent host:port or a collector http(s) endpoint, stdout prints the spans
+// on local runs and none only propagates the incoming context.
+func Start(exporter, url, service, environment string) (*tracesdk.TracerProvider, error) {
+	options := []tracesdk.TracerProviderOption{
 		// Record information about this application in a Resource.
 		tracesdk.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
 			semconv.ServiceName(service),
 			attribute.String("environment", environment),
 		)),
-	)
+	}
+
+	switch exporter {
+	case ExporterJaeger, "":
+		exp, err := newJaeger(url)
+		if err != nil {
+			return nil, err
+		}
+		// Always be sure to batch in production.
+		options = append(options, tracesdk.WithBatcher(exp))
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, tracesdk.WithSyncer(exp))
+	case ExporterNone:
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %s", exporter)
+	}
+
+	tracingProvider := tracesdk.NewTracerProvider(options...)
+	otel.SetTracerProvider(tracingProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		jaegerpropagator.Jaeger{},
+	))
+
 	return tracingProvider, nil
 }
+
+func newJaeger(url string) (*jaeger.Exporter, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(url)))
+	}
+
+	host, port, err := net.SplitHostPort(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return jaeger.New(jaeger.WithAgentEndpoint(jaeger.WithAgentHost(host), jaeger.WithAgentPort(port)))
+}
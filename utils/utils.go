@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
@@ -8,15 +9,36 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// DBExecutor is implemented by *sql.DB and *sql.Tx, every query runs under
+// the context of its caller.
 type DBExecutor interface {
-	Exec(query string, args ...interface{}) (sql.Result, error)
-	Prepare(string) (*sql.Stmt, error)
-	Query(string, ...interface{}) (*sql.Rows, error)
-	QueryRow(string, ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var queryTimeout = 30 * time.Second
+
+// SetQueryTimeout is called once on start with DB_QUERY_TIMEOUT, 0 to only
+// follow ctx.
+func SetQueryTimeout(timeout time.Duration) {
+	queryTimeout = timeout
+}
+
+// WithQueryTimeout bounds a repository call by the query timeout. The
+// caller defers cancel once the rows are read.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, queryTimeout)
 }
 
 var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")